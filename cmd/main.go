@@ -8,14 +8,39 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"github.com/vikasgithub/risky-plumbers/internal/admin"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"github.com/vikasgithub/risky-plumbers/internal/changefeed"
 	"github.com/vikasgithub/risky-plumbers/internal/config"
+	"github.com/vikasgithub/risky-plumbers/internal/events"
+	"github.com/vikasgithub/risky-plumbers/internal/graphqlapi"
+	"github.com/vikasgithub/risky-plumbers/internal/grpcapi"
 	"github.com/vikasgithub/risky-plumbers/internal/healthcheck"
+	"github.com/vikasgithub/risky-plumbers/internal/idempotency"
 	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/metrics"
+	"github.com/vikasgithub/risky-plumbers/internal/oidc"
+	"github.com/vikasgithub/risky-plumbers/internal/openapi"
+	"github.com/vikasgithub/risky-plumbers/internal/ratelimit"
 	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"github.com/vikasgithub/risky-plumbers/internal/tenant"
+	"github.com/vikasgithub/risky-plumbers/internal/tlsconfig"
+	"github.com/vikasgithub/risky-plumbers/internal/tracing"
+	"github.com/vikasgithub/risky-plumbers/internal/webhook"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"path"
+	"strings"
 	"syscall"
+	"time"
 )
 
 var flagConfig = flag.String("config", "./config/local.yml", "path to the config file")
@@ -30,47 +55,599 @@ func main() {
 		logger.Errorf("failed to load application configuration: %s", err)
 		os.Exit(-1)
 	}
+	configured, logLevel, err := log.NewWithConfig(newLogConfig(cfg.Log))
+	if err != nil {
+		logger.Errorf("invalid log configuration: %s", err)
+		os.Exit(-1)
+	}
+	logger = configured
+	defer logger.Sync()
+
+	// the spans are exported once the tracer provider is installed, the spans of the packages are no-ops until then
+	shutdownTracing := func(context.Context) error { return nil }
+	if cfg.Tracing.Enabled {
+		provider, err := tracing.NewProvider(context.Background(), newTracingConfig(cfg.Tracing))
+		if err != nil {
+			logger.Errorf("failed to configure the tracing: %s", err)
+			os.Exit(-1)
+		}
+		tracing.Install(provider)
+		shutdownTracing = provider.Shutdown
+	}
 
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
+	r.Use(log.Middleware())
+	if cfg.Tracing.Enabled {
+		r.Use(tracing.Middleware())
+	}
+	r.Use(log.AccessLog(logger))
+	// the metrics are served on their own port, so that they are not exposed with the api
+	var metricsRegistry *prometheus.Registry
+	if cfg.Metrics.Enabled {
+		metricsRegistry = metrics.NewRegistry()
+		r.Use(metrics.Middleware(metricsRegistry))
+	}
 	r.Use(middleware.Recoverer)
 	r.Use(render.SetContentType(render.ContentTypeJSON))
 
-	healthcheck.RegisterHandlers(r)
-	apiRouter := buildApiRouter(logger)
+	broker := risk.NewBroker(cfg.Events.ReplaySize)
+	webhookStore := webhook.NewMemoryStore()
+	dispatcher, err := webhook.NewDispatcher(webhookStore, webhook.Config(cfg.Webhooks), logger)
+	if err != nil {
+		logger.Errorf("invalid webhooks configuration: %s", err)
+		os.Exit(-1)
+	}
+
+	var sessions oidc.SessionStore
+	if cfg.Auth.OIDC.Enabled {
+		sessions = oidc.NewMemorySessionStore()
+		if err := registerOIDC(r, cfg.Auth.OIDC, sessions, logger); err != nil {
+			logger.Errorf("failed to configure the oidc login: %s", err)
+			os.Exit(-1)
+		}
+	}
+	var authenticator auth.Authenticator
+	if cfg.Auth.Enabled {
+		authenticator, err = newAuthenticator(cfg.Auth, sessions)
+		if err != nil {
+			logger.Errorf("failed to configure the authentication: %s", err)
+			os.Exit(-1)
+		}
+	}
+	// the operations are authorized once the callers are authenticated
+	var serviceOptions []risk.ServiceOption
+	if authenticator != nil {
+		policy := auth.Policy(cfg.RBAC.Policy)
+		if err := policy.Validate(); err != nil {
+			logger.Errorf("invalid rbac policy: %s", err)
+			os.Exit(-1)
+		}
+		serviceOptions = append(serviceOptions, risk.WithPolicy(policy))
+	}
+	tenantLimits, err := newTenantLimits(cfg.Tenants)
+	if err != nil {
+		logger.Errorf("invalid tenant configuration: %s", err)
+		os.Exit(-1)
+	}
+	serviceOptions = append(serviceOptions, risk.WithTenantLimits(tenantLimits))
+	riskStore := risk.NewStore(logger)
+	if metricsRegistry != nil {
+		metricsRegistry.MustRegister(metrics.NewRiskCollector(riskStore, logger))
+		riskStore = metrics.InstrumentStore(riskStore, metricsRegistry)
+	}
+	riskService := risk.NewService(riskStore, logger, serviceOptions...)
+	sinks := []risk.Publisher{broker, dispatcher}
+	var busPublisher events.Publisher
+	if cfg.Bus.Enabled {
+		busPublisher, err = newBusPublisher(cfg.Bus)
+		if err != nil {
+			logger.Errorf("failed to connect to the message bus: %s", err)
+			os.Exit(-1)
+		}
+		sinks = append(sinks, events.NewSink(busPublisher, cfg.Bus.TopicPrefix))
+	}
+	relay := risk.NewRelay(riskStore, risk.RelayConfig(cfg.Outbox), logger, sinks...)
+
+	// the callers are throttled by address before the authentication, and once they are authenticated, so that
+	// each principal has its own buckets
+	var addressLimiter, rateLimiter func(http.Handler) http.Handler
+	closeRateLimitStore := func() error { return nil }
+	if cfg.RateLimit.Enabled {
+		rateLimitConfig, err := newRateLimitConfig(cfg.RateLimit)
+		if err != nil {
+			logger.Errorf("invalid rate limit configuration: %s", err)
+			os.Exit(-1)
+		}
+		var rateLimitStore ratelimit.Store
+		rateLimitStore, closeRateLimitStore, err = newRateLimitStore(cfg.RateLimit)
+		if err != nil {
+			logger.Errorf("failed to configure the rate limit store: %s", err)
+			os.Exit(-1)
+		}
+		addressLimiter = ratelimit.AddressMiddleware(rateLimitStore, ratelimit.Limit(cfg.RateLimit.Address), logger)
+		rateLimiter = ratelimit.Middleware(rateLimitStore, rateLimitConfig, logger)
+	}
+
+	idempotencyStore, closeIdempotencyStore, err := newIdempotencyStore(cfg.Idempotency)
+	if err != nil {
+		logger.Errorf("failed to configure the idempotency store: %s", err)
+		os.Exit(-1)
+	}
+
+	// the service is ready once its dependencies respond
+	health := healthcheck.New(cfg.Health.Timeout)
+	health.Register("repository", riskStore.Ping)
+	if busPublisher != nil {
+		health.Register("eventBus", busPublisher.Ping)
+	}
+	health.Register("diskSpace", healthcheck.DiskSpace(cfg.Health.DiskPath, cfg.Health.MinFreeBytes))
+	healthcheck.RegisterHandlers(r, health)
+	apiRouter := buildApiRouter(cfg, riskService, broker, webhookStore, idempotencyStore, authenticator,
+		addressLimiter, rateLimiter, logLevel, logger)
 	r.Mount("/api/v1", apiRouter)
 
+	graphqlRouter := chi.NewRouter()
+	graphqlRouter.Use(middleware.RequestSize(cfg.Server.MaxRequestBodyBytes))
+	if addressLimiter != nil {
+		graphqlRouter.Use(addressLimiter)
+	}
+	if authenticator != nil {
+		graphqlRouter.Use(auth.Middleware(authenticator, logger))
+	}
+	if rateLimiter != nil {
+		graphqlRouter.Use(rateLimiter)
+	}
+	graphqlRouter.Use(tenant.Middleware())
+	err = graphqlapi.RegisterHandlers(graphqlRouter, riskService, cfg.GraphQL.MaxDepth, cfg.GraphQL.MaxComplexity, logger)
+	if err != nil {
+		logger.Errorf("failed to build the graphql schema: %s", err)
+		os.Exit(-1)
+	}
+	r.Mount("/api", graphqlRouter)
+
 	// build HTTP server
 	address := fmt.Sprintf(":%v", cfg.Server.Port)
 	server := &http.Server{
-		Addr:    address,
-		Handler: r,
+		Addr:              address,
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	// the change streams never end by themselves, closing the broker ends them so that the server can drain
+	server.RegisterOnShutdown(broker.Close)
+	// the certificates are reloaded when their files change, the api and the gRPC API share them
+	var tlsReloader *tlsconfig.Reloader
+	if cfg.Server.TLS.Enabled {
+		tlsReloader, err = tlsconfig.New(newTLSConfig(cfg.Server.TLS), logger)
+		if err != nil {
+			logger.Errorf("invalid tls configuration: %s", err)
+			os.Exit(-1)
+		}
+		server.TLSConfig = tlsReloader.TLSConfig()
+	}
+	if len(cfg.Auth.ClientCertificates) > 0 && (!cfg.Server.TLS.Enabled || cfg.Server.TLS.ClientCAFile == "") {
+		logger.Errorf("the client certificates need tls with a client ca bundle")
+		os.Exit(-1)
 	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
+	// the relay keeps publishing the events of the requests in flight until the servers are drained
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	relayStopped := make(chan struct{})
+	go func() {
+		relay.Run(relayCtx)
+		close(relayStopped)
+	}()
 	go func() {
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		var err error
+		if tlsReloader != nil {
+			// the certificates are provided by the tls configuration
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logger.Error(err)
 			os.Exit(-1)
 		}
 	}()
 	logger.Infof("server is running at %v", address)
 
+	var metricsServer *http.Server
+	if metricsRegistry != nil {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle(cfg.Metrics.Path, metrics.Handler(metricsRegistry))
+		metricsServer = &http.Server{
+			Addr:              fmt.Sprintf(":%v", cfg.Metrics.Port),
+			Handler:           metricsMux,
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		}
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error(err)
+				os.Exit(-1)
+			}
+		}()
+		logger.Infof("metrics are served at %v%v", metricsServer.Addr, cfg.Metrics.Path)
+	}
+
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Enabled {
+		grpcAddress := fmt.Sprintf(":%v", cfg.GRPC.Port)
+		listener, err := net.Listen("tcp", grpcAddress)
+		if err != nil {
+			logger.Errorf("failed to listen on %v: %s", grpcAddress, err)
+			os.Exit(-1)
+		}
+		var opts []grpc.ServerOption
+		if tlsReloader != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(tlsReloader.TLSConfig())))
+		}
+		if authenticator != nil {
+			opts = append(opts, grpcapi.AuthOptions(authenticator)...)
+		}
+		opts = append(opts, grpcapi.TenantOptions()...)
+		grpcServer = grpcapi.NewServer(riskService, broker, logger, opts...)
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Error(err)
+				os.Exit(-1)
+			}
+		}()
+		logger.Infof("grpc server is running at %v", grpcAddress)
+	}
+
 	<-ctx.Done()
+	// a second signal stops the service at once
+	stop()
 	logger.Info("got interruption signal")
-	if err := server.Shutdown(context.TODO()); err != nil {
-		logger.Errorf("server shutdown returned an err: %v\n", err)
+	// the load balancers see the service unready and stop sending requests before the server stops
+	health.Shutdown()
+	time.Sleep(cfg.Health.ShutdownDelay)
+
+	// the requests in flight, the relay and the webhook deliveries share the grace period
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Errorf("server did not drain the requests in flight, closing their connections: %v", err)
+		server.Close()
+	}
+	if grpcServer != nil {
+		stopGRPC(shutdownCtx, grpcServer, broker, logger)
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			logger.Errorf("metrics server shutdown returned an err: %v", err)
+		}
+	}
+	// publish the events written by the last requests
+	stopRelay()
+	<-relayStopped
+	if err := relay.Flush(shutdownCtx); err != nil {
+		logger.Errorf("failed to relay the outbox events: %v", err)
+	}
+	if err := dispatcher.Close(shutdownCtx); err != nil {
+		logger.Errorf("webhook dispatcher did not stop: %v", err)
+	}
+	if err := riskStore.Close(); err != nil {
+		logger.Errorf("failed to close the risk store: %v", err)
+	}
+	if err := closeRateLimitStore(); err != nil {
+		logger.Errorf("failed to close the rate limit store: %v", err)
+	}
+	if err := closeIdempotencyStore(); err != nil {
+		logger.Errorf("failed to close the idempotency store: %v", err)
+	}
+	if busPublisher != nil {
+		if err := busPublisher.Close(); err != nil {
+			logger.Errorf("failed to close the message bus publisher: %v", err)
+		}
+	}
+	// the spans of the shutdown are exported last, with a deadline of their own
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelTracing()
+	if err := shutdownTracing(tracingCtx); err != nil {
+		logger.Errorf("failed to export the last spans: %v", err)
 	}
 
 	logger.Info("Server stopped...")
 }
 
-func buildApiRouter(logger log.Logger) *chi.Mux {
+// stopGRPC waits for the rpcs in flight until ctx is done, then cancels them. The streams of risk changes end
+// when the broker is closed.
+func stopGRPC(ctx context.Context, server *grpc.Server, broker *risk.Broker, logger log.Logger) {
+	broker.Close()
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		logger.Errorf("grpc server did not drain the rpcs in flight, cancelling them: %v", ctx.Err())
+		server.Stop()
+	}
+}
+
+func newBusPublisher(cfg config.BusConfig) (events.Publisher, error) {
+	switch cfg.Driver {
+	case "inprocess":
+		return events.NewInProcessPublisher(), nil
+	case "nats":
+		return events.NewNATSPublisher(cfg.URL)
+	default:
+		return nil, fmt.Errorf("unknown message bus driver %q", cfg.Driver)
+	}
+}
+
+// newAuthenticator accepts the configured API keys, the JWT bearer tokens when a JWKS is configured and the
+// session cookies when the OIDC login is enabled
+func newAuthenticator(cfg config.AuthConfig, sessions oidc.SessionStore) (auth.Authenticator, error) {
+	var chain auth.Chain
+	if len(cfg.APIKeys) > 0 {
+		keys := make([]auth.APIKey, 0, len(cfg.APIKeys))
+		for _, key := range cfg.APIKeys {
+			if key.Tenant != "" && !tenant.Valid(key.Tenant) {
+				return nil, fmt.Errorf("api key %q has an invalid tenant %q", key.Name, key.Tenant)
+			}
+			keys = append(keys, auth.APIKey(key))
+		}
+		authenticator, err := auth.NewAPIKeyAuthenticator(keys)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, authenticator)
+	}
+	if cfg.JWT.JWKSFile != "" || cfg.JWT.JWKSURL != "" {
+		// without them, any token signed by the identity provider would be accepted, whichever client it was issued to
+		if cfg.JWT.Issuer == "" || cfg.JWT.Audience == "" {
+			return nil, errors.New("the jwt issuer and audience are required with a jwks")
+		}
+		keySource, err := auth.NewKeySource(auth.JWTConfig(cfg.JWT), &http.Client{Timeout: 10 * time.Second})
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, auth.NewJWTAuthenticator(keySource, auth.JWTConfig(cfg.JWT)))
+	}
+	if sessions != nil {
+		chain = append(chain, oidc.NewSessionAuthenticator(sessions))
+	}
+	// the client certificates come last, so that the credentials sent with a request take precedence
+	if len(cfg.ClientCertificates) > 0 {
+		certificates := make([]auth.ClientCertificate, 0, len(cfg.ClientCertificates))
+		for _, certificate := range cfg.ClientCertificates {
+			if certificate.Tenant != "" && !tenant.Valid(certificate.Tenant) {
+				return nil, fmt.Errorf("client certificate %q has an invalid tenant %q", certificate.Subject, certificate.Tenant)
+			}
+			certificates = append(certificates, auth.ClientCertificate(certificate))
+		}
+		authenticator, err := auth.NewClientCertificateAuthenticator(certificates)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, authenticator)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("authentication is enabled without api keys, jwks, oidc or client certificates")
+	}
+	return chain, nil
+}
+
+// allowMockIdPEnv set to true lets the mock identity provider serve an issuer which is not a loopback address, e.g.
+// in the containers of the integration tests
+const allowMockIdPEnv = "ALLOW_MOCK_IDP"
+
+// registerOIDC registers the login endpoints and, in local development, the mock identity provider
+func registerOIDC(r chi.Router, cfg config.OIDCConfig, sessions oidc.SessionStore, logger log.Logger) error {
+	issuer, err := url.Parse(cfg.Issuer)
+	if err != nil || !issuer.IsAbs() {
+		return fmt.Errorf("the issuer %q is not an absolute url", cfg.Issuer)
+	}
+	if cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return errors.New("the client id and the redirect url are required")
+	}
+	if cfg.MockIdP.Enabled {
+		if strings.Trim(issuer.Path, "/") == "" {
+			return fmt.Errorf("the mock identity provider needs a path in its issuer %q, e.g. /mock-idp", cfg.Issuer)
+		}
+		if !isLoopback(issuer.Hostname()) && os.Getenv(allowMockIdPEnv) != "true" {
+			return fmt.Errorf("the mock identity provider logs in without a password, its issuer %q must be a loopback "+
+				"address unless %s=true", cfg.Issuer, allowMockIdPEnv)
+		}
+		users := make([]oidc.MockUser, 0, len(cfg.MockIdP.Users))
+		for _, user := range cfg.MockIdP.Users {
+			users = append(users, oidc.MockUser(user))
+		}
+		mock, err := oidc.NewMockProvider(cfg.Issuer, users)
+		if err != nil {
+			return err
+		}
+		r.Mount(issuer.Path, mock.Handler())
+		logger.Infof("the mock identity provider is serving at %v, it logs in without a password", cfg.Issuer)
+	}
+	oidcConfig := oidc.Config{
+		Issuer:           cfg.Issuer,
+		ClientID:         cfg.ClientID,
+		ClientSecret:     cfg.ClientSecret,
+		RedirectURL:      cfg.RedirectURL,
+		Scopes:           cfg.Scopes,
+		GroupsClaim:      cfg.GroupsClaim,
+		TenantClaim:      cfg.TenantClaim,
+		GroupRoles:       cfg.GroupRoles,
+		SessionTTL:       cfg.SessionTTL,
+		CookieSecure:     cfg.CookieSecure,
+		PostLoginURL:     cfg.PostLoginURL,
+		MaxPendingLogins: cfg.MaxPendingLogins,
+	}
+	provider := oidc.NewProvider(oidcConfig, &http.Client{Timeout: 10 * time.Second})
+	oidc.RegisterHandlers(r, provider, sessions, oidcConfig, logger)
+	return nil
+}
+
+// isLoopback tells whether host is localhost or a loopback address
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// newLogConfig returns the configuration of the logger of the service
+func newLogConfig(cfg config.LogConfig) log.Config {
+	return log.Config{
+		Level:       cfg.Level,
+		Encoding:    cfg.Encoding,
+		Development: cfg.Development,
+		Sampling:    log.SamplingConfig(cfg.Sampling),
+		OutputPaths: cfg.OutputPaths,
+		Rotation:    log.RotationConfig(cfg.Rotation),
+	}
+}
+
+// newTLSConfig returns the configuration of the certificates of the servers
+func newTLSConfig(cfg config.ServerTLSConfig) tlsconfig.Config {
+	return tlsconfig.Config{
+		CertFile:       cfg.CertFile,
+		KeyFile:        cfg.KeyFile,
+		MinVersion:     cfg.MinVersion,
+		CipherPolicy:   cfg.CipherPolicy,
+		ClientCAFile:   cfg.ClientCAFile,
+		ClientAuth:     cfg.ClientAuth,
+		ReloadInterval: cfg.ReloadInterval,
+	}
+}
+
+// newTracingConfig returns the configuration of the tracer provider
+func newTracingConfig(cfg config.TracingConfig) tracing.Config {
+	return tracing.Config{
+		ServiceName: cfg.ServiceName,
+		Exporter:    cfg.Exporter,
+		Protocol:    cfg.Protocol,
+		Endpoint:    cfg.Endpoint,
+		Insecure:    cfg.Insecure,
+		SampleRatio: cfg.SampleRatio,
+	}
+}
+
+// newTenantLimits returns the validation limits overridden by the tenants
+func newTenantLimits(tenants map[string]config.TenantConfig) (map[string]risk.Limits, error) {
+	limits := make(map[string]risk.Limits, len(tenants))
+	for id, t := range tenants {
+		if !tenant.Valid(id) {
+			return nil, fmt.Errorf("invalid tenant id %q", id)
+		}
+		limits[id] = risk.Limits(t)
+	}
+	return limits, nil
+}
+
+// newRateLimitStore returns the store of the buckets and a function closing it
+func newRateLimitStore(cfg config.RateLimitConfig) (ratelimit.Store, func() error, error) {
+	switch cfg.Store {
+	case "memory":
+		return ratelimit.NewMemoryStore(), func() error { return nil }, nil
+	case "redis":
+		client := redis.NewClient(&redis.Options{Addr: cfg.Redis.Addr, Password: cfg.Redis.Password, DB: cfg.Redis.DB})
+		return ratelimit.NewRedisStore(client, cfg.Redis.KeyPrefix), client.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown rate limit store %q", cfg.Store)
+	}
+}
+
+// newIdempotencyStore returns the store of the idempotency records and a function closing it
+func newIdempotencyStore(cfg config.IdempotencyConfig) (idempotency.Store, func() error, error) {
+	// the records would expire at once, so that the retries run again
+	if cfg.TTL <= 0 {
+		return nil, nil, fmt.Errorf("idempotency.ttl must be positive, got %v", cfg.TTL)
+	}
+	switch cfg.Store {
+	case "memory":
+		return idempotency.NewMemoryStore(), func() error { return nil }, nil
+	case "redis":
+		client := redis.NewClient(&redis.Options{Addr: cfg.Redis.Addr, Password: cfg.Redis.Password, DB: cfg.Redis.DB})
+		return idempotency.NewRedisStore(client, cfg.Redis.KeyPrefix), client.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown idempotency store %q", cfg.Store)
+	}
+}
+
+// newRateLimitConfig returns the limits of the routes
+func newRateLimitConfig(cfg config.RateLimitConfig) (ratelimit.Config, error) {
+	limits := ratelimit.Config{Default: ratelimit.Limit(cfg.Default)}
+	if !limits.Default.Valid() {
+		return limits, errors.New("the default limit needs positive requests and period")
+	}
+	for _, route := range cfg.Routes {
+		rule := ratelimit.Rule{
+			Name:   route.Name,
+			Method: route.Method,
+			Path:   route.Path,
+			Limit:  ratelimit.Limit{Requests: route.Requests, Period: route.Period, Burst: route.Burst},
+		}
+		if rule.Name == "" {
+			rule.Name = strings.TrimSpace(rule.Method + " " + rule.Path)
+		}
+		if _, err := path.Match(rule.Path, "/"); err != nil || rule.Path == "" {
+			return limits, fmt.Errorf("route %q has an invalid path pattern %q", rule.Name, rule.Path)
+		}
+		if !rule.Valid() {
+			return limits, fmt.Errorf("route %q needs positive requests and period", rule.Name)
+		}
+		limits.Rules = append(limits.Rules, rule)
+	}
+	return limits, nil
+}
+
+func buildApiRouter(cfg *config.Config, riskService risk.Service, broker *risk.Broker, webhookStore webhook.Store,
+	idempotencyStore idempotency.Store, authenticator auth.Authenticator,
+	addressLimiter, rateLimiter func(http.Handler) http.Handler, logLevel zap.AtomicLevel, logger log.Logger) *chi.Mux {
 	r := chi.NewRouter()
+	r.Use(middleware.RequestSize(cfg.Server.MaxRequestBodyBytes))
+
+	doc := openapi.New("Risky Plumbers API", "1.0.0", "/api/v1")
+	risk.Describe(doc)
+	changefeed.Describe(doc)
+	webhook.Describe(doc)
+	admin.Describe(doc)
+	if authenticator != nil {
+		doc.AddSecurityScheme("apiKey", &openapi.SecurityScheme{Type: "apiKey", In: "header", Name: auth.HeaderAPIKey})
+		doc.AddSecurityScheme("bearer", &openapi.SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"})
+		if cfg.Auth.OIDC.Enabled {
+			doc.AddSecurityScheme("session", &openapi.SecurityScheme{Type: "apiKey", In: "cookie", Name: auth.SessionCookie})
+		}
+	}
+	// the documentation stays readable without credentials
+	openapi.RegisterHandlers(r, doc)
+
+	r.Group(func(r chi.Router) {
+		if addressLimiter != nil {
+			r.Use(addressLimiter)
+		}
+		if authenticator != nil {
+			r.Use(auth.Middleware(authenticator, logger))
+		}
+		if rateLimiter != nil {
+			r.Use(rateLimiter)
+		}
+		r.Use(tenant.Middleware())
+		r.Use(idempotency.Middleware(idempotencyStore, cfg.Idempotency.TTL, logger))
 
-	//Add handlers here
-	risk.RegisterHandlers(r, risk.NewService(risk.NewRepository(logger), logger))
+		//Add handlers here
+		risk.RegisterHandlers(r, riskService, logger)
+		r.With(risk.Authorized(riskService, auth.ActionRead)).Group(func(r chi.Router) {
+			changefeed.RegisterHandlers(r, broker, cfg.Events.Heartbeat, logger)
+		})
+		webhook.RegisterHandlers(r, webhookStore, riskService, logger)
+		r.With(risk.Authorized(riskService, auth.ActionAdminister)).Group(func(r chi.Router) {
+			admin.RegisterHandlers(r, logLevel, logger)
+		})
+	})
 
 	return r
 }
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/vikasgithub/risky-plumbers/pkg/client"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return flags
+}
+
+func parse(flags *flag.FlagSet, args []string, nargs int, usage string) error {
+	if err := flags.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if flags.NArg() != nargs {
+		return usageError(fmt.Errorf("usage: riskctl %s %s", flags.Name(), usage))
+	}
+	return nil
+}
+
+func listAll(ctx context.Context, c *client.Client) ([]*client.Risk, error) {
+	var risks []*client.Risk
+	it := c.List(ctx)
+	for it.Next() {
+		risks = append(risks, it.Risk())
+	}
+	return risks, it.Err()
+}
+
+// reconnectDelay is how long list --watch waits before opening the change stream again once it ended
+var reconnectDelay = time.Second
+
+func listCommand(ctx context.Context, env *environment, args []string) error {
+	flags := newFlagSet("list")
+	watch := flags.Bool("watch", false, "follow the changes of the risks until interrupted")
+	if err := parse(flags, args, 0, "[--watch]"); err != nil {
+		return err
+	}
+	if *watch {
+		return watchCommand(ctx, env)
+	}
+	risks, err := listAll(ctx, env.client)
+	if err != nil {
+		return err
+	}
+	return printRisks(env.stdout, env.output, risks)
+}
+
+// watchCommand lists the risks, then follows the change stream of the service. The table is redrawn on every
+// change, the json and yaml outputs print the events after the list.
+func watchCommand(ctx context.Context, env *environment) error {
+	// the stream is opened first, so that the changes made while listing are not missed
+	stream, err := env.client.Events(ctx, "")
+	if err != nil {
+		return err
+	}
+	risks, err := listAll(ctx, env.client)
+	if err != nil {
+		stream.Close()
+		return err
+	}
+	if err := printWatched(env, risks, nil); err != nil {
+		stream.Close()
+		return err
+	}
+	for {
+		for stream.Next() {
+			event := stream.Event()
+			risks = applyEvent(risks, event)
+			if err := printWatched(env, risks, event); err != nil {
+				stream.Close()
+				return err
+			}
+		}
+		stream.Close()
+		if ctx.Err() != nil {
+			return nil
+		}
+		// the stream ended, e.g. the instance of the service shut down, the missed events are replayed on reconnect
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(reconnectDelay):
+		}
+		if stream, err = env.client.Events(ctx, stream.LastEventID()); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// applyEvent returns the risks changed by the event
+func applyEvent(risks []*client.Risk, event *client.Event) []*client.Risk {
+	if event.Risk == nil {
+		return risks
+	}
+	for i, risk := range risks {
+		if risk.ID == event.Risk.ID {
+			risks[i] = event.Risk
+			return risks
+		}
+	}
+	return append(risks, event.Risk)
+}
+
+// printWatched redraws the table of the risks, or prints the event in the json and yaml outputs
+func printWatched(env *environment, risks []*client.Risk, event *client.Event) error {
+	if env.output == outputTable {
+		// clear the screen before redrawing the table
+		fmt.Fprint(env.stdout, "\033[H\033[2J")
+		return printRisks(env.stdout, env.output, risks)
+	}
+	if event == nil {
+		return printRisks(env.stdout, env.output, risks)
+	}
+	return printEvent(env.stdout, env.output, event)
+}
+
+func getCommand(ctx context.Context, env *environment, args []string) error {
+	flags := newFlagSet("get")
+	if err := parse(flags, args, 1, "<id>"); err != nil {
+		return err
+	}
+	risk, err := env.client.Get(ctx, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	return printRisk(env.stdout, env.output, risk)
+}
+
+func createCommand(ctx context.Context, env *environment, args []string) error {
+	flags := newFlagSet("create")
+	state := flags.String("state", "open", "state of the risk")
+	title := flags.String("title", "", "title of the risk")
+	description := flags.String("description", "", "description of the risk")
+	confidential := flags.Bool("confidential", false, "redact the description for the callers who may not read confidential risks")
+	tags := flags.String("tags", "", "comma separated tags of the risk")
+	if err := parse(flags, args, 0, "--title <title> --description <description> [--state open] [--confidential] [--tags a,b]"); err != nil {
+		return err
+	}
+	input := &client.CreateRiskRequest{
+		State:        *state,
+		Title:        *title,
+		Description:  *description,
+		Confidential: *confidential,
+	}
+	if *tags != "" {
+		input.Tags = strings.Split(*tags, ",")
+	}
+	risk, err := env.client.Create(ctx, input)
+	if err != nil {
+		return err
+	}
+	return printRisk(env.stdout, env.output, risk)
+}
+
+// updateCommand sends only the flags which are set, the other fields of the risk keep their value
+func updateCommand(ctx context.Context, env *environment, args []string) error {
+	flags := newFlagSet("update")
+	state := flags.String("state", "", "new state of the risk")
+	title := flags.String("title", "", "new title of the risk")
+	description := flags.String("description", "", "new description of the risk")
+	confidential := flags.Bool("confidential", false, "redact the description for the callers who may not read confidential risks")
+	tags := flags.String("tags", "", "comma separated tags replacing the tags of the risk, empty to remove them")
+	// the id comes first, the flags are parsed after it
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return usageError(fmt.Errorf("usage: riskctl update %s", updateUsage))
+	}
+	id := args[0]
+	if err := parse(flags, args[1:], 0, updateUsage); err != nil {
+		return err
+	}
+	input := &client.UpdateRiskRequest{}
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "state":
+			input.State = state
+		case "title":
+			input.Title = title
+		case "description":
+			input.Description = description
+		case "confidential":
+			input.Confidential = confidential
+		case "tags":
+			values := []string{}
+			if *tags != "" {
+				values = strings.Split(*tags, ",")
+			}
+			input.Tags = &values
+		}
+	})
+	if *input == (client.UpdateRiskRequest{}) {
+		return usageError(errors.New("update: set at least one of the flags"))
+	}
+	risk, err := env.client.Update(ctx, id, input)
+	if err != nil {
+		return err
+	}
+	return printRisk(env.stdout, env.output, risk)
+}
+
+const updateUsage = "<id> [--state <state>] [--title <title>] [--description <description>] [--confidential=true|false] [--tags a,b]"
+
+func transitionCommand(ctx context.Context, env *environment, args []string) error {
+	flags := newFlagSet("transition")
+	if err := parse(flags, args, 2, "<id> <state>"); err != nil {
+		return err
+	}
+	risk, err := env.client.Transition(ctx, flags.Arg(0), flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	return printRisk(env.stdout, env.output, risk)
+}
+
+func exportCommand(ctx context.Context, env *environment, args []string) error {
+	flags := newFlagSet("export")
+	file := flags.String("f", "", "file to write to, defaults to stdout")
+	if err := parse(flags, args, 0, "[-f <file>]"); err != nil {
+		return err
+	}
+	format := env.output
+	if format == outputTable {
+		format = outputJSON
+	}
+	risks, err := env.client.Export(ctx)
+	if err != nil {
+		return err
+	}
+	if *file == "" {
+		return printRisks(env.stdout, format, risks)
+	}
+	f, err := os.Create(*file)
+	if err != nil {
+		return err
+	}
+	if err := printRisks(f, format, risks); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// importCommand creates the risks of an export. Every risk is created with an idempotency key derived from its
+// content, so that an import which failed halfway can be run again.
+func importCommand(ctx context.Context, env *environment, args []string) error {
+	flags := newFlagSet("import")
+	if err := parse(flags, args, 1, "<file|->"); err != nil {
+		return err
+	}
+	var data []byte
+	var err error
+	if flags.Arg(0) == "-" {
+		data, err = io.ReadAll(env.stdin)
+	} else {
+		data, err = os.ReadFile(flags.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+	var risks []*client.CreateRiskRequest
+	// YAML is a superset of JSON, therefore this reads both formats
+	if err := yaml.Unmarshal(data, &risks); err != nil {
+		return usageError(fmt.Errorf("parsing %s: %w", flags.Arg(0), err))
+	}
+
+	var created []*client.Risk
+	for i, input := range risks {
+		key, _ := json.Marshal(input)
+		sum := sha256.Sum256(key)
+		risk, err := env.client.CreateWithKey(ctx, "import-"+hex.EncodeToString(sum[:]), input)
+		if err != nil {
+			return fmt.Errorf("importing risk %d: %w", i+1, err)
+		}
+		created = append(created, risk)
+	}
+	return printRisks(env.stdout, env.output, created)
+}
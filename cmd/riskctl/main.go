@@ -0,0 +1,162 @@
+// Command riskctl is the command line client of the risky plumbers service.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/vikasgithub/risky-plumbers/pkg/client"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// exit codes
+const (
+	exitOK           = 0
+	exitError        = 1
+	exitUsage        = 2
+	exitNotFound     = 3
+	exitInvalid      = 4
+	exitConflict     = 5
+	exitUnauthorized = 7
+	exitForbidden    = 8
+)
+
+const usage = `Usage: riskctl [global flags] <command> [flags] [args]
+
+Commands:
+  list                       list the risks
+  get <id>                   show a risk
+  create                     create a risk
+  update <id>                change the fields of a risk
+  transition <id> <state>    move a risk to another state
+  export                     write all the risks of the tenant as JSON or YAML
+  import <file>              create the risks listed in a JSON or YAML file
+
+Global flags:
+`
+
+type usageErr struct {
+	error
+}
+
+func usageError(err error) error {
+	return usageErr{err}
+}
+
+type command func(ctx context.Context, env *environment, args []string) error
+
+var commands = map[string]command{
+	"list":       listCommand,
+	"get":        getCommand,
+	"create":     createCommand,
+	"update":     updateCommand,
+	"transition": transitionCommand,
+	"export":     exportCommand,
+	"import":     importCommand,
+}
+
+// environment is shared by all the commands
+type environment struct {
+	client *client.Client
+	output string
+	stdout io.Writer
+	stdin  io.Reader
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("riskctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	configPath := flags.String("config", "", "path to the profile file (default "+defaultProfilePath()+")")
+	profileName := flags.String("profile", "", "profile to use, defaults to the current profile of the profile file")
+	url := flags.String("url", "", "url of the service, overrides the profile")
+	output := flags.String("o", "", "output format: table, json or yaml")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n", flags.Arg(0))
+		flags.Usage()
+		return exitUsage
+	}
+
+	path := *configPath
+	if path == "" {
+		path = defaultProfilePath()
+	}
+	p, err := loadProfile(path, *profileName, *configPath != "")
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	if *url != "" {
+		p.URL = *url
+	}
+	if *output != "" {
+		p.Output = *output
+	}
+
+	if apiKey := os.Getenv("RISKCTL_API_KEY"); apiKey != "" {
+		p.APIKey = apiKey
+	}
+	if token := os.Getenv("RISKCTL_TOKEN"); token != "" {
+		p.Token = token
+	}
+	if tenant := os.Getenv("RISKCTL_TENANT"); tenant != "" {
+		p.Tenant = tenant
+	}
+
+	env := &environment{
+		client: client.New(p.URL, client.WithAPIKey(p.APIKey), client.WithBearerToken(p.Token), client.WithTenant(p.Tenant)),
+		output: p.Output,
+		stdout: stdout,
+		stdin:  stdin,
+	}
+	if err := cmd(ctx, env, flags.Args()[1:]); err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitCode(err)
+	}
+	return exitOK
+}
+
+// exitCode maps an error to the exit code of the process
+func exitCode(err error) int {
+	var usage usageErr
+	switch {
+	case errors.As(err, &usage):
+		return exitUsage
+	case errors.Is(err, client.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, client.ErrInvalidRequest):
+		return exitInvalid
+	case errors.Is(err, client.ErrConflict):
+		return exitConflict
+	case errors.Is(err, client.ErrUnauthorized):
+		return exitUnauthorized
+	case errors.Is(err, client.ErrForbidden):
+		return exitForbidden
+	}
+	return exitError
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/stretchr/testify/assert"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"github.com/vikasgithub/risky-plumbers/internal/changefeed"
+	"github.com/vikasgithub/risky-plumbers/internal/idempotency"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"github.com/vikasgithub/risky-plumbers/internal/tenant"
+	"github.com/vikasgithub/risky-plumbers/pkg/client"
+	"gopkg.in/yaml.v3"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newServer starts the risk api, with its change stream, requiring the API keys admin-key or viewer-key
+func newServer(t *testing.T) *httptest.Server {
+	logger := log.New()
+	store := risk.NewStore(logger)
+	broker := risk.NewBroker(100)
+	service := risk.NewService(store, logger, risk.WithPolicy(auth.DefaultPolicy()))
+	authenticator, err := auth.NewAPIKeyAuthenticator([]auth.APIKey{
+		{Name: "admin", Key: "admin-key", Roles: []string{auth.RoleAdmin}},
+		{Name: "viewer", Key: "viewer-key", Roles: []string{auth.RoleViewer}},
+	})
+	assert.NoError(t, err)
+
+	api := chi.NewRouter()
+	api.Use(auth.Middleware(authenticator, logger))
+	api.Use(tenant.Middleware())
+	api.Use(idempotency.Middleware(idempotency.NewMemoryStore(), time.Hour, logger))
+	risk.RegisterHandlers(api, service, logger)
+	changefeed.RegisterHandlers(api, broker, 50*time.Millisecond, logger)
+	r := chi.NewRouter()
+	r.Use(render.SetContentType(render.ContentTypeJSON))
+	r.Mount("/api/v1", api)
+	server := httptest.NewServer(r)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	relay := risk.NewRelay(store, risk.RelayConfig{PollInterval: 10 * time.Millisecond, BatchSize: 10}, logger, broker)
+	go relay.Run(ctx)
+	t.Cleanup(func() {
+		cancel()
+		broker.Close()
+		server.Close()
+	})
+	return server
+}
+
+// syncBuffer is written by a running command while the test reads it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// isolate keeps the tests from reading the profile file and the environment of the user
+func isolate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("RISKCTL_API_KEY", "")
+	t.Setenv("RISKCTL_TOKEN", "")
+	t.Setenv("RISKCTL_TENANT", "")
+}
+
+// riskctl runs the command and returns its exit code, output and error output
+func riskctl(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, strings.NewReader(""), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestExitCodes(t *testing.T) {
+	isolate(t)
+	server := newServer(t)
+	admin := []string{"--url", server.URL}
+	t.Setenv("RISKCTL_API_KEY", "admin-key")
+
+	for name, test := range map[string]struct {
+		args []string
+		code int
+	}{
+		"No Command":       {nil, exitUsage},
+		"Unknown Command":  {[]string{"delete", "1"}, exitUsage},
+		"Unknown Flag":     {append(admin, "list", "--interval", "1s"), exitUsage},
+		"Missing Argument": {append(admin, "get"), exitUsage},
+		"Unknown Output":   {append(admin, "-o", "xml", "list"), exitUsage},
+		"Help":             {[]string{"-h"}, exitOK},
+		"Not Found":        {append(admin, "get", "unknown"), exitNotFound},
+		"Invalid":          {append(admin, "create", "--title", "t"), exitInvalid},
+		"Unreachable":      {[]string{"--url", "http://127.0.0.1:1", "list"}, exitError},
+	} {
+		t.Run(name, func(t *testing.T) {
+			code, _, _ := riskctl(test.args...)
+			assert.Equal(t, test.code, code)
+		})
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		t.Setenv("RISKCTL_API_KEY", "wrong-key")
+		code, _, _ := riskctl(append(admin, "list")...)
+		assert.Equal(t, exitUnauthorized, code)
+	})
+
+	t.Run("Forbidden", func(t *testing.T) {
+		t.Setenv("RISKCTL_API_KEY", "viewer-key")
+		code, _, _ := riskctl(append(admin, "create", "--title", "t", "--description", "d")...)
+		assert.Equal(t, exitForbidden, code)
+	})
+}
+
+func TestProfiles(t *testing.T) {
+	isolate(t)
+	server := newServer(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf(`
+current: broken
+profiles:
+  broken:
+    url: http://127.0.0.1:1
+    apiKey: viewer-key
+  test:
+    url: %s
+    output: json
+    apiKey: viewer-key
+`, server.URL)), 0o600))
+
+	t.Run("Current Profile", func(t *testing.T) {
+		code, _, _ := riskctl("--config", path, "list")
+		assert.Equal(t, exitError, code)
+	})
+
+	t.Run("Selected Profile", func(t *testing.T) {
+		code, stdout, stderr := riskctl("--config", path, "--profile", "test", "list")
+		assert.Equal(t, exitOK, code, stderr)
+		assert.JSONEq(t, `[]`, stdout, "the output of the profile is json")
+	})
+
+	t.Run("Flags Override The Profile", func(t *testing.T) {
+		code, stdout, _ := riskctl("--config", path, "--url", server.URL, "-o", "yaml", "--profile", "broken", "list")
+		assert.Equal(t, exitOK, code)
+		assert.Equal(t, "[]\n", stdout)
+	})
+
+	t.Run("Environment Overrides The Profile", func(t *testing.T) {
+		args := []string{"--config", path, "--profile", "test", "create", "--title", "t", "--description", "d"}
+		code, _, _ := riskctl(args...)
+		assert.Equal(t, exitForbidden, code, "the key of the profile is a viewer")
+		t.Setenv("RISKCTL_API_KEY", "admin-key")
+		code, _, stderr := riskctl(args...)
+		assert.Equal(t, exitOK, code, stderr)
+	})
+
+	t.Run("Unknown Profile", func(t *testing.T) {
+		code, _, stderr := riskctl("--config", path, "--profile", "prod", "list")
+		assert.Equal(t, exitUsage, code)
+		assert.Contains(t, stderr, `profile "prod" is not defined`)
+	})
+
+	t.Run("Missing Profile File", func(t *testing.T) {
+		code, _, _ := riskctl("--config", filepath.Join(t.TempDir(), "missing.yaml"), "list")
+		assert.Equal(t, exitUsage, code)
+	})
+}
+
+func TestOutputs(t *testing.T) {
+	isolate(t)
+	server := newServer(t)
+	t.Setenv("RISKCTL_API_KEY", "admin-key")
+	code, _, stderr := riskctl("--url", server.URL, "create", "--title", "leak", "--description", "water", "--tags", "Pipe,cellar")
+	assert.Equal(t, exitOK, code, stderr)
+
+	code, stdout, _ := riskctl("--url", server.URL, "list")
+	assert.Equal(t, exitOK, code)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Equal(t, []string{"ID", "STATE", "TITLE"}, strings.Fields(lines[0]))
+		assert.Equal(t, []string{"open", "leak"}, strings.Fields(lines[1])[1:])
+	}
+
+	var risks []*client.Risk
+	code, stdout, _ = riskctl("--url", server.URL, "-o", "json", "list")
+	assert.Equal(t, exitOK, code)
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &risks))
+	if assert.Len(t, risks, 1) {
+		assert.Equal(t, "water", risks[0].Description)
+		assert.Equal(t, []string{"pipe", "cellar"}, risks[0].Tags)
+	}
+
+	code, stdout, _ = riskctl("--url", server.URL, "-o", "yaml", "get", risks[0].ID)
+	assert.Equal(t, exitOK, code)
+	var found client.Risk
+	assert.NoError(t, yaml.Unmarshal([]byte(stdout), &found))
+	assert.Equal(t, *risks[0], found)
+}
+
+func TestUpdateAndTransition(t *testing.T) {
+	isolate(t)
+	server := newServer(t)
+	t.Setenv("RISKCTL_API_KEY", "admin-key")
+	c := client.New(server.URL, client.WithAPIKey("admin-key"))
+	created, err := c.Create(context.Background(), &client.CreateRiskRequest{State: "open", Title: "leak", Description: "water", Tags: []string{"pipe"}})
+	assert.NoError(t, err)
+	url := []string{"--url", server.URL, "-o", "json"}
+
+	var updated client.Risk
+	code, stdout, stderr := riskctl(append(url, "update", created.ID, "--title", "flood", "--tags", "")...)
+	assert.Equal(t, exitOK, code, stderr)
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &updated))
+	assert.Equal(t, client.Risk{ID: created.ID, State: "open", Title: "flood", Description: "water"}, updated,
+		"the fields left out keep their value")
+
+	code, stdout, stderr = riskctl(append(url, "transition", created.ID, "closed")...)
+	assert.Equal(t, exitOK, code, stderr)
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &updated))
+	assert.Equal(t, "closed", updated.State)
+
+	for name, test := range map[string]struct {
+		args []string
+		code int
+	}{
+		"No Field":          {[]string{"update", created.ID}, exitUsage},
+		"Missing Id":        {[]string{"update", "--title", "t"}, exitUsage},
+		"Missing State":     {[]string{"transition", created.ID}, exitUsage},
+		"Invalid State":     {[]string{"transition", created.ID, "gone"}, exitInvalid},
+		"Update Not Found":  {[]string{"update", "unknown", "--title", "t"}, exitNotFound},
+		"Transition Absent": {[]string{"transition", "unknown", "open"}, exitNotFound},
+	} {
+		t.Run(name, func(t *testing.T) {
+			code, _, _ := riskctl(append(url, test.args...)...)
+			assert.Equal(t, test.code, code)
+		})
+	}
+
+	t.Run("Forbidden", func(t *testing.T) {
+		t.Setenv("RISKCTL_API_KEY", "viewer-key")
+		code, _, _ := riskctl(append(url, "transition", created.ID, "open")...)
+		assert.Equal(t, exitForbidden, code)
+	})
+}
+
+func TestImportIsIdempotent(t *testing.T) {
+	isolate(t)
+	server := newServer(t)
+	t.Setenv("RISKCTL_API_KEY", "admin-key")
+	path := filepath.Join(t.TempDir(), "risks.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+- {state: open, title: leak, description: water}
+- {state: closed, title: rust, description: pipe}
+`), 0o600))
+
+	for i := 0; i < 2; i++ {
+		code, _, stderr := riskctl("--url", server.URL, "import", path)
+		assert.Equal(t, exitOK, code, stderr)
+	}
+	code, stdout, _ := riskctl("--url", server.URL, "-o", "json", "export")
+	assert.Equal(t, exitOK, code)
+	var risks []*client.Risk
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &risks))
+	assert.Len(t, risks, 2, "the second import created nothing")
+
+	code, _, _ = riskctl("--url", server.URL, "import", filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Equal(t, exitError, code)
+}
+
+func TestWatch(t *testing.T) {
+	isolate(t)
+	server := newServer(t)
+	t.Setenv("RISKCTL_API_KEY", "admin-key")
+	c := client.New(server.URL, client.WithAPIKey("admin-key"))
+	_, err := c.Create(context.Background(), &client.CreateRiskRequest{State: "open", Title: "listed", Description: "d"})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var stdout, stderr syncBuffer
+	done := make(chan int)
+	go func() {
+		done <- run(ctx, []string{"--url", server.URL, "-o", "json", "list", "--watch"}, strings.NewReader(""), &stdout, &stderr)
+	}()
+	assert.Eventually(t, func() bool { return strings.Contains(stdout.String(), "listed") }, 5*time.Second, 10*time.Millisecond)
+
+	_, err = c.Create(context.Background(), &client.CreateRiskRequest{State: "open", Title: "streamed", Description: "d"})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return strings.Contains(stdout.String(), "streamed") }, 5*time.Second, 10*time.Millisecond)
+	var event client.Event
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	assert.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &event))
+	assert.Equal(t, client.EventCreated, event.Type)
+	assert.Equal(t, "streamed", event.Risk.Title)
+
+	cancel()
+	select {
+	case code := <-done:
+		assert.Equal(t, exitOK, code, stderr.String())
+	case <-time.After(5 * time.Second):
+		t.Fatal("the watch did not stop")
+	}
+}
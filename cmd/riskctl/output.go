@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/vikasgithub/risky-plumbers/pkg/client"
+	"gopkg.in/yaml.v3"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// printRisks writes the risks in the given format
+func printRisks(w io.Writer, format string, risks []*client.Risk) error {
+	switch format {
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tSTATE\tTITLE")
+		for _, risk := range risks {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", risk.ID, risk.State, risk.Title)
+		}
+		return tw.Flush()
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if risks == nil {
+			risks = []*client.Risk{}
+		}
+		return encoder.Encode(risks)
+	case outputYAML:
+		return yaml.NewEncoder(w).Encode(risks)
+	}
+	return usageError(fmt.Errorf("unknown output format %q, use one of table, json or yaml", format))
+}
+
+// printRisk writes a single risk in the given format
+func printRisk(w io.Writer, format string, risk *client.Risk) error {
+	switch format {
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "ID:\t%s\n", risk.ID)
+		fmt.Fprintf(tw, "State:\t%s\n", risk.State)
+		fmt.Fprintf(tw, "Title:\t%s\n", risk.Title)
+		fmt.Fprintf(tw, "Description:\t%s\n", risk.Description)
+		if risk.Confidential {
+			fmt.Fprintf(tw, "Confidential:\t%t\n", risk.Confidential)
+		}
+		if len(risk.Tags) > 0 {
+			fmt.Fprintf(tw, "Tags:\t%s\n", strings.Join(risk.Tags, ", "))
+		}
+		return tw.Flush()
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(risk)
+	case outputYAML:
+		return yaml.NewEncoder(w).Encode(risk)
+	}
+	return usageError(fmt.Errorf("unknown output format %q, use one of table, json or yaml", format))
+}
+
+// printEvent writes a change of a risk in the json or yaml format
+func printEvent(w io.Writer, format string, event *client.Event) error {
+	switch format {
+	case outputJSON:
+		return json.NewEncoder(w).Encode(event)
+	case outputYAML:
+		return yaml.NewEncoder(w).Encode(event)
+	}
+	return usageError(fmt.Errorf("unknown output format %q, use one of table, json or yaml", format))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+)
+
+const defaultURL = "http://localhost:8080"
+
+// profile holds the settings used to reach one deployment of the service
+type profile struct {
+	URL    string `yaml:"url"`
+	Output string `yaml:"output"`
+	// APIKey or Token authenticate the requests, the RISKCTL_API_KEY and RISKCTL_TOKEN environment variables
+	// take precedence
+	APIKey string `yaml:"apiKey"`
+	Token  string `yaml:"token"`
+	// Tenant selects the tenant when the service does not take it from the credentials, RISKCTL_TENANT takes
+	// precedence
+	Tenant string `yaml:"tenant"`
+}
+
+// profileFile is the content of the riskctl configuration file, e.g.
+//
+//	current: dev
+//	profiles:
+//	  dev:
+//	    url: http://localhost:8080
+//	    output: table
+type profileFile struct {
+	Current  string             `yaml:"current"`
+	Profiles map[string]profile `yaml:"profiles"`
+}
+
+func defaultProfilePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "riskctl", "config.yaml")
+}
+
+// loadProfile reads the named profile from path. The current profile of the file is used when name is empty.
+// A missing file is not an error when the path is the default one.
+func loadProfile(path, name string, explicitPath bool) (profile, error) {
+	p := profile{URL: defaultURL, Output: outputTable}
+	if path == "" {
+		return p, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !explicitPath {
+			return p, nil
+		}
+		return p, err
+	}
+	var file profileFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return p, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if name == "" {
+		name = file.Current
+	}
+	if name == "" {
+		return p, nil
+	}
+	found, ok := file.Profiles[name]
+	if !ok {
+		return p, fmt.Errorf("profile %q is not defined in %s", name, path)
+	}
+	if found.URL != "" {
+		p.URL = found.URL
+	}
+	if found.Output != "" {
+		p.Output = found.Output
+	}
+	p.APIKey = found.APIKey
+	p.Token = found.Token
+	p.Tenant = found.Tenant
+	return p, nil
+}
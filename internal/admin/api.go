@@ -0,0 +1,85 @@
+// Package admin serves the administrative operations of the service.
+package admin
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	validation "github.com/go-ozzo/ozzo-validation"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/i18n"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/request"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"net/http"
+)
+
+// Levels are the log levels which can be set at runtime
+var Levels = []interface{}{"debug", "info", "warn", "error"}
+
+// LogLevel is the level of the logger of the service.
+type LogLevel struct {
+	Level string `json:"level"`
+}
+
+func (ll *LogLevel) Bind(r *http.Request) error {
+	return nil
+}
+
+func (ll *LogLevel) Validate() error {
+	return validation.ValidateStruct(ll,
+		validation.Field(&ll.Level,
+			errorstype.Coded(validation.Required, errorstype.CodeRequired, nil),
+			errorstype.Coded(validation.In(Levels...), errorstype.CodeInvalidValue,
+				map[string]interface{}{"allowed": Levels})),
+	)
+}
+
+func (ll *LogLevel) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+type resource struct {
+	level  zap.AtomicLevel
+	logger log.Logger
+}
+
+// RegisterHandlers registers the endpoints reading and changing the log level. The caller authorizes them.
+func RegisterHandlers(r chi.Router, level zap.AtomicLevel, logger log.Logger) {
+	res := resource{level, logger}
+
+	r.Get("/admin/log-level", res.getLogLevel)
+	r.Put("/admin/log-level", res.putLogLevel)
+}
+
+func (res resource) getLogLevel(w http.ResponseWriter, r *http.Request) {
+	render.Render(w, r, &LogLevel{Level: res.level.Level().String()})
+}
+
+func (res resource) putLogLevel(w http.ResponseWriter, r *http.Request) {
+	data := &LogLevel{}
+	if err := request.BindJSON(r, data); err != nil {
+		render.Render(w, r, errorstype.ErrBind(err))
+		return
+	}
+	if err := data.Validate(); err != nil {
+		render.Render(w, r, errorstype.ErrValidation(err, i18n.RequestLanguage(r)))
+		return
+	}
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(data.Level)); err != nil {
+		render.Render(w, r, errorstype.ErrInvalidRequest(err))
+		return
+	}
+	previous := res.level.Level()
+	res.level.SetLevel(level)
+	// the change is logged at the new level at least, so that it is not filtered out by itself
+	entryLevel := zapcore.InfoLevel
+	if level > entryLevel {
+		entryLevel = level
+	}
+	if entry := res.logger.WithContext(r.Context()).Desugar().Check(entryLevel, "changed the log level"); entry != nil {
+		entry.Write(zap.Stringer("from", previous), zap.Stringer("to", level))
+	}
+	render.Render(w, r, &LogLevel{Level: level.String()})
+}
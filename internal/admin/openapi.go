@@ -0,0 +1,40 @@
+package admin
+
+import (
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/openapi"
+	"net/http"
+)
+
+// Describe adds the operations registered by RegisterHandlers to the OpenAPI document.
+func Describe(doc *openapi.Document) {
+	levelSchema := openapi.SchemaOf(LogLevel{})
+	levelSchema.Required = []string{"level"}
+	levelSchema.Properties["level"].Enum = Levels
+	levelRef := doc.AddSchema("LogLevel", levelSchema)
+	errRef := doc.AddSchema("ErrResponse", openapi.SchemaOf(errorstype.ErrResponse{}))
+
+	doc.AddOperation(http.MethodGet, "/admin/log-level", &openapi.Operation{
+		OperationID: "getLogLevel",
+		Summary:     "Get the log level of the service",
+		Tags:        []string{"admin"},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSON("The log level", levelRef),
+			"403": openapi.JSON("Missing the administer permission", errRef),
+		},
+	})
+	doc.AddOperation(http.MethodPut, "/admin/log-level", &openapi.Operation{
+		OperationID: "setLogLevel",
+		Summary:     "Change the log level of the service until it restarts",
+		Tags:        []string{"admin"},
+		RequestBody: &openapi.RequestBody{
+			Required: true,
+			Content:  map[string]openapi.MediaType{"application/json": {Schema: levelRef}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSON("The new log level", levelRef),
+			"400": openapi.JSON("Invalid level", errRef),
+			"403": openapi.JSON("Missing the administer permission", errRef),
+		},
+	})
+}
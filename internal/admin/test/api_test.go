@@ -0,0 +1,47 @@
+package admintest
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/vikasgithub/risky-plumbers/internal/admin"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogLevel(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zap.InfoLevel)
+	router := chi.NewRouter()
+	admin.RegisterHandlers(router, level, log.New())
+	send := func(method, body string) *httptest.ResponseRecorder {
+		rq := httptest.NewRequest(method, "/admin/log-level", strings.NewReader(body))
+		rq.Header.Set("Content-Type", "application/json")
+		rs := httptest.NewRecorder()
+		router.ServeHTTP(rs, rq)
+		return rs
+	}
+
+	rs := send(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, rs.Code)
+	assert.JSONEq(t, `{"level":"info"}`, rs.Body.String())
+
+	rs = send(http.MethodPut, `{"level":"debug"}`)
+	assert.Equal(t, http.StatusOK, rs.Code)
+	assert.JSONEq(t, `{"level":"debug"}`, rs.Body.String())
+	assert.Equal(t, zap.DebugLevel, level.Level())
+
+	t.Run("Unknown Level", func(t *testing.T) {
+		rs := send(http.MethodPut, `{"level":"verbose"}`)
+		assert.Equal(t, http.StatusBadRequest, rs.Code)
+		assert.Contains(t, rs.Body.String(), `"field":"level"`)
+		assert.Equal(t, zap.DebugLevel, level.Level())
+	})
+
+	t.Run("Missing Level", func(t *testing.T) {
+		rs := send(http.MethodPut, `{}`)
+		assert.Equal(t, http.StatusBadRequest, rs.Code)
+	})
+}
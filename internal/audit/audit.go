@@ -0,0 +1,64 @@
+// Package audit records who accessed sensitive data.
+package audit
+
+import (
+	"context"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/tenant"
+	"time"
+)
+
+// Audited actions
+const (
+	ActionReadConfidentialRisk = "risk.read-confidential"
+)
+
+// Entry is an audited access.
+type Entry struct {
+	Time   time.Time
+	Action string
+	// Subject and Method identify the principal, they are empty when the authentication is disabled
+	Subject  string
+	Method   string
+	TenantID string
+	// ResourceID is the id of the accessed resource
+	ResourceID string
+	// Redacted reports whether the sensitive fields were hidden from the principal
+	Redacted bool
+}
+
+// NewEntry returns an entry of the action on the resource by the principal of ctx, in the tenant of ctx.
+func NewEntry(ctx context.Context, action, resourceID string) Entry {
+	entry := Entry{Time: time.Now().UTC(), Action: action, TenantID: tenant.FromContext(ctx), ResourceID: resourceID}
+	if principal, ok := auth.PrincipalFrom(ctx); ok {
+		entry.Subject, entry.Method = principal.Subject, principal.Method
+	}
+	return entry
+}
+
+// Logger records the audit entries.
+type Logger interface {
+	Record(ctx context.Context, entry Entry)
+}
+
+type logger struct {
+	logger log.Logger
+}
+
+// NewLogger returns a Logger writing the entries to the application log, marked with "audit": true so that they
+// can be shipped apart. The entries are neither sampled nor filtered by the level of the service, see log.Logger.Audit.
+func NewLogger(l log.Logger) Logger {
+	return &logger{log.Logger{SugaredLogger: l.Audit().With("audit", true)}}
+}
+
+func (l *logger) Record(ctx context.Context, entry Entry) {
+	l.logger.WithContext(ctx).Infow(entry.Action,
+		"time", entry.Time,
+		"subject", entry.Subject,
+		"method", entry.Method,
+		"tenantId", entry.TenantID,
+		"resourceId", entry.ResourceID,
+		"redacted", entry.Redacted,
+	)
+}
@@ -0,0 +1,80 @@
+package audittest
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/vikasgithub/risky-plumbers/internal/audit"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/tenant"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewEntry(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "ada", Method: auth.MethodSession})
+	ctx = tenant.WithTenant(ctx, "acme")
+
+	entry := audit.NewEntry(ctx, audit.ActionReadConfidentialRisk, "risk-1")
+	assert.Equal(t, "ada", entry.Subject)
+	assert.Equal(t, auth.MethodSession, entry.Method)
+	assert.Equal(t, "acme", entry.TenantID)
+	assert.Equal(t, "risk-1", entry.ResourceID)
+	assert.WithinDuration(t, time.Now(), entry.Time, time.Second)
+
+	anonymous := audit.NewEntry(context.Background(), audit.ActionReadConfidentialRisk, "risk-1")
+	assert.Empty(t, anonymous.Subject)
+	assert.Equal(t, tenant.Default, anonymous.TenantID)
+}
+
+func TestLogger(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	auditor := audit.NewLogger(log.Logger{SugaredLogger: zap.New(core).Sugar()})
+
+	entry := audit.Entry{Action: audit.ActionReadConfidentialRisk, Subject: "ada", TenantID: "acme", ResourceID: "risk-1", Redacted: true}
+	auditor.Record(context.Background(), entry)
+
+	if assert.Equal(t, 1, logs.Len()) {
+		logged := logs.All()[0]
+		assert.Equal(t, audit.ActionReadConfidentialRisk, logged.Message)
+		fields := logged.ContextMap()
+		assert.Equal(t, true, fields["audit"])
+		assert.Equal(t, "ada", fields["subject"])
+		assert.Equal(t, "risk-1", fields["resourceId"])
+		assert.Equal(t, true, fields["redacted"])
+	}
+}
+
+func TestLoggerIsNotSampled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "service.log")
+	logger, level, err := log.NewWithConfig(log.Config{
+		Level:       "info",
+		Encoding:    "json",
+		OutputPaths: []string{path},
+		Sampling:    log.SamplingConfig{Enabled: true, Initial: 2, Thereafter: 100},
+	})
+	assert.NoError(t, err)
+	auditor := audit.NewLogger(logger)
+
+	entry := audit.Entry{Action: audit.ActionReadConfidentialRisk, Subject: "ada", TenantID: "acme", ResourceID: "risk-1"}
+	for i := 0; i < 10; i++ {
+		auditor.Record(context.Background(), entry)
+		logger.Info("repeated")
+	}
+	level.SetLevel(zap.ErrorLevel)
+	for i := 0; i < 5; i++ {
+		auditor.Record(context.Background(), entry)
+		logger.Info("repeated")
+	}
+	assert.NoError(t, logger.Sync())
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 15, strings.Count(string(content), audit.ActionReadConfidentialRisk))
+	assert.Equal(t, 2, strings.Count(string(content), "repeated"))
+}
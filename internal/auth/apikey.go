@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// APIKey configures an accepted API key. Either Key or Hash is set; Hash is the hex encoded SHA-256
+// of the key, so that the key itself does not need to be stored in the configuration.
+type APIKey struct {
+	Name  string
+	Key   string
+	Hash  string
+	Roles []string
+	// Tenant is the tenant the key gives access to
+	Tenant string
+}
+
+type apiKeyAuthenticator struct {
+	keys []apiKey
+}
+
+type apiKey struct {
+	name   string
+	hash   []byte
+	roles  []string
+	tenant string
+}
+
+// HashAPIKey returns the value to configure as APIKey.Hash for the given key.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewAPIKeyAuthenticator returns an Authenticator accepting the given keys.
+func NewAPIKeyAuthenticator(keys []APIKey) (Authenticator, error) {
+	a := &apiKeyAuthenticator{}
+	for _, key := range keys {
+		hash := key.Hash
+		if key.Key != "" {
+			hash = HashAPIKey(key.Key)
+		}
+		decoded, err := hex.DecodeString(strings.ToLower(hash))
+		if err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("api key %q must have a key or a hex encoded sha256 hash", key.Name)
+		}
+		a.keys = append(a.keys, apiKey{name: key.Name, hash: decoded, roles: key.Roles, tenant: key.Tenant})
+	}
+	return a, nil
+}
+
+func (a *apiKeyAuthenticator) Authenticate(ctx context.Context, credentials Credentials) (*Principal, error) {
+	if credentials.APIKey == "" {
+		return nil, ErrNoCredentials
+	}
+	sum := sha256.Sum256([]byte(credentials.APIKey))
+	for _, key := range a.keys {
+		if subtle.ConstantTimeCompare(sum[:], key.hash) == 1 {
+			return &Principal{Subject: key.name, Method: MethodAPIKey, Roles: key.roles, Tenant: key.tenant}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unknown api key", ErrInvalidCredentials)
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"strings"
+)
+
+// ClientCertificate maps the subject of a client certificate to a principal. Subject is the distinguished name of
+// the certificate when it contains "=", e.g. "CN=ci,OU=Platform,O=Acme", else its common name, e.g. "ci".
+type ClientCertificate struct {
+	Subject string
+	Roles   []string
+	// Tenant is the tenant the certificate gives access to
+	Tenant string
+}
+
+type clientCertificateAuthenticator struct {
+	distinguishedNames map[string]ClientCertificate
+	commonNames        map[string]ClientCertificate
+}
+
+// NewClientCertificateAuthenticator returns an Authenticator accepting the verified client certificates of the
+// given subjects. The certificates are verified by the TLS handshake against the client CA bundle.
+func NewClientCertificateAuthenticator(certificates []ClientCertificate) (Authenticator, error) {
+	a := &clientCertificateAuthenticator{
+		distinguishedNames: map[string]ClientCertificate{},
+		commonNames:        map[string]ClientCertificate{},
+	}
+	for _, certificate := range certificates {
+		if certificate.Subject == "" {
+			return nil, fmt.Errorf("a client certificate must have a subject")
+		}
+		subjects := a.commonNames
+		if strings.Contains(certificate.Subject, "=") {
+			subjects = a.distinguishedNames
+		}
+		if _, ok := subjects[certificate.Subject]; ok {
+			return nil, fmt.Errorf("client certificate subject %q is configured twice", certificate.Subject)
+		}
+		subjects[certificate.Subject] = certificate
+	}
+	return a, nil
+}
+
+func (a *clientCertificateAuthenticator) Authenticate(ctx context.Context, credentials Credentials) (*Principal, error) {
+	certificate := credentials.ClientCertificate
+	if certificate == nil {
+		return nil, ErrNoCredentials
+	}
+	// the distinguished name is more specific than the common name. A common name such as "CN=admin" must not
+	// match a configured distinguished name, nor a distinguished name a configured common name
+	configured, ok := a.distinguishedNames[certificate.Subject.String()]
+	if !ok && certificate.Subject.CommonName != "" {
+		configured, ok = a.commonNames[certificate.Subject.CommonName]
+	}
+	if ok {
+		return &Principal{Subject: configured.Subject, Method: MethodClientCertificate, Roles: configured.Roles,
+			Tenant: configured.Tenant}, nil
+	}
+	return nil, fmt.Errorf("%w: unknown client certificate subject %q", ErrInvalidCredentials, certificate.Subject)
+}
+
+// VerifiedClientCertificate returns the leaf certificate the client authenticated with during the handshake, or
+// nil when the client sent no certificate or it was not verified against the client CA bundle.
+func VerifiedClientCertificate(state *tls.ConnectionState) *x509.Certificate {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0][0]
+}
@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// signatureAlgorithms are the algorithms accepted for the tokens, symmetric algorithms are not supported with a JWKS
+var signatureAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512, jose.PS256, jose.PS384, jose.PS512, jose.ES256, jose.ES384, jose.ES512, jose.EdDSA,
+}
+
+// leeway is the clock skew tolerated when checking the validity of a token
+const leeway = time.Minute
+
+// JWTConfig configures the validation of the bearer tokens.
+type JWTConfig struct {
+	// JWKSFile or JWKSURL locate the key set used to verify the token signatures
+	JWKSFile string
+	JWKSURL  string
+	// RefreshInterval is how often the key set is fetched again from JWKSURL
+	RefreshInterval time.Duration
+	Issuer          string
+	Audience        string
+	// RolesClaim is the name of the claim holding the roles of the subject
+	RolesClaim string
+	// TenantClaim is the name of the claim holding the tenant of the subject
+	TenantClaim string
+}
+
+// KeySource provides the keys used to verify the tokens.
+type KeySource interface {
+	Keys(ctx context.Context, keyID string) ([]jose.JSONWebKey, error)
+}
+
+// NewKeySource returns the key source configured by JWKSFile or JWKSURL.
+func NewKeySource(config JWTConfig, client *http.Client) (KeySource, error) {
+	switch {
+	case config.JWKSFile != "":
+		data, err := os.ReadFile(config.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		var keySet jose.JSONWebKeySet
+		if err := json.Unmarshal(data, &keySet); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", config.JWKSFile, err)
+		}
+		return staticKeySource{keySet}, nil
+	case config.JWKSURL != "":
+		return &remoteKeySource{url: config.JWKSURL, client: client, refreshInterval: config.RefreshInterval}, nil
+	}
+	return nil, errors.New("either a jwks file or a jwks url is required")
+}
+
+type staticKeySource struct {
+	keySet jose.JSONWebKeySet
+}
+
+func (s staticKeySource) Keys(ctx context.Context, keyID string) ([]jose.JSONWebKey, error) {
+	return keysWithID(s.keySet, keyID), nil
+}
+
+// keysWithID returns the keys with the given id, or all the keys when the token does not name its key
+func keysWithID(keySet jose.JSONWebKeySet, keyID string) []jose.JSONWebKey {
+	if keyID == "" {
+		return keySet.Keys
+	}
+	return keySet.Key(keyID)
+}
+
+// remoteKeySource caches the key set fetched from a url. The key set is fetched again after the refresh
+// interval, or when a token is signed with an unknown key, at most once per minRefetchInterval. The key set is
+// fetched without holding the lock, and the concurrent callers share the fetch in flight.
+type remoteKeySource struct {
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+
+	mu        sync.Mutex
+	keySet    jose.JSONWebKeySet
+	fetchedAt time.Time
+	// inFlight is the fetch in progress, nil when there is none
+	inFlight *keySetFetch
+}
+
+// keySetFetch is a fetch of the key set, done is closed once err is set
+type keySetFetch struct {
+	done chan struct{}
+	err  error
+}
+
+const minRefetchInterval = 10 * time.Second
+
+func (s *remoteKeySource) Keys(ctx context.Context, keyID string) ([]jose.JSONWebKey, error) {
+	s.mu.Lock()
+	age := time.Since(s.fetchedAt)
+	keys := keysWithID(s.keySet, keyID)
+	if !s.fetchedAt.IsZero() && age <= s.refreshInterval && (len(keys) > 0 || age <= minRefetchInterval) {
+		s.mu.Unlock()
+		return keys, nil
+	}
+	call := s.inFlight
+	if call == nil {
+		call = &keySetFetch{done: make(chan struct{})}
+		s.inFlight = call
+		s.mu.Unlock()
+		s.refresh(ctx, call)
+	} else {
+		s.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fetchedAt.IsZero() {
+		return nil, call.err
+	}
+	// the cached keys are still used when the key set is not available
+	return keysWithID(s.keySet, keyID), nil
+}
+
+// refresh fetches the key set for call and caches it
+func (s *remoteKeySource) refresh(ctx context.Context, call *keySetFetch) {
+	keySet, err := s.fetch(ctx)
+	s.mu.Lock()
+	if err == nil {
+		s.keySet = keySet
+		s.fetchedAt = time.Now()
+	}
+	call.err = err
+	s.inFlight = nil
+	s.mu.Unlock()
+	close(call.done)
+}
+
+func (s *remoteKeySource) fetch(ctx context.Context) (jose.JSONWebKeySet, error) {
+	var keySet jose.JSONWebKeySet
+	rq, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return keySet, err
+	}
+	rs, err := s.client.Do(rq)
+	if err != nil {
+		return keySet, err
+	}
+	defer rs.Body.Close()
+	if rs.StatusCode != http.StatusOK {
+		return keySet, fmt.Errorf("fetching %s returned status %d", s.url, rs.StatusCode)
+	}
+	err = json.NewDecoder(io.LimitReader(rs.Body, 1<<20)).Decode(&keySet)
+	return keySet, err
+}
+
+type jwtAuthenticator struct {
+	keys   KeySource
+	config JWTConfig
+}
+
+// NewJWTAuthenticator returns an Authenticator accepting the bearer tokens signed with a key of the key source.
+func NewJWTAuthenticator(keys KeySource, config JWTConfig) Authenticator {
+	return &jwtAuthenticator{keys, config}
+}
+
+func (a *jwtAuthenticator) Authenticate(ctx context.Context, credentials Credentials) (*Principal, error) {
+	if credentials.BearerToken == "" {
+		return nil, ErrNoCredentials
+	}
+	claims, custom, err := VerifyJWT(ctx, a.keys, credentials.BearerToken, a.config.Issuer, a.config.Audience)
+	if err != nil {
+		return nil, err
+	}
+	tenant, _ := custom[a.config.TenantClaim].(string)
+	return &Principal{
+		Subject: claims.Subject,
+		Method:  MethodJWT,
+		Roles:   StringList(custom[a.config.RolesClaim]),
+		Tenant:  tenant,
+	}, nil
+}
+
+// VerifyJWT checks the signature of a token against the keys of the key source, its expiry, and its issuer and
+// audience when they are not empty. It returns the registered and all the claims of the token. The errors of
+// invalid tokens wrap ErrInvalidCredentials.
+func VerifyJWT(ctx context.Context, keys KeySource, raw, issuer, audience string) (*jwt.Claims, map[string]interface{}, error) {
+	token, err := jwt.ParseSigned(raw, signatureAlgorithms)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	candidates, err := keys.Keys(ctx, token.Headers[0].KeyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var claims jwt.Claims
+	custom := map[string]interface{}{}
+	verified := false
+	for _, key := range candidates {
+		if err := token.Claims(key.Public(), &claims, &custom); err == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, nil, fmt.Errorf("%w: the token signature can not be verified", ErrInvalidCredentials)
+	}
+	expected := jwt.Expected{Issuer: issuer, Time: time.Now()}
+	if audience != "" {
+		expected.AnyAudience = jwt.Audience{audience}
+	}
+	if err := claims.ValidateWithLeeway(expected, leeway); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	if claims.Expiry == nil {
+		return nil, nil, fmt.Errorf("%w: the token has no expiry", ErrInvalidCredentials)
+	}
+	return &claims, custom, nil
+}
+
+// StringList returns the strings of a claim, which is either a string or a list of strings
+func StringList(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var list []string
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"errors"
+	"github.com/go-chi/render"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"net/http"
+	"strings"
+)
+
+// HeaderAPIKey is the header carrying the API key
+const HeaderAPIKey = "X-API-Key"
+
+// SessionCookie is the cookie carrying the id of the session of a browser logged in with OIDC
+const SessionCookie = "rp_session"
+
+// CredentialsFromRequest reads the API key header, the bearer token of the Authorization header, the
+// session cookie and the verified client certificate.
+func CredentialsFromRequest(r *http.Request) Credentials {
+	credentials := Credentials{
+		APIKey:            r.Header.Get(HeaderAPIKey),
+		BearerToken:       BearerToken(r.Header.Get("Authorization")),
+		ClientCertificate: VerifiedClientCertificate(r.TLS),
+	}
+	if cookie, err := r.Cookie(SessionCookie); err == nil {
+		credentials.SessionID = cookie.Value
+	}
+	return credentials
+}
+
+// BearerToken returns the token of an Authorization header value using the Bearer scheme.
+func BearerToken(authorization string) string {
+	scheme, token, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// Middleware rejects the requests which can not be authenticated with 401 and puts the principal
+// of the others into the request context.
+func Middleware(authenticator Authenticator, logger log.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := authenticator.Authenticate(r.Context(), CredentialsFromRequest(r))
+			if err != nil {
+				if !errors.Is(err, ErrNoCredentials) && !errors.Is(err, ErrInvalidCredentials) {
+					logger.WithContext(r.Context()).Errorf("failed to authenticate the request: %v", err)
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer, ApiKey header="`+HeaderAPIKey+`"`)
+				render.Render(w, r, errorstype.ErrUnauthorized(err))
+				return
+			}
+			log.AddAccessFields(r.Context(), "subject", principal.Subject, "authMethod", principal.Method)
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
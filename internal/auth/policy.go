@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"sort"
+	"strings"
+)
+
+// Actions on risks and on the service
+const (
+	ActionRead                 = "read"
+	ActionCreate               = "create"
+	ActionUpdate               = "update"
+	ActionTransitionToAccepted = "transition-to-accepted"
+	// ActionReadConfidential reveals the confidential fields of the risks, they are redacted without it
+	ActionReadConfidential = "read-confidential"
+	// ActionAdminister runs the administrative operations of the service, e.g. changing the log level
+	ActionAdminister = "administer"
+	// ActionManageWebhooks creates, reads and deletes the webhook subscriptions and reads their deliveries
+	ActionManageWebhooks = "manage-webhooks"
+)
+
+// Roles of the default policy
+const (
+	RoleViewer      = "viewer"
+	RoleContributor = "contributor"
+	RoleRiskOwner   = "risk-owner"
+	RoleAdmin       = "admin"
+	// RoleSecurity is the role of the security team, which reads the confidential risks
+	RoleSecurity = "security"
+)
+
+var actions = []string{ActionRead, ActionCreate, ActionUpdate, ActionTransitionToAccepted, ActionReadConfidential,
+	ActionAdminister, ActionManageWebhooks}
+
+// Policy maps a role to the actions it allows.
+type Policy map[string][]string
+
+// DefaultPolicy returns the policy used when the configuration does not define one.
+func DefaultPolicy() Policy {
+	return Policy{
+		RoleViewer:      {ActionRead},
+		RoleContributor: {ActionRead, ActionCreate, ActionUpdate},
+		RoleRiskOwner:   {ActionRead, ActionCreate, ActionUpdate, ActionTransitionToAccepted},
+		RoleAdmin:       actions,
+		RoleSecurity:    {ActionRead, ActionReadConfidential},
+	}
+}
+
+// Validate returns an error if the policy refers to an unknown action.
+func (p Policy) Validate() error {
+	roles := make([]string, 0, len(p))
+	for role := range p {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		for _, action := range p[role] {
+			if !contains(actions, action) {
+				return fmt.Errorf("role %q allows the unknown action %q, the actions are %s",
+					role, action, strings.Join(actions, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// Allows reports whether one of the roles allows the action. Role names are not case sensitive.
+func (p Policy) Allows(roles []string, action string) bool {
+	for _, role := range roles {
+		if contains(p[strings.ToLower(role)], action) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize returns an *errorstype.ForbiddenError unless the principal of ctx has a role allowing the action.
+func (p Policy) Authorize(ctx context.Context, action string) error {
+	principal, ok := PrincipalFrom(ctx)
+	if !ok || !p.Allows(principal.Roles, action) {
+		return &errorstype.ForbiddenError{Permission: action}
+	}
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+// Package auth authenticates the callers of the api using API keys, JWT bearer tokens or client certificates.
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+)
+
+// Authentication methods
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+	// MethodSession authenticates the browsers logged in with OIDC by their session cookie
+	MethodSession = "session"
+	// MethodClientCertificate authenticates the callers by the client certificate of a mutual TLS connection
+	MethodClientCertificate = "client_certificate"
+)
+
+var (
+	// ErrNoCredentials is returned by an Authenticator when the request has no credentials it can check
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials is returned when the credentials are unknown, expired or badly signed
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal is the authenticated caller.
+type Principal struct {
+	// Subject is the name of the API key, the subject of the token or the subject of the client certificate
+	Subject string
+	// Method is the authentication method, MethodAPIKey, MethodJWT, MethodSession or MethodClientCertificate
+	Method string
+	Roles  []string
+	// Tenant is the tenant the principal acts on, the default tenant when empty
+	Tenant string
+}
+
+// Credentials are the credentials sent with a request.
+type Credentials struct {
+	APIKey      string
+	BearerToken string
+	// SessionID is the value of the session cookie
+	SessionID string
+	// ClientCertificate is the verified client certificate of a mutual TLS connection
+	ClientCertificate *x509.Certificate
+}
+
+// Authenticator checks one kind of credentials.
+type Authenticator interface {
+	// Authenticate returns the principal of the credentials, ErrNoCredentials if the credentials
+	// it checks are missing, or an error wrapping ErrInvalidCredentials.
+	Authenticate(ctx context.Context, credentials Credentials) (*Principal, error)
+}
+
+// Chain tries the authenticators in order until one of them finds its credentials.
+type Chain []Authenticator
+
+func (c Chain) Authenticate(ctx context.Context, credentials Credentials) (*Principal, error) {
+	for _, authenticator := range c {
+		principal, err := authenticator.Authenticate(ctx, credentials)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return principal, err
+	}
+	return nil, ErrNoCredentials
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the principal of the request, if it was authenticated.
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
@@ -0,0 +1,285 @@
+package authtest
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// issuer is a local stand-in for an identity provider, serving its key set and signing tokens
+type issuer struct {
+	key    *rsa.PrivateKey
+	keyID  string
+	server *httptest.Server
+}
+
+func newIssuer(t *testing.T) *issuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	i := &issuer{key: key, keyID: "test-key"}
+	i.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(i.keySet())
+	}))
+	t.Cleanup(i.server.Close)
+	return i
+}
+
+func (i *issuer) keySet() jose.JSONWebKeySet {
+	return jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &i.key.PublicKey, KeyID: i.keyID, Algorithm: string(jose.RS256), Use: "sig"},
+	}}
+}
+
+func (i *issuer) token(t *testing.T, claims jwt.Claims, roles ...string) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: i.key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", i.keyID))
+	assert.NoError(t, err)
+	token, err := jwt.Signed(signer).Claims(claims).Claims(map[string]interface{}{"roles": roles, "tenant": "acme"}).Serialize()
+	assert.NoError(t, err)
+	return token
+}
+
+func validClaims() jwt.Claims {
+	return jwt.Claims{
+		Subject:  "alice",
+		Issuer:   "https://issuer.test",
+		Audience: jwt.Audience{"risky-plumbers"},
+		Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		IssuedAt: jwt.NewNumericDate(time.Now()),
+	}
+}
+
+var jwtConfig = auth.JWTConfig{
+	RefreshInterval: time.Hour,
+	Issuer:          "https://issuer.test",
+	Audience:        "risky-plumbers",
+	RolesClaim:      "roles",
+	TenantClaim:     "tenant",
+}
+
+// newRouter returns a router answering with the subject of the authenticated principal
+func newRouter(authenticator auth.Authenticator) *chi.Mux {
+	router := chi.NewRouter()
+	router.Use(auth.Middleware(authenticator, log.New()))
+	router.Get("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := auth.PrincipalFrom(r.Context())
+		json.NewEncoder(w).Encode(principal)
+	})
+	return router
+}
+
+func get(router http.Handler, header, value string) *httptest.ResponseRecorder {
+	rq, _ := http.NewRequest("GET", "/whoami", nil)
+	if header != "" {
+		rq.Header.Set(header, value)
+	}
+	rs := httptest.NewRecorder()
+	router.ServeHTTP(rs, rq)
+	return rs
+}
+
+func TestAPIKeys(t *testing.T) {
+	authenticator, err := auth.NewAPIKeyAuthenticator([]auth.APIKey{
+		{Name: "clear", Key: "clear-key", Roles: []string{"reader"}, Tenant: "acme"},
+		{Name: "hashed", Hash: auth.HashAPIKey("hashed-key")},
+	})
+	assert.NoError(t, err)
+	router := newRouter(authenticator)
+
+	rs := get(router, auth.HeaderAPIKey, "clear-key")
+	assert.Equal(t, http.StatusOK, rs.Code)
+	assert.JSONEq(t, `{"Subject":"clear","Method":"api_key","Roles":["reader"],"Tenant":"acme"}`, rs.Body.String())
+
+	rs = get(router, auth.HeaderAPIKey, "hashed-key")
+	assert.Equal(t, http.StatusOK, rs.Code)
+	assert.Contains(t, rs.Body.String(), `"Subject":"hashed"`)
+
+	rs = get(router, auth.HeaderAPIKey, "unknown-key")
+	assert.Equal(t, http.StatusUnauthorized, rs.Code)
+	assert.JSONEq(t, `{"status":"Unauthorized."}`, rs.Body.String())
+
+	rs = get(router, "", "")
+	assert.Equal(t, http.StatusUnauthorized, rs.Code)
+	assert.NotEmpty(t, rs.Header().Get("WWW-Authenticate"))
+}
+
+func TestInvalidAPIKeyConfig(t *testing.T) {
+	_, err := auth.NewAPIKeyAuthenticator([]auth.APIKey{{Name: "broken", Hash: "not a hash"}})
+	assert.Error(t, err)
+}
+
+func TestClientCertificates(t *testing.T) {
+	authenticator, err := auth.NewClientCertificateAuthenticator([]auth.ClientCertificate{
+		{Subject: "ci", Roles: []string{"viewer"}},
+		{Subject: "CN=deploy,O=Acme", Roles: []string{"admin"}, Tenant: "acme"},
+	})
+	assert.NoError(t, err)
+	authenticate := func(subject pkix.Name) (*auth.Principal, error) {
+		return authenticator.Authenticate(context.Background(),
+			auth.Credentials{ClientCertificate: &x509.Certificate{Subject: subject}})
+	}
+
+	principal, err := authenticate(pkix.Name{CommonName: "ci", Organization: []string{"Acme"}})
+	assert.NoError(t, err)
+	assert.Equal(t, &auth.Principal{Subject: "ci", Method: auth.MethodClientCertificate, Roles: []string{"viewer"}}, principal)
+
+	principal, err = authenticate(pkix.Name{CommonName: "deploy", Organization: []string{"Acme"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "CN=deploy,O=Acme", principal.Subject)
+	assert.Equal(t, "acme", principal.Tenant)
+
+	_, err = authenticate(pkix.Name{CommonName: "deploy", Organization: []string{"Globex"}})
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials, "the distinguished name must match")
+
+	_, err = authenticate(pkix.Name{CommonName: "CN=deploy,O=Acme"})
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials, "a common name never matches a distinguished name")
+
+	_, err = authenticator.Authenticate(context.Background(), auth.Credentials{})
+	assert.ErrorIs(t, err, auth.ErrNoCredentials)
+
+	_, err = auth.NewClientCertificateAuthenticator([]auth.ClientCertificate{{Subject: "ci"}, {Subject: "ci"}})
+	assert.Error(t, err)
+}
+
+func TestJWT(t *testing.T) {
+	idp := newIssuer(t)
+	config := jwtConfig
+	config.JWKSURL = idp.server.URL
+	keySource, err := auth.NewKeySource(config, http.DefaultClient)
+	assert.NoError(t, err)
+	router := newRouter(auth.NewJWTAuthenticator(keySource, config))
+
+	t.Run("Valid Token", func(t *testing.T) {
+		rs := get(router, "Authorization", "Bearer "+idp.token(t, validClaims(), "admin"))
+		assert.Equal(t, http.StatusOK, rs.Code)
+		assert.JSONEq(t, `{"Subject":"alice","Method":"jwt","Roles":["admin"],"Tenant":"acme"}`, rs.Body.String())
+	})
+
+	t.Run("Expired Token", func(t *testing.T) {
+		claims := validClaims()
+		claims.Expiry = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+		rs := get(router, "Authorization", "Bearer "+idp.token(t, claims))
+		assert.Equal(t, http.StatusUnauthorized, rs.Code)
+	})
+
+	t.Run("Token Without Expiry", func(t *testing.T) {
+		claims := validClaims()
+		claims.Expiry = nil
+		rs := get(router, "Authorization", "Bearer "+idp.token(t, claims))
+		assert.Equal(t, http.StatusUnauthorized, rs.Code)
+	})
+
+	t.Run("Wrong Issuer", func(t *testing.T) {
+		claims := validClaims()
+		claims.Issuer = "https://someone.else"
+		rs := get(router, "Authorization", "Bearer "+idp.token(t, claims))
+		assert.Equal(t, http.StatusUnauthorized, rs.Code)
+	})
+
+	t.Run("Wrong Audience", func(t *testing.T) {
+		claims := validClaims()
+		claims.Audience = jwt.Audience{"another-service"}
+		rs := get(router, "Authorization", "Bearer "+idp.token(t, claims))
+		assert.Equal(t, http.StatusUnauthorized, rs.Code)
+	})
+
+	t.Run("Unknown Signing Key", func(t *testing.T) {
+		other := newIssuer(t)
+		rs := get(router, "Authorization", "Bearer "+other.token(t, validClaims()))
+		assert.Equal(t, http.StatusUnauthorized, rs.Code)
+	})
+
+	t.Run("Malformed Token", func(t *testing.T) {
+		rs := get(router, "Authorization", "Bearer not-a-token")
+		assert.Equal(t, http.StatusUnauthorized, rs.Code)
+	})
+}
+
+func TestJWKSFile(t *testing.T) {
+	idp := newIssuer(t)
+	data, _ := json.Marshal(idp.keySet())
+	config := jwtConfig
+	config.JWKSFile = filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(config.JWKSFile, data, 0o600))
+
+	keySource, err := auth.NewKeySource(config, http.DefaultClient)
+	assert.NoError(t, err)
+	principal, err := auth.NewJWTAuthenticator(keySource, config).
+		Authenticate(context.Background(), auth.Credentials{BearerToken: idp.token(t, validClaims())})
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", principal.Subject)
+}
+
+func TestChain(t *testing.T) {
+	idp := newIssuer(t)
+	config := jwtConfig
+	config.JWKSURL = idp.server.URL
+	keySource, _ := auth.NewKeySource(config, http.DefaultClient)
+	apiKeys, _ := auth.NewAPIKeyAuthenticator([]auth.APIKey{{Name: "ci", Key: "ci-key"}})
+	router := newRouter(auth.Chain{apiKeys, auth.NewJWTAuthenticator(keySource, config)})
+
+	assert.Equal(t, http.StatusOK, get(router, auth.HeaderAPIKey, "ci-key").Code)
+	assert.Equal(t, http.StatusOK, get(router, "Authorization", "Bearer "+idp.token(t, validClaims())).Code)
+	assert.Equal(t, http.StatusUnauthorized, get(router, "Authorization", "Basic Y2k6Y2kta2V5").Code)
+}
+
+func TestJWKSFetchIsShared(t *testing.T) {
+	idp := newIssuer(t)
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		json.NewEncoder(w).Encode(idp.keySet())
+	}))
+	t.Cleanup(server.Close)
+	config := jwtConfig
+	config.JWKSURL = server.URL
+	keySource, err := auth.NewKeySource(config, http.DefaultClient)
+	assert.NoError(t, err)
+	authenticator := auth.NewJWTAuthenticator(keySource, config)
+	token := idp.token(t, validClaims())
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := authenticator.Authenticate(context.Background(), auth.Credentials{BearerToken: token})
+			errs <- err
+		}()
+	}
+	assert.Eventually(t, func() bool { return fetches.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// the fetch does not hold the lock, so a caller can give up while it is in flight
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = keySource.Keys(ctx, idp.keyID)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), fetches.Load(), "the callers shared the fetch")
+}
@@ -0,0 +1,214 @@
+// Package changefeed streams the changes of risks to clients using Server-Sent Events and WebSockets.
+package changefeed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"github.com/vikasgithub/risky-plumbers/internal/tenant"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type resource struct {
+	broker    *risk.Broker
+	heartbeat time.Duration
+	upgrader  websocket.Upgrader
+	logger    log.Logger
+}
+
+// RegisterHandlers serves the events published to broker at /risks/events as Server-Sent Events and at
+// /risks/events/ws over a WebSocket. A heartbeat is sent every heartbeat interval to keep idle connections open.
+// The streams outlive the WriteTimeout of the server, see writeDeadline. They end when the broker is closed.
+func RegisterHandlers(r chi.Router, broker *risk.Broker, heartbeat time.Duration, logger log.Logger) {
+	res := resource{broker: broker, heartbeat: heartbeat, logger: logger}
+	r.Get("/risks/events", res.sse)
+	r.Get("/risks/events/ws", res.websocket)
+}
+
+// writeDeadline returns the deadline of the next write to a stream. It is moved forward before every write and
+// outlasts the next heartbeat, so that only a client which stops reading is dropped.
+func (res resource) writeDeadline() time.Time {
+	return time.Now().Add(2 * res.heartbeat)
+}
+
+// filter selects the events of the tenant of the request whose risk is in one of the states of the state query
+// parameters and has one of the tags of the tag query parameters. A filter without states or without tags does not
+// restrict the states or the tags.
+type filter struct {
+	tenantID string
+	states   map[string]bool
+	tags     map[string]bool
+}
+
+func newFilter(r *http.Request) filter {
+	query := r.URL.Query()
+	return filter{
+		tenantID: tenant.FromContext(r.Context()),
+		states:   valuesOf(query["state"]),
+		tags:     valuesOf(query["tag"]),
+	}
+}
+
+// valuesOf returns the comma separated values of the query parameters in lower case
+func valuesOf(parameters []string) map[string]bool {
+	values := map[string]bool{}
+	for _, parameter := range parameters {
+		for _, value := range strings.Split(parameter, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values[strings.ToLower(value)] = true
+			}
+		}
+	}
+	return values
+}
+
+func (f filter) matches(event risk.Event) bool {
+	if event.TenantID != f.tenantID {
+		return false
+	}
+	if len(f.states) > 0 && !f.states[strings.ToLower(event.Risk.State)] {
+		return false
+	}
+	if len(f.tags) == 0 {
+		return true
+	}
+	for _, tag := range event.Risk.Tags {
+		if f.tags[tag] {
+			return true
+		}
+	}
+	return false
+}
+
+func (res resource) sse(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	f := newFilter(r)
+	replay, events, unsubscribe := res.broker.SubscribeAfter(r.Header.Get("Last-Event-ID"))
+	defer unsubscribe()
+
+	// the write deadline of the server would end the stream, it is moved forward before every write
+	controller := http.NewResponseController(w)
+	extendDeadline := func() {
+		if err := controller.SetWriteDeadline(res.writeDeadline()); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			res.logger.WithContext(r.Context()).Debugf("failed to extend the write deadline of the stream: %v", err)
+		}
+	}
+	extendDeadline()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for _, event := range replay {
+		if f.matches(event) {
+			writeSSE(w, event)
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(res.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			extendDeadline()
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if f.matches(event) {
+				extendDeadline()
+				writeSSE(w, event)
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, event risk.Event) {
+	data, _ := json.Marshal(event)
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
+
+// websocket streams the events as JSON text messages. Browsers cannot set headers on WebSocket requests,
+// therefore the last event id is also accepted as the lastEventId query parameter.
+func (res resource) websocket(w http.ResponseWriter, r *http.Request) {
+	lastEventID := r.URL.Query().Get("lastEventId")
+	if lastEventID == "" {
+		lastEventID = r.Header.Get("Last-Event-ID")
+	}
+	conn, err := res.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade already replied with an error
+		return
+	}
+	defer conn.Close()
+
+	f := newFilter(r)
+	replay, events, unsubscribe := res.broker.SubscribeAfter(lastEventID)
+	defer unsubscribe()
+
+	// the client does not send messages, reading detects when it goes away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Upgrade cleared the deadlines of the server
+	write := func(event risk.Event) error {
+		conn.SetWriteDeadline(res.writeDeadline())
+		return conn.WriteJSON(event)
+	}
+	for _, event := range replay {
+		if f.matches(event) {
+			if err := write(event); err != nil {
+				return
+			}
+		}
+	}
+
+	ticker := time.NewTicker(res.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(res.heartbeat)); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if f.matches(event) {
+				if err := write(event); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
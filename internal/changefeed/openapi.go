@@ -0,0 +1,64 @@
+package changefeed
+
+import (
+	"github.com/vikasgithub/risky-plumbers/internal/openapi"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"net/http"
+)
+
+// Describe adds the operations registered by RegisterHandlers to the OpenAPI document.
+func Describe(doc *openapi.Document) {
+	eventRef := doc.AddSchema("RiskEvent", openapi.SchemaOf(risk.Event{}))
+	stateFilter := openapi.Parameter{
+		Name:        "state",
+		In:          "query",
+		Description: "Only stream the events of risks in the given states, comma separated or repeated",
+		Schema:      &openapi.Schema{Type: "array", Items: &openapi.Schema{Type: "string"}},
+	}
+	tagFilter := openapi.Parameter{
+		Name:        "tag",
+		In:          "query",
+		Description: "Only stream the events of risks having one of the given tags, comma separated or repeated",
+		Schema:      &openapi.Schema{Type: "array", Items: &openapi.Schema{Type: "string"}},
+	}
+
+	doc.AddOperation(http.MethodGet, "/risks/events", &openapi.Operation{
+		OperationID: "streamRiskEvents",
+		Summary:     "Stream the changes of risks as Server-Sent Events",
+		Tags:        []string{"events"},
+		Parameters: []openapi.Parameter{
+			stateFilter,
+			tagFilter,
+			{
+				Name:        "Last-Event-ID",
+				In:          "header",
+				Description: "Resume after the given event, replaying the buffered events published since",
+				Schema:      &openapi.Schema{Type: "string"},
+			},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {
+				Description: "Stream of events, the data of each event is a RiskEvent",
+				Content:     map[string]openapi.MediaType{"text/event-stream": {Schema: eventRef}},
+			},
+		},
+	})
+	doc.AddOperation(http.MethodGet, "/risks/events/ws", &openapi.Operation{
+		OperationID: "streamRiskEventsWebSocket",
+		Summary:     "Stream the changes of risks over a WebSocket, each message is a RiskEvent",
+		Tags:        []string{"events"},
+		Parameters: []openapi.Parameter{
+			stateFilter,
+			tagFilter,
+			{
+				Name:        "lastEventId",
+				In:          "query",
+				Description: "Resume after the given event, replaying the buffered events published since",
+				Schema:      &openapi.Schema{Type: "string"},
+			},
+		},
+		Responses: map[string]openapi.Response{
+			"101": {Description: "Switching to the WebSocket protocol"},
+		},
+	})
+}
@@ -0,0 +1,239 @@
+package changefeedtest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/vikasgithub/risky-plumbers/internal/changefeed"
+	"github.com/vikasgithub/risky-plumbers/internal/entity"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/openapi"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"github.com/vikasgithub/risky-plumbers/internal/tenant"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newServer(t *testing.T, broker *risk.Broker) *httptest.Server {
+	router := chi.NewRouter()
+	router.Use(tenant.Middleware())
+	changefeed.RegisterHandlers(router, broker, time.Minute, log.New())
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func publish(broker *risk.Broker, id, state string) {
+	publishFor(broker, tenant.Default, id, state)
+}
+
+func publishFor(broker *risk.Broker, tenantID, id, state string, tags ...string) {
+	broker.Publish(context.Background(), risk.Event{
+		ID:       id,
+		Type:     risk.EventCreated,
+		Time:     time.Now(),
+		TenantID: tenantID,
+		Risk:     &entity.Risk{ID: "risk-" + id, State: state, Title: "t", Description: "d", Tags: tags},
+	})
+}
+
+// readSSE returns the ids of the next n events of the stream
+func readSSE(t *testing.T, reader *bufio.Reader, n int) []string {
+	var ids []string
+	for len(ids) < n {
+		line, err := reader.ReadString('\n')
+		if !assert.NoError(t, err) {
+			return ids
+		}
+		if strings.HasPrefix(line, "data: ") {
+			var event risk.Event
+			assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+			ids = append(ids, event.ID)
+		}
+	}
+	return ids
+}
+
+func TestServerSentEvents(t *testing.T) {
+	broker := risk.NewBroker(10)
+	server := newServer(t, broker)
+	publish(broker, "1", "open")
+	publish(broker, "2", "closed")
+	publish(broker, "3", "open")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rq, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/risks/events?state=open", nil)
+	rq.Header.Set("Last-Event-ID", "1")
+	rs, err := http.DefaultClient.Do(rq)
+	assert.NoError(t, err)
+	defer rs.Body.Close()
+	assert.Equal(t, "text/event-stream", rs.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(rs.Body)
+	// event 2 is replayed but filtered out by its state
+	assert.Equal(t, []string{"3"}, readSSE(t, reader, 1))
+
+	publish(broker, "4", "closed")
+	publish(broker, "5", "open")
+	assert.Equal(t, []string{"5"}, readSSE(t, reader, 1))
+}
+
+func TestTagFilter(t *testing.T) {
+	broker := risk.NewBroker(10)
+	server := newServer(t, broker)
+	publishFor(broker, tenant.Default, "1", "open", "water")
+	publishFor(broker, tenant.Default, "2", "open", "rust")
+	publishFor(broker, tenant.Default, "3", "closed", "water")
+	publish(broker, "4", "open")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rq, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/risks/events?state=open&tag=Water,gas", nil)
+	rq.Header.Set("Last-Event-ID", "unknown")
+	rs, err := http.DefaultClient.Do(rq)
+	assert.NoError(t, err)
+	defer rs.Body.Close()
+
+	reader := bufio.NewReader(rs.Body)
+	// the states and the tags both have to match
+	assert.Equal(t, []string{"1"}, readSSE(t, reader, 1))
+
+	publishFor(broker, tenant.Default, "5", "open", "rust")
+	publishFor(broker, tenant.Default, "6", "open", "gas", "rust")
+	assert.Equal(t, []string{"6"}, readSSE(t, reader, 1))
+}
+
+func TestWebSocket(t *testing.T) {
+	broker := risk.NewBroker(10)
+	server := newServer(t, broker)
+	publish(broker, "1", "open")
+	publish(broker, "2", "open")
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/risks/events/ws?lastEventId=1"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var event risk.Event
+	assert.NoError(t, conn.ReadJSON(&event))
+	assert.Equal(t, "2", event.ID)
+
+	// the subscription is registered before the replay is sent, so no event is missed
+	publish(broker, "3", "open")
+	assert.NoError(t, conn.ReadJSON(&event))
+	assert.Equal(t, "3", event.ID)
+	assert.Equal(t, "risk-3", event.Risk.ID)
+}
+
+func TestEventsOfOtherTenantsAreNotStreamed(t *testing.T) {
+	broker := risk.NewBroker(10)
+	server := newServer(t, broker)
+	publishFor(broker, "acme", "1", "open")
+	publishFor(broker, "globex", "2", "open")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rq, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/risks/events", nil)
+	rq.Header.Set("Last-Event-ID", "unknown")
+	rq.Header.Set(tenant.Header, "globex")
+	rs, err := http.DefaultClient.Do(rq)
+	assert.NoError(t, err)
+	defer rs.Body.Close()
+
+	reader := bufio.NewReader(rs.Body)
+	assert.Equal(t, []string{"2"}, readSSE(t, reader, 1))
+
+	publishFor(broker, "acme", "3", "open")
+	publish(broker, "4", "open")
+	publishFor(broker, "globex", "5", "open")
+	assert.Equal(t, []string{"5"}, readSSE(t, reader, 1))
+}
+
+func TestReplayBufferIsBounded(t *testing.T) {
+	broker := risk.NewBroker(2)
+	publish(broker, "1", "open")
+	publish(broker, "2", "open")
+	publish(broker, "3", "open")
+
+	replay, _, unsubscribe := broker.SubscribeAfter("unknown")
+	defer unsubscribe()
+	assert.Len(t, replay, 2)
+	assert.Equal(t, "2", replay[0].ID)
+	assert.Equal(t, "3", replay[1].ID)
+}
+
+func TestStreamsOutliveTheWriteTimeout(t *testing.T) {
+	broker := risk.NewBroker(10)
+	router := chi.NewRouter()
+	router.Use(tenant.Middleware())
+	changefeed.RegisterHandlers(router, broker, 100*time.Millisecond, log.New())
+	server := httptest.NewUnstartedServer(router)
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rq, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/risks/events", nil)
+	rs, err := http.DefaultClient.Do(rq)
+	assert.NoError(t, err)
+	defer rs.Body.Close()
+	reader := bufio.NewReader(rs.Body)
+
+	time.Sleep(300 * time.Millisecond)
+	publish(broker, "1", "open")
+	assert.Equal(t, []string{"1"}, readSSE(t, reader, 1))
+}
+
+func TestClosingTheBrokerEndsTheStreams(t *testing.T) {
+	broker := risk.NewBroker(10)
+	server := newServer(t, broker)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rq, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/risks/events", nil)
+	rs, err := http.DefaultClient.Do(rq)
+	assert.NoError(t, err)
+	defer rs.Body.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/risks/events/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	broker.Close()
+	_, err = io.ReadAll(rs.Body)
+	assert.NoError(t, err, "the event stream ends")
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsUnexpectedCloseError(err) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF),
+		"the websocket is closed: %v", err)
+
+	// the streams opened afterwards end at once
+	_, events, unsubscribe := broker.SubscribeAfter("")
+	defer unsubscribe()
+	_, ok := <-events
+	assert.False(t, ok)
+}
+
+func TestOpenAPICoversAllRoutes(t *testing.T) {
+	router := chi.NewRouter()
+	changefeed.RegisterHandlers(router, risk.NewBroker(1), time.Minute, log.New())
+	doc := openapi.New("test", "test", "/api/v1")
+	changefeed.Describe(doc)
+
+	chi.Walk(router, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		assert.True(t, doc.HasOperation(method, route), "%s %s is not described in the OpenAPI document", method, route)
+		return nil
+	})
+}
@@ -4,21 +4,392 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/spf13/viper"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
 	"github.com/vikasgithub/risky-plumbers/internal/log"
 	"os"
+	"time"
 )
 
 const (
-	defaultServerPort = 8080
+	defaultServerPort                = 8080
+	defaultServerMaxRequestBodyBytes = 1 << 20
+	defaultServerReadHeaderTimeout   = 5 * time.Second
+	defaultServerReadTimeout         = 30 * time.Second
+	defaultServerWriteTimeout        = 30 * time.Second
+	defaultServerIdleTimeout         = 2 * time.Minute
+	defaultServerShutdownTimeout     = 30 * time.Second
+	defaultServerTLSMinVersion       = "1.2"
+	defaultServerTLSCipherPolicy     = "intermediate"
+	defaultServerTLSClientAuth       = "optional"
+	defaultServerTLSReloadInterval   = 10 * time.Second
+	defaultIdempotencyTTL            = 24 * time.Hour
+	defaultIdempotencyStore          = "memory"
+	defaultIdempotencyRedisKeyPrefix = "riskyplumbers:idempotency:"
+	defaultGRPCPort                  = 9090
+	defaultGraphQLMaxDepth           = 10
+	defaultGraphQLMaxComplexity      = 1000
+	defaultEventsReplaySize          = 1000
+	defaultEventsHeartbeat           = 15 * time.Second
+	defaultOutboxPollInterval        = 100 * time.Millisecond
+	defaultOutboxBatchSize           = 100
+	defaultBusDriver                 = "inprocess"
+	defaultBusTopicPrefix            = "riskyplumbers"
+	defaultJWKSRefreshInterval       = time.Hour
+	defaultJWTRolesClaim             = "roles"
+	defaultJWTTenantClaim            = "tenant"
+	defaultOIDCGroupsClaim           = "groups"
+	defaultOIDCTenantClaim           = "tenant"
+	defaultOIDCSessionTTL            = 8 * time.Hour
+	defaultOIDCPostLoginURL          = "/"
+	defaultOIDCMaxPendingLogins      = 10000
+	defaultRateLimitStore            = "memory"
+	defaultRateLimitRequests         = 600
+	defaultRateLimitPeriod           = time.Minute
+	defaultRateLimitBurst            = 100
+	defaultRateLimitAddressRequests  = 1200
+	defaultRateLimitAddressBurst     = 200
+	defaultRateLimitRedisKeyPrefix   = "riskyplumbers:ratelimit:"
+	defaultLogLevel                  = "info"
+	defaultLogEncoding               = "json"
+	defaultLogSamplingInitial        = 100
+	defaultLogSamplingThereafter     = 100
+	defaultLogRotationMaxSizeMB      = 100
+	defaultLogRotationMaxBackups     = 10
+	defaultLogRotationMaxAgeDays     = 30
+	defaultMetricsPort               = 8081
+	defaultMetricsPath               = "/metrics"
+	defaultTracingServiceName        = "risky-plumbers"
+	defaultTracingExporter           = "otlp"
+	defaultTracingProtocol           = "grpc"
+	defaultTracingSampleRatio        = 1.0
+	defaultHealthTimeout             = 2 * time.Second
+	defaultHealthDiskPath            = "."
+	defaultHealthMinFreeBytes        = 100 << 20
+	defaultHealthShutdownDelay       = 5 * time.Second
+	defaultWebhooksWorkers           = 4
+	defaultWebhooksQueueSize         = 1024
+	defaultWebhooksMaxAttempts       = 8
+	defaultWebhooksBaseDelay         = time.Second
+	defaultWebhooksMaxDelay          = 10 * time.Minute
+	defaultWebhooksTimeout           = 10 * time.Second
 )
 
 // Config represents an application configuration.
 type Config struct {
-	Server ServerConfig
+	Server      ServerConfig
+	Idempotency IdempotencyConfig
+	GRPC        GRPCConfig
+	GraphQL     GraphQLConfig
+	Events      EventsConfig
+	Outbox      OutboxConfig
+	Bus         BusConfig
+	Auth        AuthConfig
+	RBAC        RBACConfig
+	Webhooks    WebhooksConfig
+	RateLimit   RateLimitConfig
+	Log         LogConfig
+	Metrics     MetricsConfig
+	Tracing     TracingConfig
+	Health      HealthConfig
+	// Tenants overrides the validation of the risks per tenant, keyed by tenant id
+	Tenants map[string]TenantConfig
 }
 
 type ServerConfig struct {
 	Port int
+	// MaxRequestBodyBytes limits the size of request bodies accepted by the api
+	MaxRequestBodyBytes int64
+	// ReadHeaderTimeout and ReadTimeout limit the reading of the request headers and of the whole request
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	// WriteTimeout limits the writing of a response, the change streams are not limited by it
+	WriteTimeout time.Duration
+	// IdleTimeout is how long a keep-alive connection waits for the next request
+	IdleTimeout time.Duration
+	// ShutdownTimeout is the grace period given to the requests in flight and to the background workers once the
+	// service is stopped, the remaining connections are closed after it
+	ShutdownTimeout time.Duration
+	TLS             ServerTLSConfig
+}
+
+type ServerTLSConfig struct {
+	// Enabled serves the api and the gRPC API over TLS
+	Enabled  bool
+	CertFile string
+	KeyFile  string
+	// MinVersion is 1.2 or 1.3
+	MinVersion string
+	// CipherPolicy is intermediate, TLS 1.2 with the ECDHE AEAD cipher suites and TLS 1.3, or modern, TLS 1.3 only
+	CipherPolicy string
+	// ClientCAFile is the PEM bundle of the CAs issuing the client certificates, it enables mutual TLS
+	ClientCAFile string
+	// ClientAuth is optional, the client certificates are verified when they are sent, or required
+	ClientAuth string
+	// ReloadInterval is how often the files are checked for changes
+	ReloadInterval time.Duration
+}
+
+type IdempotencyConfig struct {
+	// TTL is how long the response of a request made with an Idempotency-Key is replayed
+	TTL time.Duration
+	// Store is memory, or redis to replay the responses from any instance of the service
+	Store string
+	Redis RedisConfig
+}
+
+type GRPCConfig struct {
+	Enabled bool
+	Port    int
+}
+
+type GraphQLConfig struct {
+	// MaxDepth limits the nesting of the selections of a query
+	MaxDepth int
+	// MaxComplexity limits the number of fields a query may resolve, see the graphqlapi package
+	MaxComplexity int
+}
+
+type EventsConfig struct {
+	// ReplaySize is the number of events kept to resume the change feed using Last-Event-ID
+	ReplaySize int
+	// Heartbeat is the interval of the heartbeats keeping idle change feed connections open
+	Heartbeat time.Duration
+}
+
+type OutboxConfig struct {
+	// PollInterval is the delay between two reads of the outbox by the relay
+	PollInterval time.Duration
+	// BatchSize is the maximum number of events read from the outbox at once
+	BatchSize int
+}
+
+type BusConfig struct {
+	// Enabled publishes the risk events to the message bus
+	Enabled bool
+	// Driver is either inprocess or nats
+	Driver string
+	// URL of the NATS server
+	URL string
+	// TopicPrefix is prepended to the event types to build the topics, e.g. riskyplumbers.risk.created
+	TopicPrefix string
+}
+
+type AuthConfig struct {
+	// Enabled requires the callers of the api to authenticate with an API key, a JWT bearer token or a session cookie
+	Enabled bool
+	APIKeys []APIKeyConfig
+	JWT     JWTConfig
+	OIDC    OIDCConfig
+	// ClientCertificates map the subjects of the client certificates of mutual TLS to principals
+	ClientCertificates []ClientCertificateConfig
+}
+
+type ClientCertificateConfig struct {
+	// Subject is the common name or the distinguished name of the certificate, e.g. CN=ci,O=Acme
+	Subject string
+	Roles   []string
+	// Tenant is the tenant the certificate gives access to, the default tenant when empty
+	Tenant string
+}
+
+type APIKeyConfig struct {
+	Name string
+	// Key is the API key in clear, or Hash its hex encoded SHA-256
+	Key   string
+	Hash  string
+	Roles []string
+	// Tenant is the tenant the key gives access to, the default tenant when empty
+	Tenant string
+}
+
+type JWTConfig struct {
+	// JWKSFile or JWKSURL locate the keys verifying the token signatures, JWT authentication is disabled without them
+	JWKSFile        string
+	JWKSURL         string
+	RefreshInterval time.Duration
+	// Issuer and Audience are required with a JWKS, the tokens must carry them
+	Issuer   string
+	Audience string
+	// RolesClaim is the name of the claim holding the roles of the subject
+	RolesClaim string
+	// TenantClaim is the name of the claim holding the tenant of the subject
+	TenantClaim string
+}
+
+type OIDCConfig struct {
+	// Enabled lets browsers log in at the provider of Issuer with the authorization code flow
+	Enabled      bool
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the url of /auth/callback registered at the provider
+	RedirectURL string
+	Scopes      []string
+	// GroupsClaim is the name of the ID token claim holding the groups of the user
+	GroupsClaim string
+	// TenantClaim is the name of the ID token claim holding the tenant of the user
+	TenantClaim string
+	// GroupRoles maps a group to the roles of its members, the group names are case-insensitive
+	GroupRoles map[string][]string
+	// SessionTTL is how long a login lasts
+	SessionTTL time.Duration
+	// CookieSecure sends the cookies over https only, disable it for local development over http
+	CookieSecure bool
+	// PostLoginURL is where the browser goes after the login unless it asked for another page
+	PostLoginURL string
+	// MaxPendingLogins limits the logins started and not completed yet, the next ones are refused
+	MaxPendingLogins int
+	// MockIdP serves a mock provider at the path of Issuer, it logs in its users without a password
+	MockIdP MockIdPConfig
+}
+
+type MockIdPConfig struct {
+	Enabled bool
+	// Users default to oidc.DefaultMockUsers
+	Users []MockUserConfig
+}
+
+type MockUserConfig struct {
+	Subject string
+	Name    string
+	Email   string
+	Groups  []string
+	Tenant  string
+}
+
+type RBACConfig struct {
+	// Policy maps a role to the actions it allows: read, create, update, transition-to-accepted, read-confidential,
+	// administer and manage-webhooks.
+	// The roles of the configuration are merged into the default policy, see auth.DefaultPolicy
+	Policy map[string][]string
+}
+
+type TenantConfig struct {
+	// MaxTitleLength and MaxDescriptionLength override the default limits when set
+	MaxTitleLength       int
+	MaxDescriptionLength int
+	// States replaces the states a risk of the tenant may have when set
+	States []string
+}
+
+type WebhooksConfig struct {
+	// Workers is the number of webhook deliveries sent concurrently
+	Workers int
+	// QueueSize is the number of webhook deliveries waiting for a worker, the deliveries published while the queue
+	// is full are queued again after BaseDelay
+	QueueSize int
+	// MaxAttempts is the number of attempts before a delivery is moved to the dead-letter queue
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, it doubles with every retry up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Timeout limits the duration of a single delivery attempt
+	Timeout time.Duration
+	// AllowedNetworks are the CIDRs of the internal receivers, e.g. 10.20.0.0/16. The deliveries are only sent to
+	// public addresses otherwise
+	AllowedNetworks []string
+}
+
+type RateLimitConfig struct {
+	// Enabled throttles the callers of the api, keyed by principal or IP address
+	Enabled bool
+	// Store is memory, or redis to share the buckets between the instances of the service
+	Store string
+	Redis RedisConfig
+	// Address applies to all the requests of an IP address before they are authenticated, so that the callers with
+	// invalid credentials are throttled too
+	Address RateLimitLimitConfig
+	// Default applies to the requests matching none of the Routes
+	Default RateLimitLimitConfig
+	// Routes are checked in order, the first route matching a request applies
+	Routes []RateLimitRouteConfig
+}
+
+type RateLimitLimitConfig struct {
+	// Requests are allowed per Period on average, in bursts of up to Burst requests
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+type RateLimitRouteConfig struct {
+	// Name identifies the bucket of the route, it defaults to the method and path
+	Name string
+	// Method matches every method when empty
+	Method string
+	// Path is a pattern matched against the request path, e.g. /api/v1/risks/*
+	Path     string
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+	// KeyPrefix is prepended to the keys written by the service
+	KeyPrefix string
+}
+
+type LogConfig struct {
+	// Level is debug, info, warn or error, it can be changed at runtime with PUT /api/v1/admin/log-level
+	Level string
+	// Encoding is json or console
+	Encoding    string
+	Development bool
+	Sampling    LogSamplingConfig
+	// OutputPaths are stdout, stderr or file paths
+	OutputPaths []string
+	Rotation    LogRotationConfig
+}
+
+type LogSamplingConfig struct {
+	Enabled bool
+	// Initial entries with the same level and message are kept each second, then every Thereafter-th entry
+	Initial    int
+	Thereafter int
+}
+
+type LogRotationConfig struct {
+	// Enabled rotates the files of the OutputPaths
+	Enabled    bool
+	MaxSizeMB  int
+	MaxBackups int
+	MaxAgeDays int
+	Compress   bool
+}
+
+type MetricsConfig struct {
+	Enabled bool
+	// Port is the admin port serving the metrics, apart from the api
+	Port int
+	Path string
+}
+
+type TracingConfig struct {
+	Enabled     bool
+	ServiceName string
+	// Exporter is otlp or stdout
+	Exporter string
+	// Protocol is the protocol of the otlp exporter, grpc or http
+	Protocol string
+	// Endpoint is the host:port of the otlp collector, localhost:4317 for grpc and localhost:4318 for http when empty
+	Endpoint string
+	Insecure bool
+	// SampleRatio is the ratio of the new traces which are sampled
+	SampleRatio float64
+}
+
+type HealthConfig struct {
+	// Timeout limits the duration of each readiness check
+	Timeout time.Duration
+	// DiskPath is a path on the file system whose free space is checked
+	DiskPath     string
+	MinFreeBytes uint64
+	// ShutdownDelay is how long /readyz responds 503 before the server stops accepting connections, so that the
+	// load balancers stop sending requests first
+	ShutdownDelay time.Duration
 }
 
 // Load returns an application configuration which is populated from the given configuration file and environment variables.
@@ -31,6 +402,82 @@ func Load(file string, logger log.Logger) (*Config, error) {
 		return nil, err
 	}
 	viper.SetDefault("server.port", defaultServerPort)
+	viper.SetDefault("server.maxRequestBodyBytes", defaultServerMaxRequestBodyBytes)
+	viper.SetDefault("server.readHeaderTimeout", defaultServerReadHeaderTimeout)
+	viper.SetDefault("server.readTimeout", defaultServerReadTimeout)
+	viper.SetDefault("server.writeTimeout", defaultServerWriteTimeout)
+	viper.SetDefault("server.idleTimeout", defaultServerIdleTimeout)
+	viper.SetDefault("server.shutdownTimeout", defaultServerShutdownTimeout)
+	viper.SetDefault("server.tls.enabled", false)
+	viper.SetDefault("server.tls.minVersion", defaultServerTLSMinVersion)
+	viper.SetDefault("server.tls.cipherPolicy", defaultServerTLSCipherPolicy)
+	viper.SetDefault("server.tls.clientAuth", defaultServerTLSClientAuth)
+	viper.SetDefault("server.tls.reloadInterval", defaultServerTLSReloadInterval)
+	viper.SetDefault("idempotency.ttl", defaultIdempotencyTTL)
+	viper.SetDefault("idempotency.store", defaultIdempotencyStore)
+	viper.SetDefault("idempotency.redis.keyPrefix", defaultIdempotencyRedisKeyPrefix)
+	viper.SetDefault("grpc.enabled", true)
+	viper.SetDefault("grpc.port", defaultGRPCPort)
+	viper.SetDefault("graphql.maxDepth", defaultGraphQLMaxDepth)
+	viper.SetDefault("graphql.maxComplexity", defaultGraphQLMaxComplexity)
+	viper.SetDefault("events.replaySize", defaultEventsReplaySize)
+	viper.SetDefault("events.heartbeat", defaultEventsHeartbeat)
+	viper.SetDefault("outbox.pollInterval", defaultOutboxPollInterval)
+	viper.SetDefault("outbox.batchSize", defaultOutboxBatchSize)
+	viper.SetDefault("bus.enabled", false)
+	viper.SetDefault("bus.driver", defaultBusDriver)
+	viper.SetDefault("bus.topicPrefix", defaultBusTopicPrefix)
+	viper.SetDefault("auth.enabled", false)
+	viper.SetDefault("auth.jwt.refreshInterval", defaultJWKSRefreshInterval)
+	viper.SetDefault("auth.jwt.rolesClaim", defaultJWTRolesClaim)
+	viper.SetDefault("auth.jwt.tenantClaim", defaultJWTTenantClaim)
+	viper.SetDefault("auth.oidc.enabled", false)
+	viper.SetDefault("auth.oidc.scopes", []string{"openid", "profile", "email"})
+	viper.SetDefault("auth.oidc.groupsClaim", defaultOIDCGroupsClaim)
+	viper.SetDefault("auth.oidc.tenantClaim", defaultOIDCTenantClaim)
+	viper.SetDefault("auth.oidc.sessionTTL", defaultOIDCSessionTTL)
+	viper.SetDefault("auth.oidc.cookieSecure", true)
+	viper.SetDefault("auth.oidc.postLoginURL", defaultOIDCPostLoginURL)
+	viper.SetDefault("auth.oidc.maxPendingLogins", defaultOIDCMaxPendingLogins)
+	viper.SetDefault("rbac.policy", map[string][]string(auth.DefaultPolicy()))
+	viper.SetDefault("webhooks.workers", defaultWebhooksWorkers)
+	viper.SetDefault("webhooks.queueSize", defaultWebhooksQueueSize)
+	viper.SetDefault("webhooks.maxAttempts", defaultWebhooksMaxAttempts)
+	viper.SetDefault("webhooks.baseDelay", defaultWebhooksBaseDelay)
+	viper.SetDefault("webhooks.maxDelay", defaultWebhooksMaxDelay)
+	viper.SetDefault("webhooks.timeout", defaultWebhooksTimeout)
+	viper.SetDefault("rateLimit.enabled", false)
+	viper.SetDefault("rateLimit.store", defaultRateLimitStore)
+	viper.SetDefault("rateLimit.redis.keyPrefix", defaultRateLimitRedisKeyPrefix)
+	viper.SetDefault("rateLimit.default.requests", defaultRateLimitRequests)
+	viper.SetDefault("rateLimit.default.period", defaultRateLimitPeriod)
+	viper.SetDefault("rateLimit.default.burst", defaultRateLimitBurst)
+	viper.SetDefault("rateLimit.address.requests", defaultRateLimitAddressRequests)
+	viper.SetDefault("rateLimit.address.period", defaultRateLimitPeriod)
+	viper.SetDefault("rateLimit.address.burst", defaultRateLimitAddressBurst)
+	viper.SetDefault("log.level", defaultLogLevel)
+	viper.SetDefault("log.encoding", defaultLogEncoding)
+	viper.SetDefault("log.development", false)
+	viper.SetDefault("log.sampling.enabled", true)
+	viper.SetDefault("log.sampling.initial", defaultLogSamplingInitial)
+	viper.SetDefault("log.sampling.thereafter", defaultLogSamplingThereafter)
+	viper.SetDefault("log.outputPaths", []string{"stderr"})
+	viper.SetDefault("log.rotation.enabled", false)
+	viper.SetDefault("log.rotation.maxSizeMB", defaultLogRotationMaxSizeMB)
+	viper.SetDefault("log.rotation.maxBackups", defaultLogRotationMaxBackups)
+	viper.SetDefault("log.rotation.maxAgeDays", defaultLogRotationMaxAgeDays)
+	viper.SetDefault("metrics.enabled", true)
+	viper.SetDefault("metrics.port", defaultMetricsPort)
+	viper.SetDefault("metrics.path", defaultMetricsPath)
+	viper.SetDefault("tracing.enabled", false)
+	viper.SetDefault("tracing.serviceName", defaultTracingServiceName)
+	viper.SetDefault("tracing.exporter", defaultTracingExporter)
+	viper.SetDefault("tracing.protocol", defaultTracingProtocol)
+	viper.SetDefault("tracing.sampleRatio", defaultTracingSampleRatio)
+	viper.SetDefault("health.timeout", defaultHealthTimeout)
+	viper.SetDefault("health.diskPath", defaultHealthDiskPath)
+	viper.SetDefault("health.minFreeBytes", defaultHealthMinFreeBytes)
+	viper.SetDefault("health.shutdownDelay", defaultHealthShutdownDelay)
 	var c Config
 	if err := viper.MergeConfig(bytes.NewBuffer(configBytes)); err != nil {
 		fmt.Printf("Error reading config file, %s\n", err)
@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+// Comment is a remark written on a risk.
+type Comment struct {
+	ID     string `json:"id"`
+	RiskID string `json:"riskId"`
+	// Author is the subject of the principal who wrote the comment, it is empty without authentication
+	Author    string    `json:"author,omitempty"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+}
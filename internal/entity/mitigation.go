@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+// Mitigation is an action reducing a risk.
+type Mitigation struct {
+	ID     string `json:"id"`
+	RiskID string `json:"riskId"`
+	Title  string `json:"title"`
+	// Status is planned, in-progress or done
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -5,4 +5,8 @@ type Risk struct {
 	State       string `json:"state"`
 	Title       string `json:"title"`
 	Description string `json:"description"`
+	// Confidential risks have their description redacted for the callers who may not read confidential risks
+	Confidential bool `json:"confidential,omitempty"`
+	// Tags are lower case and unique
+	Tags []string `json:"tags,omitempty"`
 }
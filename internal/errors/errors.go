@@ -2,22 +2,46 @@ package errorstype
 
 import (
 	"errors"
+	"fmt"
 	"github.com/go-chi/render"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
 	"net/http"
 )
 
 var ErrRecordNotFound = errors.New("record does not exist")
 
+var ErrUnsupportedMediaType = errors.New("content type must be application/json")
+
+// ForbiddenError is returned when the caller lacks the permission required by an operation.
+type ForbiddenError struct {
+	Permission string
+}
+
+func (e *ForbiddenError) Error() string {
+	return "missing permission: " + e.Permission
+}
+
+// Extensions are added to the GraphQL errors
+func (e *ForbiddenError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": "FORBIDDEN", "permission": e.Permission}
+}
+
 type ErrResponse struct {
-	Err            error  `json:"-"`               // low-level runtime error
-	HTTPStatusCode int    `json:"-"`               // http response status code
-	StatusText     string `json:"status"`          // user-level status message
-	AppCode        int64  `json:"code,omitempty"`  // application-specific error code
-	ErrorText      string `json:"error,omitempty"` // application-level error message, for debugging
+	Err            error        `json:"-"`                    // low-level runtime error
+	HTTPStatusCode int          `json:"-"`                    // http response status code
+	StatusText     string       `json:"status"`               // user-level status message
+	AppCode        int64        `json:"code,omitempty"`       // application-specific error code
+	ErrorText      string       `json:"error,omitempty"`      // application-level error message, for debugging
+	Errors         []FieldError `json:"errors,omitempty"`     // field-level validation errors
+	Permission     string       `json:"permission,omitempty"` // missing permission of a forbidden request
+	RequestID      string       `json:"requestId,omitempty"`  // id of the failed request, to find its log entries
+	CorrelationID  string       `json:"correlationId,omitempty"`
 }
 
 func (e *ErrResponse) Render(w http.ResponseWriter, r *http.Request) error {
 	render.Status(r, e.HTTPStatusCode)
+	e.RequestID = log.RequestID(r.Context())
+	e.CorrelationID = log.CorrelationID(r.Context())
 	return nil
 }
 
@@ -30,6 +54,56 @@ func ErrInvalidRequest(err error) render.Renderer {
 	}
 }
 
+// ErrBind maps an error returned while reading the request body to the matching error response.
+func ErrBind(err error) render.Renderer {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, ErrUnsupportedMediaType):
+		return &ErrResponse{
+			Err:            err,
+			HTTPStatusCode: http.StatusUnsupportedMediaType,
+			StatusText:     "Unsupported media type.",
+			ErrorText:      err.Error(),
+		}
+	case errors.As(err, &maxBytesErr):
+		return &ErrResponse{
+			Err:            err,
+			HTTPStatusCode: http.StatusRequestEntityTooLarge,
+			StatusText:     "Request body too large.",
+			ErrorText:      fmt.Sprintf("request body must not be larger than %d bytes", maxBytesErr.Limit),
+		}
+	}
+	return ErrInvalidRequest(err)
+}
+
+// ErrUnauthorized is rendered when the request has no valid credentials. The cause is not returned to the caller.
+func ErrUnauthorized(err error) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HTTPStatusCode: http.StatusUnauthorized,
+		StatusText:     "Unauthorized.",
+	}
+}
+
+// ErrForbidden renders a 403 response naming the missing permission.
+func ErrForbidden(err *ForbiddenError) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HTTPStatusCode: http.StatusForbidden,
+		StatusText:     "Forbidden.",
+		ErrorText:      err.Error(),
+		Permission:     err.Permission,
+	}
+}
+
+func ErrInternal(err error) render.Renderer {
+	return &ErrResponse{
+		Err:            err,
+		HTTPStatusCode: http.StatusInternalServerError,
+		StatusText:     "Internal server error.",
+	}
+}
+
 func ErrRender(err error) render.Renderer {
 	return &ErrResponse{
 		Err:            err,
@@ -39,4 +113,12 @@ func ErrRender(err error) render.Renderer {
 	}
 }
 
-var ErrResponseNotFound = &ErrResponse{HTTPStatusCode: 404, StatusText: "Resource not found."}
+// ErrResponseNotFound returns a new response each time, as rendering it sets the ids of the request.
+func ErrResponseNotFound() render.Renderer {
+	return &ErrResponse{HTTPStatusCode: 404, StatusText: "Resource not found."}
+}
+
+// ErrTooManyRequests is rendered when the caller exceeded its rate limit
+func ErrTooManyRequests() render.Renderer {
+	return &ErrResponse{HTTPStatusCode: http.StatusTooManyRequests, StatusText: "Too many requests."}
+}
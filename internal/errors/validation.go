@@ -0,0 +1,100 @@
+package errorstype
+
+import (
+	"errors"
+	"github.com/go-chi/render"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/vikasgithub/risky-plumbers/internal/i18n"
+	"net/http"
+	"sort"
+)
+
+// Validation error codes returned in FieldError.Code
+const (
+	CodeRequired     = "required"
+	CodeLengthMax    = "length_max"
+	CodeLengthRange  = "length_range"
+	CodeInvalidValue = "invalid_value"
+	CodeInvalidURL   = "invalid_url"
+)
+
+// FieldError describes a validation failure of a single request field.
+type FieldError struct {
+	Field   string                 `json:"field"`
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
+
+// RuleError is returned by rules wrapped with Coded. Error returns the default (english) message
+// so that the error string of validation.Errors stays unchanged.
+type RuleError struct {
+	Code    string
+	Message string
+	Params  map[string]interface{}
+}
+
+func (e *RuleError) Error() string {
+	return e.Message
+}
+
+type codedRule struct {
+	rule   validation.Rule
+	code   string
+	params map[string]interface{}
+}
+
+// Coded wraps an ozzo validation rule so that its failures carry a machine-readable code and parameters.
+func Coded(rule validation.Rule, code string, params map[string]interface{}) validation.Rule {
+	return codedRule{rule: rule, code: code, params: params}
+}
+
+func (r codedRule) Validate(value interface{}) error {
+	err := r.rule.Validate(value)
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(validation.InternalError); ok {
+		return err
+	}
+	return &RuleError{Code: r.code, Message: err.Error(), Params: r.params}
+}
+
+// NewFieldErrors converts validation.Errors into a list of field errors sorted by field name,
+// with messages localized to lang.
+func NewFieldErrors(errs validation.Errors, lang string) []FieldError {
+	fields := make([]string, 0, len(errs))
+	for field := range errs {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	list := make([]FieldError, 0, len(fields))
+	for _, field := range fields {
+		fieldErr := FieldError{Field: field, Message: errs[field].Error()}
+		var ruleErr *RuleError
+		if errors.As(errs[field], &ruleErr) {
+			fieldErr.Code = ruleErr.Code
+			fieldErr.Params = ruleErr.Params
+			fieldErr.Message = i18n.Message(lang, ruleErr.Code, ruleErr.Message, ruleErr.Params)
+		}
+		list = append(list, fieldErr)
+	}
+	return list
+}
+
+// ErrValidation renders a 400 response listing every invalid field. Any error other than
+// validation.Errors is rendered as a plain invalid request.
+func ErrValidation(err error, lang string) render.Renderer {
+	var errs validation.Errors
+	if !errors.As(err, &errs) {
+		return ErrInvalidRequest(err)
+	}
+	return &ErrResponse{
+		Err:            err,
+		HTTPStatusCode: http.StatusBadRequest,
+		StatusText:     "Invalid request.",
+		ErrorText:      err.Error(),
+		Errors:         NewFieldErrors(errs, lang),
+	}
+}
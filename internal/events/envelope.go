@@ -0,0 +1,91 @@
+// Package events publishes the risk changes to a message bus for downstream consumers.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"time"
+)
+
+// CloudEvents attributes of the published envelopes
+const (
+	SpecVersion = "1.0"
+	Source      = "/risky-plumbers/risks"
+	// ContentType is the content type of the structured mode CloudEvents messages
+	ContentType = "application/cloudevents+json"
+	// DataVersion is appended to the event types, it changes with incompatible changes of the data
+	DataVersion = "v1"
+)
+
+// Envelope is a CloudEvents 1.0 event in the structured mode, see https://github.com/cloudevents/spec.
+type Envelope struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	// TenantID is the tenantid extension attribute, consumers use it to route the events of each tenant
+	TenantID string `json:"tenantid,omitempty"`
+	// CorrelationID is the correlationid extension attribute, the correlation id of the request changing the risk
+	CorrelationID string          `json:"correlationid,omitempty"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// Publisher sends envelopes to a topic of a message bus.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, envelope Envelope) error
+	// Ping returns an error when the message bus can not be reached
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// NewEnvelope wraps a risk event. The envelope keeps the id of the event, so consumers can ignore duplicates.
+func NewEnvelope(event risk.Event) (Envelope, error) {
+	data, err := json.Marshal(event.Risk)
+	if err != nil {
+		return Envelope{}, err
+	}
+	envelope := Envelope{
+		SpecVersion:     SpecVersion,
+		ID:              event.ID,
+		Source:          Source,
+		Type:            fmt.Sprintf("%s.%s", event.Type, DataVersion),
+		Time:            event.Time,
+		DataContentType: "application/json",
+		TenantID:        event.TenantID,
+		CorrelationID:   event.CorrelationID,
+		Data:            data,
+	}
+	if event.Risk != nil {
+		envelope.Subject = event.Risk.ID
+	}
+	return envelope, nil
+}
+
+// Topic returns the topic of the events of the given type, e.g. riskyplumbers.risk.created for the prefix riskyplumbers.
+func Topic(prefix, eventType string) string {
+	return prefix + "." + eventType
+}
+
+// Sink is a risk.Publisher forwarding the risk events to a message bus.
+type Sink struct {
+	publisher   Publisher
+	topicPrefix string
+}
+
+// NewSink returns a sink publishing the events to the topics starting with topicPrefix.
+func NewSink(publisher Publisher, topicPrefix string) *Sink {
+	return &Sink{publisher, topicPrefix}
+}
+
+func (s *Sink) Publish(ctx context.Context, event risk.Event) error {
+	envelope, err := NewEnvelope(event)
+	if err != nil {
+		return err
+	}
+	return s.publisher.Publish(ctx, Topic(s.topicPrefix, event.Type), envelope)
+}
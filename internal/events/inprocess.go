@@ -0,0 +1,84 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// subscriberBuffer is the number of envelopes buffered per subscriber, slow subscribers miss envelopes
+// published while their buffer is full
+const subscriberBuffer = 64
+
+// InProcessPublisher delivers the envelopes to subscribers of the same process. It is used when no message bus
+// is configured and in tests.
+type InProcessPublisher struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan Envelope]struct{}
+	closed      bool
+}
+
+// NewInProcessPublisher creates a publisher without subscribers.
+func NewInProcessPublisher() *InProcessPublisher {
+	return &InProcessPublisher{subscribers: map[string]map[chan Envelope]struct{}{}}
+}
+
+func (p *InProcessPublisher) Publish(ctx context.Context, topic string, envelope Envelope) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for ch := range p.subscribers[topic] {
+		select {
+		case ch <- envelope:
+		default:
+		}
+	}
+	return nil
+}
+
+// Ping returns an error once the publisher is closed.
+func (p *InProcessPublisher) Ping(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return errors.New("the in-process publisher is closed")
+	}
+	return nil
+}
+
+// Subscribe returns a channel receiving the envelopes published to topic, and a function which must be
+// called to unsubscribe.
+func (p *InProcessPublisher) Subscribe(topic string) (<-chan Envelope, func()) {
+	ch := make(chan Envelope, subscriberBuffer)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if p.subscribers[topic] == nil {
+		p.subscribers[topic] = map[chan Envelope]struct{}{}
+	}
+	p.subscribers[topic][ch] = struct{}{}
+	return ch, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if _, ok := p.subscribers[topic][ch]; ok {
+			delete(p.subscribers[topic], ch)
+			close(ch)
+		}
+	}
+}
+
+// Close closes the channels of all the subscribers.
+func (p *InProcessPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, subscribers := range p.subscribers {
+		for ch := range subscribers {
+			close(ch)
+		}
+	}
+	p.subscribers = map[string]map[chan Envelope]struct{}{}
+	p.closed = true
+	return nil
+}
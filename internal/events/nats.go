@@ -0,0 +1,66 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"time"
+)
+
+// flushTimeout limits the wait for the acknowledgement when the context has no deadline
+const flushTimeout = 5 * time.Second
+
+// NATSPublisher publishes the envelopes as NATS messages, using the topic as subject.
+type NATSPublisher struct {
+	conn *nats.Conn
+}
+
+// NewNATSPublisher connects to the NATS server at url. The connection reconnects on its own when it is lost.
+func NewNATSPublisher(url string, opts ...nats.Option) (*NATSPublisher, error) {
+	conn, err := nats.Connect(url, append([]nats.Option{nats.Name("risky-plumbers"), nats.MaxReconnects(-1)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &NATSPublisher{conn}, nil
+}
+
+// Publish sends the envelope and waits for the server to acknowledge it, so that a failed publish is reported
+// and retried by the outbox relay.
+func (p *NATSPublisher) Publish(ctx context.Context, topic string, envelope Envelope) error {
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	msg := nats.NewMsg(topic)
+	msg.Header.Set("Content-Type", ContentType)
+	msg.Header.Set(nats.MsgIdHdr, envelope.ID)
+	msg.Data = data
+	if err := p.conn.PublishMsg(msg); err != nil {
+		return err
+	}
+	// the context of a flush needs a deadline
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, flushTimeout)
+		defer cancel()
+	}
+	return p.conn.FlushWithContext(ctx)
+}
+
+// Ping makes a round trip to the NATS server, it fails at once while the connection is lost.
+func (p *NATSPublisher) Ping(ctx context.Context) error {
+	if !p.conn.IsConnected() {
+		return fmt.Errorf("the nats connection is %v", p.conn.Status())
+	}
+	// the context of a flush needs a deadline, Flush applies the default timeout of the connection
+	if _, ok := ctx.Deadline(); !ok {
+		return p.conn.Flush()
+	}
+	return p.conn.FlushWithContext(ctx)
+}
+
+// Close sends the buffered messages and closes the connection.
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
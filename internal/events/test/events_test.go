@@ -0,0 +1,126 @@
+package eventstest
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/vikasgithub/risky-plumbers/internal/entity"
+	"github.com/vikasgithub/risky-plumbers/internal/events"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"testing"
+	"time"
+)
+
+var event = risk.Event{
+	ID:       "e1",
+	Type:     risk.EventCreated,
+	Time:     time.Date(2024, 9, 1, 10, 0, 0, 0, time.UTC),
+	TenantID: "acme",
+	Risk:     &entity.Risk{ID: "1", State: "open", Title: "t", Description: "d"},
+}
+
+func TestEnvelope(t *testing.T) {
+	envelope, err := events.NewEnvelope(event)
+	assert.NoError(t, err)
+	data, err := json.Marshal(envelope)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"specversion": "1.0",
+		"id": "e1",
+		"source": "/risky-plumbers/risks",
+		"type": "risk.created.v1",
+		"subject": "1",
+		"time": "2024-09-01T10:00:00Z",
+		"datacontenttype": "application/json",
+		"tenantid": "acme",
+		"data": {"id": "1", "state": "open", "title": "t", "description": "d"}
+	}`, string(data))
+}
+
+func TestInProcessSink(t *testing.T) {
+	publisher := events.NewInProcessPublisher()
+	defer publisher.Close()
+	created, unsubscribe := publisher.Subscribe("test.risk.created")
+	defer unsubscribe()
+	updated, unsubscribeUpdated := publisher.Subscribe("test.risk.updated")
+	defer unsubscribeUpdated()
+
+	sink := events.NewSink(publisher, "test")
+	assert.NoError(t, sink.Publish(context.Background(), event))
+
+	envelope := <-created
+	assert.Equal(t, "e1", envelope.ID)
+	assert.Equal(t, "risk.created.v1", envelope.Type)
+	assert.Empty(t, updated)
+}
+
+func runNATSServer(t *testing.T) *server.Server {
+	natsServer, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: server.RANDOM_PORT, NoLog: true, NoSigs: true})
+	assert.NoError(t, err)
+	go natsServer.Start()
+	if !natsServer.ReadyForConnections(5 * time.Second) {
+		t.Fatal("the NATS server did not start")
+	}
+	t.Cleanup(natsServer.Shutdown)
+	return natsServer
+}
+
+func TestNATSSink(t *testing.T) {
+	natsServer := runNATSServer(t)
+
+	subscriber, err := nats.Connect(natsServer.ClientURL())
+	assert.NoError(t, err)
+	defer subscriber.Close()
+	messages := make(chan *nats.Msg, 1)
+	subscription, err := subscriber.ChanSubscribe("test.risk.>", messages)
+	assert.NoError(t, err)
+	defer subscription.Unsubscribe()
+	assert.NoError(t, subscriber.Flush())
+
+	publisher, err := events.NewNATSPublisher(natsServer.ClientURL())
+	assert.NoError(t, err)
+	defer publisher.Close()
+	assert.NoError(t, events.NewSink(publisher, "test").Publish(context.Background(), event))
+
+	select {
+	case msg := <-messages:
+		assert.Equal(t, "test.risk.created", msg.Subject)
+		assert.Equal(t, events.ContentType, msg.Header.Get("Content-Type"))
+		assert.Equal(t, "e1", msg.Header.Get(nats.MsgIdHdr))
+		var envelope events.Envelope
+		assert.NoError(t, json.Unmarshal(msg.Data, &envelope))
+		assert.Equal(t, "e1", envelope.ID)
+		assert.Equal(t, "1", envelope.Subject)
+		assert.JSONEq(t, `{"id":"1","state":"open","title":"t","description":"d"}`, string(envelope.Data))
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+}
+
+func TestNATSPublishFailsWithoutServer(t *testing.T) {
+	natsServer := runNATSServer(t)
+	publisher, err := events.NewNATSPublisher(natsServer.ClientURL())
+	assert.NoError(t, err)
+	defer publisher.Close()
+	natsServer.Shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	assert.Error(t, publisher.Publish(ctx, "test.risk.created", events.Envelope{ID: "e1"}),
+		"the relay keeps the event in the outbox when it is not acknowledged")
+}
+
+func TestNATSPing(t *testing.T) {
+	natsServer := runNATSServer(t)
+	publisher, err := events.NewNATSPublisher(natsServer.ClientURL())
+	assert.NoError(t, err)
+	defer publisher.Close()
+	assert.NoError(t, publisher.Ping(context.Background()))
+
+	natsServer.Shutdown()
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	assert.Error(t, publisher.Ping(ctx), "the readiness fails while the bus can not be reached")
+}
@@ -0,0 +1,100 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/request"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"net/http"
+)
+
+type resource struct {
+	schema        graphql.Schema
+	service       risk.Service
+	maxDepth      int
+	maxComplexity int
+	logger        log.Logger
+}
+
+// Request is the body of a GraphQL request.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+func (gr *Request) Bind(r *http.Request) error {
+	return nil
+}
+
+// RegisterHandlers serves the GraphQL API at /graphql. Queries deeper than maxDepth or more complex than
+// maxComplexity are rejected before they are executed.
+func RegisterHandlers(r chi.Router, service risk.Service, maxDepth, maxComplexity int, logger log.Logger) error {
+	schema, err := NewSchema(service)
+	if err != nil {
+		return err
+	}
+	res := resource{schema: schema, service: service, maxDepth: maxDepth, maxComplexity: maxComplexity, logger: logger}
+	r.Post("/graphql", res.post)
+	r.Get("/graphql", res.get)
+	return nil
+}
+
+func (res resource) post(w http.ResponseWriter, r *http.Request) {
+	gr := &Request{}
+	if err := request.BindJSON(r, gr); err != nil {
+		render.Render(w, r, errorstype.ErrBind(err))
+		return
+	}
+	res.execute(w, r, gr, true)
+}
+
+// get executes queries sent as url parameters. Mutations are only accepted using POST.
+func (res resource) get(w http.ResponseWriter, r *http.Request) {
+	gr := &Request{
+		Query:         r.URL.Query().Get("query"),
+		OperationName: r.URL.Query().Get("operationName"),
+	}
+	if variables := r.URL.Query().Get("variables"); variables != "" {
+		if err := json.Unmarshal([]byte(variables), &gr.Variables); err != nil {
+			render.Render(w, r, errorstype.ErrInvalidRequest(err))
+			return
+		}
+	}
+	res.execute(w, r, gr, false)
+}
+
+func (res resource) execute(w http.ResponseWriter, r *http.Request, gr *Request, allowMutation bool) {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(gr.Query)})})
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+	if err := checkLimits(doc, gr.OperationName, gr.Variables, res.maxDepth, res.maxComplexity); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+	if !allowMutation && hasMutation(doc, gr.OperationName) {
+		render.Status(r, http.StatusMethodNotAllowed)
+		render.JSON(w, r, &graphql.Result{Errors: gqlerrors.FormatErrors(errMutationOverGet)})
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         res.schema,
+		RequestString:  gr.Query,
+		VariableValues: gr.Variables,
+		OperationName:  gr.OperationName,
+		Context:        withLoaders(r.Context(), newLoaders(res.service)),
+	})
+	render.JSON(w, r, result)
+}
@@ -0,0 +1,151 @@
+package graphqlapi
+
+import (
+	"errors"
+	"fmt"
+	"github.com/graphql-go/graphql/language/ast"
+	"strconv"
+)
+
+// analysis walks the selections of an operation to compute its depth and complexity.
+// Every field costs 1. The cost of the selections of a connection field is multiplied by its first argument.
+type analysis struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	// defaults are the default values of the variables of the operation being walked
+	defaults map[string]ast.Value
+	// visiting guards against fragment cycles, which the validation of graphql-go reports
+	visiting map[string]bool
+}
+
+// checkLimits returns an error if the operation selected by operationName is deeper than maxDepth
+// or more complex than maxComplexity.
+func checkLimits(doc *ast.Document, operationName string, variables map[string]interface{}, maxDepth, maxComplexity int) error {
+	a := &analysis{
+		fragments: map[string]*ast.FragmentDefinition{},
+		variables: variables,
+		visiting:  map[string]bool{},
+	}
+	var operations []*ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		switch d := definition.(type) {
+		case *ast.FragmentDefinition:
+			a.fragments[d.Name.Value] = d
+		case *ast.OperationDefinition:
+			if operationName == "" || (d.Name != nil && d.Name.Value == operationName) {
+				operations = append(operations, d)
+			}
+		}
+	}
+	for _, operation := range operations {
+		a.defaults = map[string]ast.Value{}
+		for _, definition := range operation.VariableDefinitions {
+			if definition.DefaultValue != nil {
+				a.defaults[definition.Variable.Name.Value] = definition.DefaultValue
+			}
+		}
+		depth, complexity := a.selectionSet(operation.SelectionSet)
+		if depth > maxDepth {
+			return fmt.Errorf("query depth %d exceeds the maximum depth of %d", depth, maxDepth)
+		}
+		if complexity > maxComplexity {
+			return fmt.Errorf("query complexity %d exceeds the maximum complexity of %d", complexity, maxComplexity)
+		}
+	}
+	return nil
+}
+
+func (a *analysis) selectionSet(set *ast.SelectionSet) (depth, complexity int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, selection := range set.Selections {
+		var d, c int
+		switch s := selection.(type) {
+		case *ast.Field:
+			d, c = a.selectionSet(s.SelectionSet)
+			d++
+			c = 1 + c*a.multiplier(s)
+		case *ast.InlineFragment:
+			d, c = a.selectionSet(s.SelectionSet)
+		case *ast.FragmentSpread:
+			name := s.Name.Value
+			fragment, ok := a.fragments[name]
+			if !ok || a.visiting[name] {
+				continue
+			}
+			a.visiting[name] = true
+			d, c = a.selectionSet(fragment.SelectionSet)
+			a.visiting[name] = false
+		}
+		if d > depth {
+			depth = d
+		}
+		complexity += c
+	}
+	return depth, complexity
+}
+
+// multiplier returns the number of items a field may return, taken from its first argument
+func (a *analysis) multiplier(field *ast.Field) int {
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "first" {
+			continue
+		}
+		switch v := argument.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(v.Value); err == nil && n > 0 {
+				return n
+			}
+		case *ast.Variable:
+			n, ok := a.variable(v.Name.Value)
+			if !ok {
+				// a variable which is neither supplied nor defaulted may request the largest page
+				return maxPageSize
+			}
+			if n > 0 {
+				return n
+			}
+		}
+		return defaultPageSize
+	}
+	if field.SelectionSet != nil && isConnection(field.Name.Value) {
+		return defaultPageSize
+	}
+	return 1
+}
+
+// variable returns the integer value of the named variable, supplied with the request or defaulted by the operation
+func (a *analysis) variable(name string) (int, bool) {
+	if value, ok := a.variables[name]; ok {
+		switch n := value.(type) {
+		case float64:
+			return int(n), true
+		case int:
+			return n, true
+		}
+		return 0, false
+	}
+	if value, ok := a.defaults[name].(*ast.IntValue); ok {
+		n, err := strconv.Atoi(value.Value)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+var errMutationOverGet = errors.New("mutations must be sent using POST")
+
+// hasMutation reports whether the operation selected by operationName is a mutation
+func hasMutation(doc *ast.Document, operationName string) bool {
+	for _, definition := range doc.Definitions {
+		if operation, ok := definition.(*ast.OperationDefinition); ok {
+			if operationName != "" && (operation.Name == nil || operation.Name.Value != operationName) {
+				continue
+			}
+			if operation.Operation == ast.OperationTypeMutation {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package graphqlapi
+
+import (
+	"context"
+	"github.com/vikasgithub/risky-plumbers/internal/entity"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"sort"
+	"sync"
+)
+
+type loadersKey struct{}
+
+// loaders batches the lookups made while resolving a request, one loader per kind of value. They live for the
+// duration of a request.
+type loaders struct {
+	risks       *loader[*entity.Risk]
+	comments    *loader[[]*entity.Comment]
+	mitigations *loader[[]*entity.Mitigation]
+	history     *loader[[]*change]
+}
+
+func newLoaders(service risk.Service) *loaders {
+	return &loaders{
+		// unknown risks resolve to null
+		risks:       &loader[*entity.Risk]{fetch: service.GetMany},
+		comments:    &loader[[]*entity.Comment]{fetch: service.Comments, missing: []*entity.Comment{}},
+		mitigations: &loader[[]*entity.Mitigation]{fetch: service.Mitigations, missing: []*entity.Mitigation{}},
+		history: &loader[[]*change]{
+			fetch: func(ctx context.Context, ids []string) (map[string][]*change, error) {
+				history, err := service.History(ctx, ids)
+				if err != nil {
+					return nil, err
+				}
+				changes := make(map[string][]*change, len(history))
+				for id, events := range history {
+					for _, event := range events {
+						changes[id] = append(changes[id], newChange(event))
+					}
+				}
+				return changes, nil
+			},
+			missing: []*change{},
+		},
+	}
+}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// loader batches the lookups by risk id made while resolving one level of a query into a single call of fetch.
+type loader[T any] struct {
+	fetch func(ctx context.Context, ids []string) (map[string]T, error)
+	// missing is the value of the ids missing from the result of fetch
+	missing interface{}
+
+	mu      sync.Mutex
+	pending []string
+	batch   *batch[T]
+}
+
+type batch[T any] struct {
+	once   sync.Once
+	values map[string]T
+	err    error
+}
+
+// Load registers id for the next batch and returns a thunk resolving to its value. The batch is fetched
+// when the first thunk of the batch is called, which graphql-go does after resolving the current level.
+func (l *loader[T]) Load(ctx context.Context, id string) func() (interface{}, error) {
+	l.mu.Lock()
+	if l.batch == nil {
+		l.batch = &batch[T]{}
+	}
+	b := l.batch
+	l.pending = append(l.pending, id)
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		b.once.Do(func() {
+			l.mu.Lock()
+			ids := unique(l.pending)
+			l.pending = nil
+			l.batch = nil
+			l.mu.Unlock()
+			b.values, b.err = l.fetch(ctx, ids)
+		})
+		if b.err != nil {
+			return nil, b.err
+		}
+		if value, ok := b.values[id]; ok {
+			return value, nil
+		}
+		return l.missing, nil
+	}
+}
+
+// unique returns the sorted ids without duplicates
+func unique(ids []string) []string {
+	sort.Strings(ids)
+	result := ids[:0]
+	for i, id := range ids {
+		if i == 0 || id != ids[i-1] {
+			result = append(result, id)
+		}
+	}
+	return result
+}
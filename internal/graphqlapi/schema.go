@@ -0,0 +1,378 @@
+// Package graphqlapi serves a GraphQL API over risk.Service.
+package graphqlapi
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/graphql-go/graphql"
+	"github.com/vikasgithub/risky-plumbers/internal/entity"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	cursorPrefix    = "offset:"
+)
+
+// connections lists the fields returning a connection, their cost is multiplied by the page size
+var connections = map[string]bool{"risks": true}
+
+func isConnection(field string) bool {
+	return connections[field]
+}
+
+var riskType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Risk",
+	Fields: graphql.Fields{
+		"id":    &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"state": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"title": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"description": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "Is " + risk.RedactedDescription + " when the risk is confidential and the caller may not read it",
+		},
+		"confidential": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"tags": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return tagsOf(p.Source.(*entity.Risk).Tags), nil
+			},
+		},
+		"comments": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(commentType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return loadersFrom(p.Context).comments.Load(p.Context, p.Source.(*entity.Risk).ID), nil
+			},
+		},
+		"mitigations": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(mitigationType))),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return loadersFrom(p.Context).mitigations.Load(p.Context, p.Source.(*entity.Risk).ID), nil
+			},
+		},
+		"history": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(changeType))),
+			Description: "The changes of the risk, oldest first",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return loadersFrom(p.Context).history.Load(p.Context, p.Source.(*entity.Risk).ID), nil
+			},
+		},
+	},
+})
+
+var commentType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Comment",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"author": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "The subject of the principal who wrote the comment, empty without authentication",
+		},
+		"body": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "Is " + risk.RedactedDescription + " when the risk is confidential and the caller may not read it",
+		},
+		"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+	},
+})
+
+var mitigationType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Mitigation",
+	Fields: graphql.Fields{
+		"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"title":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"status":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+	},
+})
+
+var changeType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "RiskChange",
+	Description: "A change of a risk with the fields it left, the description of a confidential risk is always redacted",
+	Fields: graphql.Fields{
+		"id":           &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"type":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"time":         &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		"state":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"title":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"description":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"confidential": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"tags":         &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+	},
+})
+
+var pageInfoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PageInfo",
+	Fields: graphql.Fields{
+		"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"endCursor":   &graphql.Field{Type: graphql.String},
+	},
+})
+
+var riskEdgeType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "RiskEdge",
+	Fields: graphql.Fields{
+		"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"node":   &graphql.Field{Type: graphql.NewNonNull(riskType)},
+	},
+})
+
+var riskConnectionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "RiskConnection",
+	Fields: graphql.Fields{
+		"edges":    &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(riskEdgeType)))},
+		"pageInfo": &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+	},
+})
+
+var createRiskInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CreateRiskInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"state":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"title":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"description":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"confidential": &graphql.InputObjectFieldConfig{Type: graphql.Boolean, DefaultValue: false},
+		"tags":         &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+	},
+})
+
+var updateRiskInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "UpdateRiskInput",
+	Description: "The fields left out keep their value",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"state":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"title":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"description":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"confidential": &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+		"tags":         &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+	},
+})
+
+var addMitigationInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "AddMitigationInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"title":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"status": &graphql.InputObjectFieldConfig{Type: graphql.String, DefaultValue: risk.MitigationPlanned},
+	},
+})
+
+// connection is the resolved value of a RiskConnection
+type connection struct {
+	Edges    []edge   `json:"edges"`
+	PageInfo pageInfo `json:"pageInfo"`
+}
+
+type edge struct {
+	Cursor string       `json:"cursor"`
+	Node   *entity.Risk `json:"node"`
+}
+
+type pageInfo struct {
+	HasNextPage bool    `json:"hasNextPage"`
+	EndCursor   *string `json:"endCursor"`
+}
+
+// change is the resolved value of a RiskChange
+type change struct {
+	ID           string    `json:"id"`
+	Type         string    `json:"type"`
+	Time         time.Time `json:"time"`
+	State        string    `json:"state"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	Confidential bool      `json:"confidential"`
+	Tags         []string  `json:"tags"`
+}
+
+func newChange(event risk.Event) *change {
+	return &change{
+		ID:           event.ID,
+		Type:         event.Type,
+		Time:         event.Time,
+		State:        event.Risk.State,
+		Title:        event.Risk.Title,
+		Description:  event.Risk.Description,
+		Confidential: event.Risk.Confidential,
+		Tags:         tagsOf(event.Risk.Tags),
+	}
+}
+
+// tagsOf returns the tags as a list which is never null
+func tagsOf(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// stringsOf returns the values of a list argument
+func stringsOf(values []interface{}) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, value.(string))
+	}
+	return result
+}
+
+// NewSchema builds the GraphQL schema resolving against the given service.
+func NewSchema(service risk.Service) (graphql.Schema, error) {
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"risk": &graphql.Field{
+				Type: riskType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadersFrom(p.Context).risks.Load(p.Context, p.Args["id"].(string)), nil
+				},
+			},
+			"risks": &graphql.Field{
+				Type: graphql.NewNonNull(riskConnectionType),
+				Args: graphql.FieldConfigArgument{
+					"first": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultPageSize},
+					"after": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					first := p.Args["first"].(int)
+					if first < 0 || first > maxPageSize {
+						return nil, fmt.Errorf("first must be between 0 and %d", maxPageSize)
+					}
+					offset := 0
+					if after, ok := p.Args["after"].(string); ok {
+						var err error
+						if offset, err = decodeCursor(after); err != nil {
+							return nil, err
+						}
+					}
+					// fetch one more risk to find out whether there is a next page
+					risks, err := service.GetAll(p.Context, offset, first+1)
+					if err != nil {
+						return nil, err
+					}
+					return newConnection(risks, offset, first), nil
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createRisk": &graphql.Field{
+				Type: graphql.NewNonNull(riskType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(createRiskInputType)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					input := p.Args["input"].(map[string]interface{})
+					createRequest := &risk.CreateRiskRequest{
+						State:       input["state"].(string),
+						Title:       input["title"].(string),
+						Description: input["description"].(string),
+					}
+					createRequest.Confidential, _ = input["confidential"].(bool)
+					if tags, ok := input["tags"].([]interface{}); ok {
+						createRequest.Tags = stringsOf(tags)
+					}
+					// trim the input the same way as the REST API does
+					createRequest.Bind(nil)
+					return service.Create(p.Context, createRequest)
+				},
+			},
+			"updateRisk": &graphql.Field{
+				Type: graphql.NewNonNull(riskType),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateRiskInputType)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					input := p.Args["input"].(map[string]interface{})
+					updateRequest := &risk.UpdateRiskRequest{}
+					if state, ok := input["state"].(string); ok {
+						updateRequest.State = &state
+					}
+					if title, ok := input["title"].(string); ok {
+						updateRequest.Title = &title
+					}
+					if description, ok := input["description"].(string); ok {
+						updateRequest.Description = &description
+					}
+					if confidential, ok := input["confidential"].(bool); ok {
+						updateRequest.Confidential = &confidential
+					}
+					if values, ok := input["tags"].([]interface{}); ok {
+						tags := stringsOf(values)
+						updateRequest.Tags = &tags
+					}
+					return service.Update(p.Context, p.Args["id"].(string), updateRequest)
+				},
+			},
+			"addComment": &graphql.Field{
+				Type: graphql.NewNonNull(commentType),
+				Args: graphql.FieldConfigArgument{
+					"riskId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"body":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return service.AddComment(p.Context, p.Args["riskId"].(string),
+						&risk.AddCommentRequest{Body: p.Args["body"].(string)})
+				},
+			},
+			"addMitigation": &graphql.Field{
+				Type: graphql.NewNonNull(mitigationType),
+				Args: graphql.FieldConfigArgument{
+					"riskId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(addMitigationInputType)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					input := p.Args["input"].(map[string]interface{})
+					addRequest := &risk.AddMitigationRequest{Title: input["title"].(string)}
+					addRequest.Status, _ = input["status"].(string)
+					return service.AddMitigation(p.Context, p.Args["riskId"].(string), addRequest)
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+func newConnection(risks []*entity.Risk, offset, first int) *connection {
+	c := &connection{Edges: []edge{}}
+	if len(risks) > first {
+		c.PageInfo.HasNextPage = true
+		risks = risks[:first]
+	}
+	for i, r := range risks {
+		c.Edges = append(c.Edges, edge{Cursor: encodeCursor(offset + i + 1), Node: r})
+	}
+	if len(c.Edges) > 0 {
+		c.PageInfo.EndCursor = &c.Edges[len(c.Edges)-1].Cursor
+	}
+	return c
+}
+
+// encodeCursor returns the opaque cursor pointing after the first offset risks
+func encodeCursor(offset int) string {
+	return base64.StdEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	invalid := errors.New("invalid cursor")
+	decoded, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(decoded), cursorPrefix) {
+		return 0, invalid
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(decoded), cursorPrefix))
+	if err != nil || offset < 0 {
+		return 0, invalid
+	}
+	return offset, nil
+}
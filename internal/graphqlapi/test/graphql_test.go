@@ -0,0 +1,244 @@
+package graphqlapitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"github.com/vikasgithub/risky-plumbers/internal/entity"
+	"github.com/vikasgithub/risky-plumbers/internal/graphqlapi"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	mocks "github.com/vikasgithub/risky-plumbers/internal/risk/mocks"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newRouter(t *testing.T, service risk.Service) *chi.Mux {
+	router := chi.NewRouter()
+	assert.NoError(t, graphqlapi.RegisterHandlers(router, service, 5, 100, log.New()))
+	return router
+}
+
+func post(router http.Handler, query string, variables map[string]interface{}) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(graphqlapi.Request{Query: query, Variables: variables})
+	rq, _ := http.NewRequest("POST", "/graphql", bytes.NewBuffer(body))
+	rq.Header.Set("Content-Type", "application/json")
+	rs := httptest.NewRecorder()
+	router.ServeHTTP(rs, rq)
+	return rs
+}
+
+func TestQueries(t *testing.T) {
+	logger := log.New()
+	service := risk.NewService(risk.NewStore(logger), logger)
+	router := newRouter(t, service)
+
+	var ids []string
+	for _, title := range []string{"a", "b", "c"} {
+		rs := post(router, `mutation($input: CreateRiskInput!) { createRisk(input: $input) { id title } }`,
+			map[string]interface{}{"input": map[string]interface{}{"state": "open", "title": title, "description": "d"}})
+		assert.Equal(t, http.StatusOK, rs.Result().StatusCode)
+		var result struct {
+			Data struct {
+				CreateRisk struct{ ID, Title string }
+			}
+		}
+		assert.NoError(t, json.Unmarshal(rs.Body.Bytes(), &result))
+		assert.Equal(t, title, result.Data.CreateRisk.Title)
+		ids = append(ids, result.Data.CreateRisk.ID)
+	}
+
+	t.Run("Risk By Id", func(t *testing.T) {
+		rs := post(router, `{ risk(id: "`+ids[0]+`") { title } missing: risk(id: "unknown") { title } }`, nil)
+		assert.Equal(t, `{"data":{"missing":null,"risk":{"title":"a"}}}`, strings.TrimSpace(rs.Body.String()))
+	})
+
+	t.Run("Connection Pages", func(t *testing.T) {
+		query := `query($after: String) { risks(first: 2, after: $after) { edges { node { id } } pageInfo { hasNextPage endCursor } } }`
+		type page struct {
+			Data struct {
+				Risks struct {
+					Edges []struct {
+						Node struct{ ID string }
+					}
+					PageInfo struct {
+						HasNextPage bool
+						EndCursor   string
+					}
+				}
+			}
+		}
+		var first page
+		assert.NoError(t, json.Unmarshal(post(router, query, nil).Body.Bytes(), &first))
+		assert.Len(t, first.Data.Risks.Edges, 2)
+		assert.True(t, first.Data.Risks.PageInfo.HasNextPage)
+
+		var second page
+		rs := post(router, query, map[string]interface{}{"after": first.Data.Risks.PageInfo.EndCursor})
+		assert.NoError(t, json.Unmarshal(rs.Body.Bytes(), &second))
+		assert.Len(t, second.Data.Risks.Edges, 1)
+		assert.False(t, second.Data.Risks.PageInfo.HasNextPage)
+	})
+
+	t.Run("Validation Error", func(t *testing.T) {
+		rs := post(router, `mutation { createRisk(input: {state: "open", title: " ", description: "d"}) { id } }`, nil)
+		assert.Contains(t, rs.Body.String(), "title: cannot be blank")
+	})
+
+	t.Run("Mutation Over GET", func(t *testing.T) {
+		query := url.Values{"query": {`mutation { createRisk(input: {state: "open", title: "t", description: "d"}) { id } }`}}
+		rq, _ := http.NewRequest("GET", "/graphql?"+query.Encode(), nil)
+		rs := httptest.NewRecorder()
+		router.ServeHTTP(rs, rq)
+		assert.Equal(t, http.StatusMethodNotAllowed, rs.Result().StatusCode)
+	})
+}
+
+func TestBatching(t *testing.T) {
+	service := &mocks.Service{}
+	router := newRouter(t, service)
+	service.On("GetMany", mock.Anything, []string{"1", "2"}).
+		Return(map[string]*entity.Risk{
+			"1": {ID: "1", State: "open", Title: "a", Description: "d"},
+			"2": {ID: "2", State: "open", Title: "b", Description: "d"},
+		}, nil).Once()
+
+	rs := post(router, `{ a: risk(id: "1") { title } b: risk(id: "2") { title } c: risk(id: "1") { id } }`, nil)
+	assert.Equal(t, `{"data":{"a":{"title":"a"},"b":{"title":"b"},"c":{"id":"1"}}}`, strings.TrimSpace(rs.Body.String()))
+	service.AssertExpectations(t)
+	service.AssertNumberOfCalls(t, "GetMany", 1)
+}
+
+func TestLimits(t *testing.T) {
+	router := newRouter(t, &mocks.Service{})
+
+	t.Run("Depth", func(t *testing.T) {
+		rs := post(router, `{ risks(first: 1) { edges { node { id } } pageInfo { __typename } } __schema { types { fields { type { ofType { name } } } } } }`, nil)
+		assert.Equal(t, http.StatusBadRequest, rs.Result().StatusCode)
+		assert.Contains(t, rs.Body.String(), "exceeds the maximum depth of 5")
+	})
+
+	t.Run("Complexity", func(t *testing.T) {
+		rs := post(router, `{ risks(first: 50) { edges { node { id title } } } }`, nil)
+		assert.Equal(t, http.StatusBadRequest, rs.Result().StatusCode)
+		assert.Contains(t, rs.Body.String(), "exceeds the maximum complexity of 100")
+	})
+
+	t.Run("Complexity Using Fragments And Variables", func(t *testing.T) {
+		rs := post(router, `query($n: Int) { risks(first: $n) { ...page } } fragment page on RiskConnection { edges { node { id title } } }`,
+			map[string]interface{}{"n": 50})
+		assert.Equal(t, http.StatusBadRequest, rs.Result().StatusCode)
+	})
+
+	t.Run("Complexity Using The Default Of A Variable", func(t *testing.T) {
+		rs := post(router, `query($n: Int = 100) { risks(first: $n) { edges { node { id } } } }`, nil)
+		assert.Equal(t, http.StatusBadRequest, rs.Result().StatusCode)
+		assert.Contains(t, rs.Body.String(), "exceeds the maximum complexity of 100")
+	})
+
+	t.Run("Complexity Using A Variable Without Value", func(t *testing.T) {
+		rs := post(router, `query($n: Int) { risks(first: $n) { edges { node { id } } } }`, nil)
+		assert.Equal(t, http.StatusBadRequest, rs.Result().StatusCode)
+		assert.Contains(t, rs.Body.String(), "exceeds the maximum complexity of 100")
+	})
+}
+
+func TestForbiddenError(t *testing.T) {
+	logger := log.New()
+	service := risk.NewService(risk.NewStore(logger), logger, risk.WithPolicy(auth.DefaultPolicy()))
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := &auth.Principal{Subject: "v", Roles: []string{auth.RoleViewer}}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	})
+	assert.NoError(t, graphqlapi.RegisterHandlers(router, service, 5, 100, logger))
+
+	rs := post(router, `mutation { createRisk(input: {state: "open", title: "t", description: "d"}) { id } }`, nil)
+	var result struct {
+		Errors []struct {
+			Message    string                 `json:"message"`
+			Extensions map[string]interface{} `json:"extensions"`
+		} `json:"errors"`
+	}
+	assert.NoError(t, json.Unmarshal(rs.Body.Bytes(), &result))
+	if assert.Len(t, result.Errors, 1) {
+		assert.Equal(t, "missing permission: create", result.Errors[0].Message)
+		assert.Equal(t, map[string]interface{}{"code": "FORBIDDEN", "permission": "create"}, result.Errors[0].Extensions)
+	}
+}
+
+func TestConfidentialRisk(t *testing.T) {
+	logger := log.New()
+	service := risk.NewService(risk.NewStore(logger), logger, risk.WithPolicy(auth.DefaultPolicy()))
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := &auth.Principal{Subject: "c", Roles: []string{auth.RoleContributor}}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	})
+	assert.NoError(t, graphqlapi.RegisterHandlers(router, service, 5, 100, logger))
+
+	rs := post(router, `mutation { createRisk(input: {state: "open", title: "t", description: "d", confidential: true}) { description confidential } }`, nil)
+	assert.Equal(t, `{"data":{"createRisk":{"confidential":true,"description":"[redacted]"}}}`, strings.TrimSpace(rs.Body.String()))
+}
+
+func TestRelatedFields(t *testing.T) {
+	logger := log.New()
+	service := risk.NewService(risk.NewStore(logger), logger)
+	router := newRouter(t, service)
+	created, err := service.Create(context.Background(), &risk.CreateRiskRequest{State: "open", Title: "t", Description: "d"})
+	assert.NoError(t, err)
+	id := created.ID
+
+	rs := post(router, `mutation($id: ID!) { updateRisk(id: $id, input: {state: "closed", tags: ["Water", " leak"]}) { state title tags } }`,
+		map[string]interface{}{"id": id})
+	assert.Equal(t, `{"data":{"updateRisk":{"state":"closed","tags":["water","leak"],"title":"t"}}}`, strings.TrimSpace(rs.Body.String()))
+	rs = post(router, `mutation($id: ID!) { addComment(riskId: $id, body: " fixed ") { body author } }`,
+		map[string]interface{}{"id": id})
+	assert.Equal(t, `{"data":{"addComment":{"author":"","body":"fixed"}}}`, strings.TrimSpace(rs.Body.String()))
+	rs = post(router, `mutation($id: ID!) { addMitigation(riskId: $id, input: {title: "replace"}) { title status } }`,
+		map[string]interface{}{"id": id})
+	assert.Equal(t, `{"data":{"addMitigation":{"status":"planned","title":"replace"}}}`, strings.TrimSpace(rs.Body.String()))
+
+	rs = post(router, `query($id: ID!) { risk(id: $id) { comments { body } mitigations { title } history { type state tags } } }`,
+		map[string]interface{}{"id": id})
+	assert.JSONEq(t, `{"data":{"risk":{
+		"comments":[{"body":"fixed"}],
+		"mitigations":[{"title":"replace"}],
+		"history":[{"type":"risk.created","state":"open","tags":[]},{"type":"risk.updated","state":"closed","tags":["water","leak"]}]
+	}}}`, rs.Body.String())
+
+	t.Run("Update Of Unknown Risk", func(t *testing.T) {
+		rs := post(router, `mutation { updateRisk(id: "unknown", input: {title: "t"}) { id } }`, nil)
+		assert.Contains(t, rs.Body.String(), `"errors"`)
+	})
+}
+
+func TestRelatedFieldsAreBatched(t *testing.T) {
+	service := &mocks.Service{}
+	router := newRouter(t, service)
+	service.On("GetAll", mock.Anything, 0, 3).
+		Return([]*entity.Risk{
+			{ID: "1", State: "open", Title: "a", Description: "d"},
+			{ID: "2", State: "open", Title: "b", Description: "d"},
+		}, nil).Once()
+	service.On("Comments", mock.Anything, []string{"1", "2"}).
+		Return(map[string][]*entity.Comment{"2": {{ID: "c", RiskID: "2", Body: "b"}}}, nil).Once()
+
+	rs := post(router, `{ risks(first: 2) { edges { node { id comments { body } } } } }`, nil)
+	assert.JSONEq(t, `{"data":{"risks":{"edges":[
+		{"node":{"id":"1","comments":[]}},
+		{"node":{"id":"2","comments":[{"body":"b"}]}}
+	]}}}`, rs.Body.String())
+	service.AssertExpectations(t)
+}
@@ -0,0 +1,76 @@
+package grpcapi
+
+import (
+	"context"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpccredentials "google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// AuthOptions returns the server options authenticating the calls with the same credentials as the REST API,
+// sent as the x-api-key or authorization metadata or as the client certificate of the connection. The health
+// service stays unauthenticated.
+func AuthOptions(authenticator auth.Authenticator) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler) (interface{}, error) {
+			ctx, err := authenticate(ctx, authenticator, info.FullMethod)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+			handler grpc.StreamHandler) error {
+			ctx, err := authenticate(stream.Context(), authenticator, info.FullMethod)
+			if err != nil {
+				return err
+			}
+			return handler(srv, &contextStream{stream, ctx})
+		}),
+	}
+}
+
+func authenticate(ctx context.Context, authenticator auth.Authenticator, method string) (context.Context, error) {
+	if isHealthCheck(method) {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	credentials := auth.Credentials{}
+	if values := md.Get(strings.ToLower(auth.HeaderAPIKey)); len(values) > 0 {
+		credentials.APIKey = values[0]
+	}
+	if values := md.Get("authorization"); len(values) > 0 {
+		credentials.BearerToken = auth.BearerToken(values[0])
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(grpccredentials.TLSInfo); ok {
+			credentials.ClientCertificate = auth.VerifiedClientCertificate(&info.State)
+		}
+	}
+	principal, err := authenticator.Authenticate(ctx, credentials)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+func isHealthCheck(method string) bool {
+	return strings.HasPrefix(method, "/"+healthpb.Health_ServiceDesc.ServiceName+"/")
+}
+
+// contextStream replaces the context of a stream with the one set by an interceptor
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,237 @@
+// Package grpcapi serves the risk API over gRPC, sharing risk.Service with the REST API.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"github.com/vikasgithub/risky-plumbers/internal/entity"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"github.com/vikasgithub/risky-plumbers/internal/tenant"
+	"github.com/vikasgithub/risky-plumbers/pkg/riskpb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"strings"
+)
+
+const defaultLimit = 100
+
+type server struct {
+	riskpb.UnimplementedRiskServiceServer
+	service risk.Service
+	broker  *risk.Broker
+	logger  log.Logger
+}
+
+// NewServer creates a gRPC server exposing the RiskService together with the gRPC health and reflection services.
+// WatchRisks streams the events published to broker.
+func NewServer(service risk.Service, broker *risk.Broker, logger log.Logger, opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(opts...)
+	riskpb.RegisterRiskServiceServer(s, &server{service: service, broker: broker, logger: logger})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(riskpb.RiskService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(s, healthServer)
+	reflection.Register(s)
+	return s
+}
+
+func (s *server) GetRisk(ctx context.Context, rq *riskpb.GetRiskRequest) (*riskpb.Risk, error) {
+	r, err := s.service.Get(ctx, rq.GetId())
+	if err != nil {
+		return nil, s.toStatus(ctx, err)
+	}
+	return toProto(r), nil
+}
+
+func (s *server) ListRisks(ctx context.Context, rq *riskpb.ListRisksRequest) (*riskpb.ListRisksResponse, error) {
+	if rq.GetOffset() < 0 || rq.GetLimit() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "offset and limit must not be negative")
+	}
+	limit := int(rq.GetLimit())
+	if limit == 0 {
+		limit = defaultLimit
+	}
+	risks, err := s.service.GetAll(ctx, int(rq.GetOffset()), limit)
+	if err != nil {
+		return nil, s.toStatus(ctx, err)
+	}
+	rs := &riskpb.ListRisksResponse{}
+	for _, r := range risks {
+		rs.Risks = append(rs.Risks, toProto(r))
+	}
+	return rs, nil
+}
+
+func (s *server) CreateRisk(ctx context.Context, rq *riskpb.CreateRiskRequest) (*riskpb.Risk, error) {
+	input := &risk.CreateRiskRequest{
+		State:        rq.GetState(),
+		Title:        rq.GetTitle(),
+		Description:  rq.GetDescription(),
+		Confidential: rq.GetConfidential(),
+		Tags:         rq.GetTags(),
+	}
+	// trim the input the same way as the REST API does
+	input.Bind(nil)
+	r, err := s.service.Create(ctx, input)
+	if err != nil {
+		return nil, s.toStatus(ctx, err)
+	}
+	return toProto(r), nil
+}
+
+func (s *server) UpdateRisk(ctx context.Context, rq *riskpb.UpdateRiskRequest) (*riskpb.Risk, error) {
+	input := &risk.UpdateRiskRequest{
+		State:        rq.State,
+		Title:        rq.Title,
+		Description:  rq.Description,
+		Confidential: rq.Confidential,
+	}
+	if rq.GetTags() != nil {
+		tags := rq.GetTags().GetValues()
+		input.Tags = &tags
+	}
+	r, err := s.service.Update(ctx, rq.GetId(), input)
+	if err != nil {
+		return nil, s.toStatus(ctx, err)
+	}
+	return toProto(r), nil
+}
+
+func (s *server) TransitionRisk(ctx context.Context, rq *riskpb.TransitionRiskRequest) (*riskpb.Risk, error) {
+	input := &risk.TransitionRiskRequest{State: rq.GetState()}
+	// trim the input the same way as the REST API does
+	input.Bind(nil)
+	r, err := s.service.Update(ctx, rq.GetId(), &risk.UpdateRiskRequest{State: &input.State})
+	if err != nil {
+		return nil, s.toStatus(ctx, err)
+	}
+	return toProto(r), nil
+}
+
+func (s *server) ExportRisks(ctx context.Context, rq *riskpb.ExportRisksRequest) (*riskpb.ExportRisksResponse, error) {
+	risks, err := s.service.Export(ctx)
+	if err != nil {
+		return nil, s.toStatus(ctx, err)
+	}
+	rs := &riskpb.ExportRisksResponse{}
+	for _, r := range risks {
+		rs.Risks = append(rs.Risks, toProto(r))
+	}
+	return rs, nil
+}
+
+func (s *server) WatchRisks(rq *riskpb.WatchRisksRequest, stream riskpb.RiskService_WatchRisksServer) error {
+	if err := s.service.Authorize(stream.Context(), auth.ActionRead); err != nil {
+		return s.toStatus(stream.Context(), err)
+	}
+	tenantID := tenant.FromContext(stream.Context())
+	events, unsubscribe := s.broker.Subscribe()
+	defer unsubscribe()
+	// the headers tell the client that the changes made from now on are streamed
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if event.TenantID != tenantID || !matchesState(event, rq.GetStates()) || !matchesTag(event, rq.GetTags()) {
+				continue
+			}
+			err := stream.Send(&riskpb.RiskEvent{
+				Id:   event.ID,
+				Type: event.Type,
+				Time: timestamppb.New(event.Time),
+				Risk: toProto(event.Risk),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func matchesState(event risk.Event, states []string) bool {
+	if len(states) == 0 {
+		return true
+	}
+	for _, state := range states {
+		if strings.EqualFold(state, event.Risk.State) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesTag(event risk.Event, tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		for _, riskTag := range event.Risk.Tags {
+			if strings.EqualFold(tag, riskTag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func toProto(r *entity.Risk) *riskpb.Risk {
+	return &riskpb.Risk{
+		Id:           r.ID,
+		State:        r.State,
+		Title:        r.Title,
+		Description:  r.Description,
+		Confidential: r.Confidential,
+		Tags:         r.Tags,
+	}
+}
+
+// toStatus maps the service errors to gRPC status errors. The unexpected errors are logged and returned without
+// their cause, like the internal errors of the REST API.
+func (s *server) toStatus(ctx context.Context, err error) error {
+	var errs validation.Errors
+	var forbidden *errorstype.ForbiddenError
+	switch {
+	case errors.Is(err, errorstype.ErrRecordNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.As(err, &forbidden):
+		st := status.New(codes.PermissionDenied, err.Error())
+		info := &errdetails.ErrorInfo{Reason: "MISSING_PERMISSION", Metadata: map[string]string{"permission": forbidden.Permission}}
+		if detailed, detailsErr := st.WithDetails(info); detailsErr == nil {
+			st = detailed
+		}
+		return st.Err()
+	case errors.As(err, &errs):
+		st := status.New(codes.InvalidArgument, err.Error())
+		badRequest := &errdetails.BadRequest{}
+		for _, fieldErr := range errorstype.NewFieldErrors(errs, "en") {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       fieldErr.Field,
+				Description: fieldErr.Message,
+			})
+		}
+		if detailed, detailsErr := st.WithDetails(badRequest); detailsErr == nil {
+			st = detailed
+		}
+		return st.Err()
+	}
+	s.logger.WithContext(ctx).Errorf("grpc request failed: %v", err)
+	return status.Error(codes.Internal, "internal error")
+}
@@ -0,0 +1,56 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"github.com/vikasgithub/risky-plumbers/internal/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// TenantOptions returns the server options resolving the tenant of the calls like the REST API does, from the
+// principal or the x-tenant-id metadata. They must follow the AuthOptions.
+func TenantOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler) (interface{}, error) {
+			ctx, err := resolveTenant(ctx, info.FullMethod)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+			handler grpc.StreamHandler) error {
+			ctx, err := resolveTenant(stream.Context(), info.FullMethod)
+			if err != nil {
+				return err
+			}
+			return handler(srv, &contextStream{stream, ctx})
+		}),
+	}
+}
+
+func resolveTenant(ctx context.Context, method string) (context.Context, error) {
+	if isHealthCheck(method) {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := ""
+	if values := md.Get(strings.ToLower(tenant.Header)); len(values) > 0 {
+		header = values[0]
+	}
+	principal, _ := auth.PrincipalFrom(ctx)
+	id, err := tenant.Resolve(principal, header)
+	if err != nil {
+		if errors.Is(err, tenant.ErrMismatch) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return tenant.WithTenant(ctx, id), nil
+}
@@ -0,0 +1,266 @@
+package grpcapitest
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"github.com/vikasgithub/risky-plumbers/internal/grpcapi"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	mocks "github.com/vikasgithub/risky-plumbers/internal/risk/mocks"
+	"github.com/vikasgithub/risky-plumbers/pkg/riskpb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+	"time"
+)
+
+func newClient(t *testing.T, opts ...grpc.ServerOption) *grpc.ClientConn {
+	logger := log.New()
+	broker := risk.NewBroker(100)
+	store := risk.NewStore(logger)
+	service := risk.NewService(store, logger)
+	relay := risk.NewRelay(store, risk.RelayConfig{PollInterval: 10 * time.Millisecond, BatchSize: 10}, logger, broker)
+	ctx, cancel := context.WithCancel(context.Background())
+	go relay.Run(ctx)
+	t.Cleanup(cancel)
+	return dial(t, grpcapi.NewServer(service, broker, logger, append(opts, grpcapi.TenantOptions()...)...))
+}
+
+// dial serves the server on an in-memory listener and returns a connection to it
+func dial(t *testing.T, server *grpc.Server) *grpc.ClientConn {
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestRiskService(t *testing.T) {
+	conn := newClient(t)
+	client := riskpb.NewRiskServiceClient(conn)
+	ctx := context.Background()
+
+	t.Run("Create And Get", func(t *testing.T) {
+		created, err := client.CreateRisk(ctx, &riskpb.CreateRiskRequest{State: "open", Title: " t ", Description: "d", Tags: []string{"Water"}})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, created.Id)
+		assert.Equal(t, "t", created.Title)
+		assert.Equal(t, []string{"water"}, created.Tags)
+
+		found, err := client.GetRisk(ctx, &riskpb.GetRiskRequest{Id: created.Id})
+		assert.NoError(t, err)
+		assert.Equal(t, created.Id, found.Id)
+	})
+
+	t.Run("Get Not Found", func(t *testing.T) {
+		_, err := client.GetRisk(ctx, &riskpb.GetRiskRequest{Id: "unknown"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Create Invalid", func(t *testing.T) {
+		_, err := client.CreateRisk(ctx, &riskpb.CreateRiskRequest{State: "open", Title: "t"})
+		st := status.Convert(err)
+		assert.Equal(t, codes.InvalidArgument, st.Code())
+		assert.Len(t, st.Details(), 1)
+		badRequest := st.Details()[0].(*errdetails.BadRequest)
+		assert.Equal(t, "description", badRequest.FieldViolations[0].Field)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		rs, err := client.ListRisks(ctx, &riskpb.ListRisksRequest{})
+		assert.NoError(t, err)
+		assert.Len(t, rs.Risks, 1)
+
+		_, err = client.ListRisks(ctx, &riskpb.ListRisksRequest{Offset: -1})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Watch", func(t *testing.T) {
+		watchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		stream, err := client.WatchRisks(watchCtx, &riskpb.WatchRisksRequest{States: []string{"closed"}, Tags: []string{"Water"}})
+		assert.NoError(t, err)
+		// the headers are received once the subscription is registered
+		_, err = stream.Header()
+		assert.NoError(t, err)
+
+		_, err = client.CreateRisk(ctx, &riskpb.CreateRiskRequest{State: "open", Title: "t", Description: "d", Tags: []string{"water"}})
+		assert.NoError(t, err)
+		_, err = client.CreateRisk(ctx, &riskpb.CreateRiskRequest{State: "closed", Title: "t", Description: "d"})
+		assert.NoError(t, err)
+		created, err := client.CreateRisk(ctx, &riskpb.CreateRiskRequest{State: "closed", Title: "t", Description: "d", Tags: []string{"water"}})
+		assert.NoError(t, err)
+
+		event, err := stream.Recv()
+		assert.NoError(t, err)
+		assert.Equal(t, risk.EventCreated, event.Type)
+		assert.Equal(t, created.Id, event.Risk.Id)
+	})
+
+	t.Run("Health", func(t *testing.T) {
+		rs, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, rs.Status)
+	})
+
+	t.Run("Update And Transition", func(t *testing.T) {
+		created, err := client.CreateRisk(ctx, &riskpb.CreateRiskRequest{State: "open", Title: "t", Description: "d", Tags: []string{"pipe"}})
+		assert.NoError(t, err)
+		title := "new"
+		updated, err := client.UpdateRisk(ctx, &riskpb.UpdateRiskRequest{Id: created.Id, Title: &title, Tags: &riskpb.TagList{}})
+		assert.NoError(t, err)
+		assert.Equal(t, "new", updated.Title)
+		assert.Equal(t, "d", updated.Description, "the fields left out keep their value")
+		assert.Empty(t, updated.Tags)
+
+		transitioned, err := client.TransitionRisk(ctx, &riskpb.TransitionRiskRequest{Id: created.Id, State: " closed "})
+		assert.NoError(t, err)
+		assert.Equal(t, "closed", transitioned.State)
+
+		_, err = client.TransitionRisk(ctx, &riskpb.TransitionRiskRequest{Id: created.Id, State: "gone"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		_, err = client.UpdateRisk(ctx, &riskpb.UpdateRiskRequest{Id: "unknown", Title: &title})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		rs, err := client.ExportRisks(ctx, &riskpb.ExportRisksRequest{})
+		assert.NoError(t, err)
+		listed, err := client.ListRisks(ctx, &riskpb.ListRisksRequest{})
+		assert.NoError(t, err)
+		assert.Len(t, rs.Risks, len(listed.Risks))
+	})
+
+	t.Run("Create Confidential", func(t *testing.T) {
+		created, err := client.CreateRisk(ctx, &riskpb.CreateRiskRequest{State: "open", Title: "t", Description: "d", Confidential: true})
+		assert.NoError(t, err)
+		// everyone may read confidential risks without a policy
+		assert.True(t, created.Confidential)
+		assert.Equal(t, "d", created.Description)
+	})
+}
+
+func TestInternalErrorsAreNotSent(t *testing.T) {
+	service := &mocks.Service{}
+	service.On("Get", mock.Anything, "1").Return(nil, errors.New("dial tcp 10.0.0.7:5432: connection refused"))
+	logger := log.New()
+	conn := dial(t, grpcapi.NewServer(service, risk.NewBroker(100), logger, grpcapi.TenantOptions()...))
+
+	_, err := riskpb.NewRiskServiceClient(conn).GetRisk(context.Background(), &riskpb.GetRiskRequest{Id: "1"})
+	st := status.Convert(err)
+	assert.Equal(t, codes.Internal, st.Code())
+	assert.Equal(t, "internal error", st.Message())
+}
+
+func TestAuthentication(t *testing.T) {
+	authenticator, _ := auth.NewAPIKeyAuthenticator([]auth.APIKey{{Name: "ci", Key: "ci-key"}})
+	conn := newClient(t, grpcapi.AuthOptions(authenticator)...)
+	client := riskpb.NewRiskServiceClient(conn)
+
+	_, err := client.ListRisks(context.Background(), &riskpb.ListRisksRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "wrong-key")
+	_, err = client.ListRisks(ctx, &riskpb.ListRisksRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "ci-key")
+	_, err = client.ListRisks(ctx, &riskpb.ListRisksRequest{})
+	assert.NoError(t, err)
+
+	watchCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.WatchRisks(watchCtx, &riskpb.WatchRisksRequest{})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	health, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err, "the health service stays unauthenticated")
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, health.Status)
+}
+
+func TestPermissionDenied(t *testing.T) {
+	logger := log.New()
+	service := risk.NewService(risk.NewStore(logger), logger, risk.WithPolicy(auth.DefaultPolicy()))
+	authenticator, _ := auth.NewAPIKeyAuthenticator([]auth.APIKey{{Name: "viewer", Key: "viewer-key", Roles: []string{auth.RoleViewer}}})
+	server := grpcapi.NewServer(service, risk.NewBroker(1), logger, grpcapi.AuthOptions(authenticator)...)
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	defer server.Stop()
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	defer conn.Close()
+	client := riskpb.NewRiskServiceClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "viewer-key")
+
+	_, err = client.ListRisks(ctx, &riskpb.ListRisksRequest{})
+	assert.NoError(t, err)
+
+	_, err = client.CreateRisk(ctx, &riskpb.CreateRiskRequest{State: "open", Title: "t", Description: "d"})
+	st := status.Convert(err)
+	assert.Equal(t, codes.PermissionDenied, st.Code())
+	if assert.Len(t, st.Details(), 1) {
+		info := st.Details()[0].(*errdetails.ErrorInfo)
+		assert.Equal(t, "MISSING_PERMISSION", info.Reason)
+		assert.Equal(t, "create", info.Metadata["permission"])
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+	authenticator, _ := auth.NewAPIKeyAuthenticator([]auth.APIKey{
+		{Name: "acme", Key: "acme-key", Tenant: "acme"},
+		{Name: "globex", Key: "globex-key", Tenant: "globex"},
+	})
+	conn := newClient(t, grpcapi.AuthOptions(authenticator)...)
+	client := riskpb.NewRiskServiceClient(conn)
+	acme := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "acme-key")
+	globex := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "globex-key")
+
+	watchCtx, cancel := context.WithTimeout(globex, 5*time.Second)
+	defer cancel()
+	stream, err := client.WatchRisks(watchCtx, &riskpb.WatchRisksRequest{})
+	assert.NoError(t, err)
+	_, err = stream.Header()
+	assert.NoError(t, err)
+
+	created, err := client.CreateRisk(acme, &riskpb.CreateRiskRequest{State: "open", Title: "t", Description: "d"})
+	assert.NoError(t, err)
+	_, err = client.GetRisk(globex, &riskpb.GetRiskRequest{Id: created.Id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	rs, err := client.ListRisks(globex, &riskpb.ListRisksRequest{})
+	assert.NoError(t, err)
+	assert.Empty(t, rs.Risks)
+
+	own, err := client.CreateRisk(globex, &riskpb.CreateRiskRequest{State: "open", Title: "t", Description: "d"})
+	assert.NoError(t, err)
+	// the event of acme is not streamed to globex
+	event, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, own.Id, event.Risk.Id)
+
+	ctx := metadata.AppendToOutgoingContext(acme, "x-tenant-id", "globex")
+	_, err = client.ListRisks(ctx, &riskpb.ListRisksRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -2,14 +2,35 @@ package healthcheck
 
 import (
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
 	"net/http"
 )
 
-func RegisterHandlers(r *chi.Mux) {
-	r.Get("/healthcheck", healthCheckHandler)
+// RegisterHandlers registers the probes and the version of the service. They are not authenticated.
+func RegisterHandlers(r chi.Router, health *Health) {
+	r.Get("/healthcheck", liveHandler)
+	r.Get("/livez", liveHandler)
+	r.Get("/readyz", readyHandler(health))
+	r.Get("/version", versionHandler)
 }
 
-// healthcheck responds to a healthcheck request.
-func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
+// liveHandler responds while the process is able to serve requests, even during the shutdown
+func liveHandler(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, map[string]string{"status": StatusOK})
+}
+
+// readyHandler responds with 503 when a check failed or the service is shutting down
+func readyHandler(health *Health) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := health.Ready(r.Context())
+		if report.Status != StatusOK {
+			render.Status(r, http.StatusServiceUnavailable)
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		render.JSON(w, r, report)
+	}
+}
+
+func versionHandler(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, Build())
 }
@@ -0,0 +1,28 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// errDiskSpaceUnsupported is returned by freeBytes on the platforms where the free space can not be read
+var errDiskSpaceUnsupported = errors.New("the free disk space can not be read on this platform")
+
+// DiskSpace returns a check failing when the file system of path has less than minFreeBytes available.
+// The check always passes on the platforms where the free space can not be read.
+func DiskSpace(path string, minFreeBytes uint64) Check {
+	return func(ctx context.Context) error {
+		free, err := freeBytes(path)
+		if errors.Is(err, errDiskSpaceUnsupported) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if free < minFreeBytes {
+			return fmt.Errorf("%d bytes are available on %s, %d are required", free, path, minFreeBytes)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package healthcheck
+
+import "syscall"
+
+// freeBytes returns the space available to an unprivileged user on the file system of path
+func freeBytes(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
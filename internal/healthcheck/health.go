@@ -0,0 +1,123 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Statuses of the readiness report and of its checks
+const (
+	StatusOK           = "ok"
+	StatusFailed       = "failed"
+	StatusShuttingDown = "shutting down"
+)
+
+// Check returns an error when a dependency of the service can not be used.
+type Check func(ctx context.Context) error
+
+// CheckResult is the outcome of a check.
+type CheckResult struct {
+	Status     string `json:"status"`
+	DurationMS int64  `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Report is the readiness of the service, the service is ready when every check passed.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Health runs the readiness checks of the service.
+type Health struct {
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	checks map[string]Check
+	// shuttingDown makes the service unready, so that the load balancers stop sending it requests
+	shuttingDown atomic.Bool
+}
+
+// New creates a Health running each check for at most timeout.
+func New(timeout time.Duration) *Health {
+	return &Health{timeout: timeout, checks: map[string]Check{}}
+}
+
+// Register adds a check, it replaces the check already registered with the same name.
+func (h *Health) Register(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check
+}
+
+// Shutdown marks the service as shutting down, it is never ready again.
+func (h *Health) Shutdown() {
+	h.shuttingDown.Store(true)
+}
+
+// Ready runs the checks concurrently and reports their outcome. The checks are skipped once the service is
+// shutting down.
+func (h *Health) Ready(ctx context.Context) Report {
+	if h.shuttingDown.Load() {
+		return Report{Status: StatusShuttingDown, Checks: map[string]CheckResult{}}
+	}
+	h.mu.RLock()
+	names := make([]string, 0, len(h.checks))
+	for name := range h.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = h.checks[name]
+	}
+	h.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = h.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(names))}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFailed
+		}
+	}
+	return report
+}
+
+// run runs a check with the timeout, a check ignoring its context is abandoned once the timeout expires
+func (h *Health) run(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- check(ctx) }()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	result := CheckResult{Status: StatusOK, DurationMS: time.Since(start).Milliseconds()}
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = errors.New("timed out after " + h.timeout.String())
+	}
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	}
+	return result
+}
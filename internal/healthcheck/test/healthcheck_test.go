@@ -0,0 +1,107 @@
+package healthchecktest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/vikasgithub/risky-plumbers/internal/healthcheck"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func serve(health *healthcheck.Health, path string) *httptest.ResponseRecorder {
+	router := chi.NewRouter()
+	healthcheck.RegisterHandlers(router, health)
+	rs := httptest.NewRecorder()
+	router.ServeHTTP(rs, httptest.NewRequest(http.MethodGet, path, nil))
+	return rs
+}
+
+func ok(ctx context.Context) error {
+	return nil
+}
+
+func TestLiveness(t *testing.T) {
+	health := healthcheck.New(time.Second)
+	health.Register("failing", func(ctx context.Context) error { return errors.New("down") })
+	health.Shutdown()
+	for _, path := range []string{"/livez", "/healthcheck"} {
+		rs := serve(health, path)
+		assert.Equal(t, http.StatusOK, rs.Code, path)
+		assert.JSONEq(t, `{"status":"ok"}`, rs.Body.String(), path)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	health := healthcheck.New(time.Second)
+	health.Register("repository", ok)
+	health.Register("eventBus", ok)
+
+	rs := serve(health, "/readyz")
+	assert.Equal(t, http.StatusOK, rs.Code)
+	var report healthcheck.Report
+	assert.NoError(t, json.Unmarshal(rs.Body.Bytes(), &report))
+	assert.Equal(t, healthcheck.StatusOK, report.Status)
+	assert.Len(t, report.Checks, 2)
+	assert.Equal(t, healthcheck.StatusOK, report.Checks["repository"].Status)
+
+	t.Run("Failed Check", func(t *testing.T) {
+		health.Register("eventBus", func(ctx context.Context) error { return errors.New("nats: connection closed") })
+		rs := serve(health, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, rs.Code)
+		var report healthcheck.Report
+		assert.NoError(t, json.Unmarshal(rs.Body.Bytes(), &report))
+		assert.Equal(t, healthcheck.StatusFailed, report.Status)
+		assert.Equal(t, healthcheck.StatusOK, report.Checks["repository"].Status)
+		assert.Equal(t, healthcheck.CheckResult{Status: healthcheck.StatusFailed, Error: "nats: connection closed"},
+			report.Checks["eventBus"])
+	})
+
+	t.Run("Shutting Down", func(t *testing.T) {
+		health.Register("eventBus", ok)
+		health.Shutdown()
+		rs := serve(health, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, rs.Code)
+		assert.JSONEq(t, `{"status":"shutting down","checks":{}}`, rs.Body.String())
+	})
+}
+
+func TestReadinessTimeout(t *testing.T) {
+	health := healthcheck.New(50 * time.Millisecond)
+	blocked := make(chan struct{})
+	defer close(blocked)
+	// a check ignoring its context does not hold the probe
+	health.Register("stuck", func(ctx context.Context) error {
+		<-blocked
+		return nil
+	})
+	start := time.Now()
+	report := health.Ready(context.Background())
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, healthcheck.StatusFailed, report.Status)
+	assert.Equal(t, "timed out after 50ms", report.Checks["stuck"].Error)
+}
+
+func TestDiskSpace(t *testing.T) {
+	assert.NoError(t, healthcheck.DiskSpace(t.TempDir(), 1)(context.Background()))
+	assert.Error(t, healthcheck.DiskSpace(t.TempDir(), math.MaxUint64)(context.Background()))
+	assert.Error(t, healthcheck.DiskSpace("/does/not/exist", 1)(context.Background()))
+}
+
+func TestVersion(t *testing.T) {
+	healthcheck.Version, healthcheck.Commit = "1.4.0", "0a1b2c3"
+	defer func() { healthcheck.Version, healthcheck.Commit = "dev", "" }()
+
+	rs := serve(healthcheck.New(time.Second), "/version")
+	assert.Equal(t, http.StatusOK, rs.Code)
+	var info healthcheck.BuildInfo
+	assert.NoError(t, json.Unmarshal(rs.Body.Bytes(), &info))
+	assert.Equal(t, "1.4.0", info.Version)
+	assert.Equal(t, "0a1b2c3", info.Commit)
+	assert.NotEmpty(t, info.GoVersion)
+}
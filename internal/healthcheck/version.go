@@ -0,0 +1,47 @@
+package healthcheck
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Version and Commit are injected at build time:
+//
+//	go build -ldflags "-X github.com/vikasgithub/risky-plumbers/internal/healthcheck.Version=1.4.0 \
+//	  -X github.com/vikasgithub/risky-plumbers/internal/healthcheck.Commit=$(git rev-parse HEAD)" ./cmd
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+// BuildInfo describes the build of the running binary.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	CommitAt  string `json:"commitAt,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Build returns the build of the running binary. The commit falls back to the revision recorded by the go
+// toolchain when it was not injected.
+func Build() BuildInfo {
+	info := BuildInfo{Version: Version, Commit: Commit, GoVersion: runtime.Version()}
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			info.CommitAt = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
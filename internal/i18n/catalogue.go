@@ -0,0 +1,81 @@
+// Package i18n provides the message catalogue used to localize user facing messages.
+package i18n
+
+import (
+	"fmt"
+	"golang.org/x/text/language"
+	"net/http"
+	"strings"
+)
+
+// DefaultLanguage is used when the caller does not ask for a supported language.
+const DefaultLanguage = "en"
+
+// catalogue maps a language to its message templates, indexed by message code.
+// Placeholders in the form of {name} are replaced with the matching message parameter.
+var catalogue = map[string]map[string]string{
+	"en": {
+		"required":      "cannot be blank",
+		"length_max":    "the length must be no more than {max}",
+		"length_min":    "the length must be no less than {min}",
+		"length_range":  "the length must be between {min} and {max}",
+		"invalid_value": "must be a valid value",
+		"invalid_url":   "must be a valid http or https URL",
+	},
+	"de": {
+		"required":      "darf nicht leer sein",
+		"length_max":    "die Länge darf höchstens {max} betragen",
+		"length_min":    "die Länge muss mindestens {min} betragen",
+		"length_range":  "die Länge muss zwischen {min} und {max} liegen",
+		"invalid_value": "muss ein gültiger Wert sein",
+		"invalid_url":   "muss eine gültige http- oder https-URL sein",
+	},
+	"fr": {
+		"required":      "ne peut pas être vide",
+		"length_max":    "la longueur ne doit pas dépasser {max}",
+		"length_min":    "la longueur doit être d'au moins {min}",
+		"length_range":  "la longueur doit être comprise entre {min} et {max}",
+		"invalid_value": "doit être une valeur valide",
+		"invalid_url":   "doit être une URL http ou https valide",
+	},
+}
+
+var matcher = language.NewMatcher([]language.Tag{
+	language.English, // the first tag is the fallback
+	language.German,
+	language.French,
+})
+
+// Language returns the supported language that best matches the given Accept-Language header value.
+func Language(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLanguage
+	}
+	tag, _, _ := matcher.Match(tags...)
+	base, _ := tag.Base()
+	if _, ok := catalogue[base.String()]; !ok {
+		return DefaultLanguage
+	}
+	return base.String()
+}
+
+// RequestLanguage returns the supported language requested by the Accept-Language header of r.
+func RequestLanguage(r *http.Request) string {
+	return Language(r.Header.Get("Accept-Language"))
+}
+
+// Message returns the localized message for code, or fallback if the catalogue has no entry for it.
+func Message(lang, code, fallback string, params map[string]interface{}) string {
+	template, ok := catalogue[lang][code]
+	if !ok {
+		template, ok = catalogue[DefaultLanguage][code]
+	}
+	if !ok {
+		return fallback
+	}
+	for name, value := range params {
+		template = strings.ReplaceAll(template, "{"+name+"}", fmt.Sprint(value))
+	}
+	return template
+}
@@ -0,0 +1,166 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/tenant"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// HeaderKey is the request header carrying the client supplied idempotency key
+	HeaderKey = "Idempotency-Key"
+	// HeaderReplayed is set on responses which are replayed from the store
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// replayedHeaders lists the response headers which are stored and replayed along with the body
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+// Middleware replays the stored response of unsafe requests sent with an Idempotency-Key header.
+// The first response of a key is kept for ttl. Reusing a key with a different request gets a 422, and
+// retrying while the first request is still being processed gets a 409. Responses with a 5xx status are
+// not stored so that the request can be retried, nor are the requests whose handler panics.
+func Middleware(store Store, ttl time.Duration, logger log.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(HeaderKey)
+			if key == "" || !isUnsafe(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxKeyLength {
+				render.Render(w, r, &errorstype.ErrResponse{
+					HTTPStatusCode: http.StatusBadRequest,
+					StatusText:     "Invalid request.",
+					ErrorText:      "idempotency key must not be longer than 255 characters",
+				})
+				return
+			}
+
+			// the keys of different callers and tenants must not collide
+			if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+				key = principal.Method + ":" + principal.Subject + ":" + key
+			}
+			key = tenant.FromContext(r.Context()) + ":" + key
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				render.Render(w, r, errorstype.ErrBind(err))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			fingerprint := fingerprint(r, body)
+			existing, err := store.Reserve(r.Context(), key, &Record{
+				Fingerprint: fingerprint,
+				ExpiresAt:   time.Now().Add(ttl),
+			})
+			if err != nil {
+				render.Render(w, r, errorstype.ErrInternal(err))
+				return
+			}
+			if existing != nil {
+				replay(w, r, existing, fingerprint)
+				return
+			}
+
+			var buf bytes.Buffer
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&buf)
+			served := false
+			defer func() {
+				// a panic of next must not keep the key reserved until it expires
+				if !served {
+					release(r, store, key, logger)
+				}
+			}()
+			next.ServeHTTP(ww, r)
+			served = true
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= http.StatusInternalServerError {
+				release(r, store, key, logger)
+				return
+			}
+			header := http.Header{}
+			for _, name := range replayedHeaders {
+				if value := ww.Header().Get(name); value != "" {
+					header.Set(name, value)
+				}
+			}
+			err = store.Complete(context.WithoutCancel(r.Context()), key, &Record{
+				Fingerprint: fingerprint,
+				Completed:   true,
+				StatusCode:  status,
+				Header:      header,
+				Body:        buf.Bytes(),
+				ExpiresAt:   time.Now().Add(ttl),
+			})
+			if err != nil {
+				logger.WithContext(r.Context()).Errorf("failed to store the response of the idempotency key, "+
+					"its retries get 409 until it expires: %v", err)
+			}
+		})
+	}
+}
+
+// release removes the reservation of key, the request is still served when it fails
+func release(r *http.Request, store Store, key string, logger log.Logger) {
+	if err := store.Release(context.WithoutCancel(r.Context()), key); err != nil {
+		logger.WithContext(r.Context()).Errorf("failed to release the idempotency key, "+
+			"its retries get 409 until it expires: %v", err)
+	}
+}
+
+func replay(w http.ResponseWriter, r *http.Request, record *Record, fingerprint string) {
+	if record.Fingerprint != fingerprint {
+		render.Render(w, r, &errorstype.ErrResponse{
+			HTTPStatusCode: http.StatusUnprocessableEntity,
+			StatusText:     "Idempotency key reused.",
+			ErrorText:      "idempotency key was already used with a different request",
+		})
+		return
+	}
+	if !record.Completed {
+		render.Render(w, r, &errorstype.ErrResponse{
+			HTTPStatusCode: http.StatusConflict,
+			StatusText:     "Request in progress.",
+			ErrorText:      "a request with the same idempotency key is still being processed",
+		})
+		return
+	}
+	for name, values := range record.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(HeaderReplayed, "true")
+	w.WriteHeader(record.StatusCode)
+	w.Write(record.Body)
+}
+
+// fingerprint identifies a request by its method, path and body
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func isUnsafe(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut ||
+		method == http.MethodPatch || method == http.MethodDelete
+}
@@ -0,0 +1,147 @@
+// Package idempotency allows clients to safely retry non-idempotent requests using the Idempotency-Key header.
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Record is the stored outcome of a request made with an idempotency key.
+type Record struct {
+	// Fingerprint identifies the request payload the key was first used with
+	Fingerprint string
+	// Completed is false while the first request is still being processed
+	Completed  bool
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	ExpiresAt  time.Time
+}
+
+// Store keeps the idempotency records. Implementations backed by a shared persistent store
+// allow the replay to work across several instances of the service.
+type Store interface {
+	// Reserve stores an in-progress record for key unless a live record exists already,
+	// in which case the existing record is returned.
+	Reserve(ctx context.Context, key string, record *Record) (*Record, error)
+	// Complete replaces the record for key with the completed response.
+	Complete(ctx context.Context, key string, record *Record) error
+	// Release removes the record for key so that the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// sweepInterval is how often the memory store drops the expired records
+const sweepInterval = time.Minute
+
+type memoryStore struct {
+	mu        sync.Mutex
+	records   map[string]*Record
+	lastSweep time.Time
+}
+
+// NewMemoryStore returns a Store which keeps the records in memory. Expired records are dropped every minute.
+func NewMemoryStore() Store {
+	return &memoryStore{records: map[string]*Record{}, lastSweep: time.Now()}
+}
+
+func (s *memoryStore) Reserve(ctx context.Context, key string, record *Record) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.lastSweep) > sweepInterval {
+		s.sweep(now)
+	}
+	if existing, ok := s.records[key]; ok && now.Before(existing.ExpiresAt) {
+		return existing, nil
+	}
+	s.records[key] = record
+	return nil, nil
+}
+
+// sweep drops the expired records, so that the keys which are never retried do not pile up
+func (s *memoryStore) sweep(now time.Time) {
+	for key, record := range s.records {
+		if !now.Before(record.ExpiresAt) {
+			delete(s.records, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+func (s *memoryStore) Complete(ctx context.Context, key string, record *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = record
+	return nil
+}
+
+func (s *memoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+type redisStore struct {
+	client    redis.Cmdable
+	keyPrefix string
+}
+
+// NewRedisStore returns a Store keeping the records in redis, so that a retry is replayed by any instance of the
+// service. The records expire with their ExpiresAt, a record which expired already is not reserved.
+func NewRedisStore(client redis.Cmdable, keyPrefix string) Store {
+	return &redisStore{client: client, keyPrefix: keyPrefix}
+}
+
+func (s *redisStore) Reserve(ctx context.Context, key string, record *Record) (*Record, error) {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		ttl := time.Until(record.ExpiresAt)
+		if ttl <= 0 {
+			// redis would keep the record forever, and reporting the key as reserved would run the request again
+			return nil, fmt.Errorf("the idempotency record %q expires before it is stored", key)
+		}
+		reserved, err := s.client.SetNX(ctx, s.keyPrefix+key, value, ttl).Result()
+		if err != nil || reserved {
+			return nil, err
+		}
+		existing, err := s.client.Get(ctx, s.keyPrefix+key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			// the existing record expired in between, try again
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var record Record
+		if err := json.Unmarshal(existing, &record); err != nil {
+			return nil, fmt.Errorf("invalid idempotency record %q: %w", key, err)
+		}
+		return &record, nil
+	}
+}
+
+func (s *redisStore) Complete(ctx context.Context, key string, record *Record) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	ttl := time.Until(record.ExpiresAt)
+	if ttl <= 0 {
+		return s.Release(ctx, key)
+	}
+	return s.client.Set(ctx, s.keyPrefix+key, value, ttl).Err()
+}
+
+func (s *redisStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.keyPrefix+key).Err()
+}
@@ -0,0 +1,192 @@
+package idempotencytest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"github.com/vikasgithub/risky-plumbers/internal/entity"
+	"github.com/vikasgithub/risky-plumbers/internal/idempotency"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	mocks "github.com/vikasgithub/risky-plumbers/internal/risk/mocks"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newRequest(key, body string) *http.Request {
+	rq, _ := http.NewRequest("POST", "/risks", bytes.NewBufferString(body))
+	rq.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		rq.Header.Set(idempotency.HeaderKey, key)
+	}
+	return rq
+}
+
+func TestIdempotency(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(idempotency.Middleware(idempotency.NewMemoryStore(), time.Hour, log.New()))
+	riskService := &mocks.Service{}
+	risk.RegisterHandlers(router, riskService, log.New())
+	body := `{"state":"open","title":"t","description":"d"}`
+
+	t.Run("Retry Replays First Response", func(t *testing.T) {
+		riskService.On("Create", mock.Anything, mock.Anything).
+			Return(&entity.Risk{ID: "1", State: "open", Title: "t", Description: "d"}, nil).Once()
+
+		rs := httptest.NewRecorder()
+		router.ServeHTTP(rs, newRequest("key-1", body))
+		assert.Equal(t, http.StatusCreated, rs.Result().StatusCode)
+		assert.Empty(t, rs.Header().Get(idempotency.HeaderReplayed))
+
+		retry := httptest.NewRecorder()
+		router.ServeHTTP(retry, newRequest("key-1", body))
+		assert.Equal(t, http.StatusCreated, retry.Result().StatusCode)
+		assert.Equal(t, "true", retry.Header().Get(idempotency.HeaderReplayed))
+		assert.Equal(t, rs.Body.String(), retry.Body.String())
+		riskService.AssertNumberOfCalls(t, "Create", 1)
+	})
+
+	t.Run("Key Reused With Different Body", func(t *testing.T) {
+		rs := httptest.NewRecorder()
+		router.ServeHTTP(rs, newRequest("key-1", `{"state":"open","title":"other","description":"d"}`))
+		assert.Equal(t, http.StatusUnprocessableEntity, rs.Result().StatusCode)
+		assert.Equal(t, `{"status":"Idempotency key reused.","error":"idempotency key was already used with a different request"}`,
+			strings.Trim(rs.Body.String(), "\n"))
+	})
+
+	t.Run("Server Errors Are Not Stored", func(t *testing.T) {
+		failing := chi.NewRouter()
+		failing.Use(idempotency.Middleware(idempotency.NewMemoryStore(), time.Hour, log.New()))
+		calls := 0
+		failing.Post("/risks", func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+		failing.ServeHTTP(httptest.NewRecorder(), newRequest("key-2", body))
+		failing.ServeHTTP(httptest.NewRecorder(), newRequest("key-2", body))
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("Panics Release The Key", func(t *testing.T) {
+		panicking := chi.NewRouter()
+		panicking.Use(middleware.Recoverer)
+		panicking.Use(idempotency.Middleware(idempotency.NewMemoryStore(), time.Hour, log.New()))
+		calls := 0
+		panicking.Post("/risks", func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				panic("boom")
+			}
+			w.WriteHeader(http.StatusCreated)
+		})
+		rs := httptest.NewRecorder()
+		panicking.ServeHTTP(rs, newRequest("key-3", body))
+		assert.Equal(t, http.StatusInternalServerError, rs.Code)
+		rs = httptest.NewRecorder()
+		panicking.ServeHTTP(rs, newRequest("key-3", body))
+		assert.Equal(t, http.StatusCreated, rs.Code, "the retry is served instead of getting 409")
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("Requests Without Key Are Not Deduplicated", func(t *testing.T) {
+		riskService.On("Create", mock.Anything, mock.Anything).
+			Return(nil, errors.New("invalid request")).Twice()
+		router.ServeHTTP(httptest.NewRecorder(), newRequest("", body))
+		router.ServeHTTP(httptest.NewRecorder(), newRequest("", body))
+		riskService.AssertNumberOfCalls(t, "Create", 3)
+	})
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	store := idempotency.NewMemoryStore()
+	existing, _ := store.Reserve(context.Background(), "key", &idempotency.Record{Fingerprint: "a", ExpiresAt: time.Now().Add(-time.Second)})
+	assert.Nil(t, existing)
+
+	existing, _ = store.Reserve(context.Background(), "key", &idempotency.Record{Fingerprint: "b", ExpiresAt: time.Now().Add(time.Hour)})
+	assert.Nil(t, existing)
+
+	existing, _ = store.Reserve(context.Background(), "key", &idempotency.Record{Fingerprint: "c", ExpiresAt: time.Now().Add(time.Hour)})
+	assert.Equal(t, "b", existing.Fingerprint)
+}
+
+func TestRedisStore(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	store := idempotency.NewRedisStore(client, "test:")
+	ctx := context.Background()
+
+	existing, err := store.Reserve(ctx, "key", &idempotency.Record{Fingerprint: "a", ExpiresAt: time.Now().Add(time.Hour)})
+	assert.NoError(t, err)
+	assert.Nil(t, existing)
+	assert.InDelta(t, time.Hour, server.TTL("test:key"), float64(2*time.Second))
+
+	existing, err = store.Reserve(ctx, "key", &idempotency.Record{Fingerprint: "b", ExpiresAt: time.Now().Add(time.Hour)})
+	assert.NoError(t, err)
+	assert.Equal(t, &idempotency.Record{Fingerprint: "a", ExpiresAt: existing.ExpiresAt}, existing)
+	assert.False(t, existing.Completed)
+
+	completed := &idempotency.Record{
+		Fingerprint: "a",
+		Completed:   true,
+		StatusCode:  http.StatusCreated,
+		Header:      http.Header{"Location": {"/risks/1"}},
+		Body:        []byte(`{"id":"1"}`),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	assert.NoError(t, store.Complete(ctx, "key", completed))
+	existing, err = store.Reserve(ctx, "key", &idempotency.Record{Fingerprint: "a", ExpiresAt: time.Now().Add(time.Hour)})
+	assert.NoError(t, err)
+	assert.True(t, existing.Completed)
+	assert.Equal(t, completed.Header, existing.Header)
+	assert.Equal(t, completed.Body, existing.Body)
+
+	// the record expires with the ttl
+	server.FastForward(2 * time.Hour)
+	existing, err = store.Reserve(ctx, "key", &idempotency.Record{Fingerprint: "c", ExpiresAt: time.Now().Add(time.Hour)})
+	assert.NoError(t, err)
+	assert.Nil(t, existing)
+
+	assert.NoError(t, store.Release(ctx, "key"))
+	assert.False(t, server.Exists("test:key"))
+
+	_, err = store.Reserve(ctx, "expired", &idempotency.Record{Fingerprint: "a", ExpiresAt: time.Now().Add(-time.Second)})
+	assert.Error(t, err, "an expired record is not reported as reserved")
+	assert.False(t, server.Exists("test:expired"))
+
+	server.Close()
+	_, err = store.Reserve(ctx, "key", &idempotency.Record{Fingerprint: "a", ExpiresAt: time.Now().Add(time.Hour)})
+	assert.Error(t, err)
+}
+
+func TestIdempotencyKeysAreScopedToThePrincipal(t *testing.T) {
+	router := chi.NewRouter()
+	authenticator, _ := auth.NewAPIKeyAuthenticator([]auth.APIKey{{Name: "a", Key: "key-a"}, {Name: "b", Key: "key-b"}})
+	router.Use(auth.Middleware(authenticator, log.New()))
+	router.Use(idempotency.Middleware(idempotency.NewMemoryStore(), time.Hour, log.New()))
+	riskService := &mocks.Service{}
+	risk.RegisterHandlers(router, riskService, log.New())
+	body := `{"state":"open","title":"t","description":"d"}`
+	riskService.On("Create", mock.Anything, mock.Anything).
+		Return(&entity.Risk{ID: "1", State: "open", Title: "t", Description: "d"}, nil).Twice()
+
+	for _, apiKey := range []string{"key-a", "key-b"} {
+		rq := newRequest("shared-key", body)
+		rq.Header.Set(auth.HeaderAPIKey, apiKey)
+		rs := httptest.NewRecorder()
+		router.ServeHTTP(rs, rq)
+		assert.Equal(t, http.StatusCreated, rs.Result().StatusCode)
+		assert.Empty(t, rs.Header().Get(idempotency.HeaderReplayed))
+	}
+	riskService.AssertExpectations(t)
+}
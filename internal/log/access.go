@@ -0,0 +1,65 @@
+package log
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5/middleware"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type accessFieldsKey struct{}
+
+// accessFields collects the fields added by the handlers to the access log entry of a request
+type accessFields struct {
+	mu     sync.Mutex
+	fields []interface{}
+}
+
+// AddAccessFields adds key-value pairs to the access log entry of the request of ctx, e.g. the principal once
+// it is authenticated. It does nothing outside of AccessLog.
+func AddAccessFields(ctx context.Context, keysAndValues ...interface{}) {
+	if access, ok := ctx.Value(accessFieldsKey{}).(*accessFields); ok {
+		access.mu.Lock()
+		access.fields = append(access.fields, keysAndValues...)
+		access.mu.Unlock()
+	}
+}
+
+// AccessLog writes an entry for every request once it is served, with its status, size and latency. It must run
+// after Middleware, so that the entries have the ids of the requests. The server errors are logged as errors.
+func AccessLog(logger Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			access := &accessFields{}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					// the handler wrote nothing
+					status = http.StatusOK
+				}
+				fields := []interface{}{
+					"method", r.Method,
+					"path", r.URL.Path,
+					"status", status,
+					"bytes", ww.BytesWritten(),
+					"latency", time.Since(start),
+					"remoteAddr", r.RemoteAddr,
+					"userAgent", r.UserAgent(),
+				}
+				access.mu.Lock()
+				fields = append(fields, access.fields...)
+				access.mu.Unlock()
+				entry := logger.WithContext(r.Context())
+				if status >= http.StatusInternalServerError {
+					entry.Errorw("request", fields...)
+				} else {
+					entry.Infow("request", fields...)
+				}
+			}()
+			next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), accessFieldsKey{}, access)))
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package log
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"time"
+)
+
+// Config configures the logger created by NewWithConfig.
+type Config struct {
+	// Level is debug, info, warn or error
+	Level string
+	// Encoding is json or console
+	Encoding string
+	// Development adds the stack traces to the warnings and panics on DPanic
+	Development bool
+	Sampling    SamplingConfig
+	// OutputPaths are stdout, stderr or the paths of files
+	OutputPaths []string
+	Rotation    RotationConfig
+}
+
+// SamplingConfig keeps the first Initial entries with the same level and message each second, then every
+// Thereafter-th entry.
+type SamplingConfig struct {
+	Enabled    bool
+	Initial    int
+	Thereafter int
+}
+
+// RotationConfig rotates the log files of the OutputPaths, the standard outputs are never rotated.
+type RotationConfig struct {
+	Enabled bool
+	// MaxSizeMB is the size of a file when it is rotated
+	MaxSizeMB int
+	// MaxBackups is the number of rotated files kept, they are all kept when 0
+	MaxBackups int
+	// MaxAgeDays is the age of the rotated files when they are deleted, they are never deleted when 0
+	MaxAgeDays int
+	// Compress gzips the rotated files
+	Compress bool
+}
+
+// NewWithConfig creates a logger from the configuration. The returned level changes the level of the logger
+// at runtime.
+func NewWithConfig(cfg Config) (Logger, zap.AtomicLevel, error) {
+	level, err := zap.ParseAtomicLevel(cfg.Level)
+	if err != nil {
+		return Logger{}, level, err
+	}
+
+	var encoder zapcore.Encoder
+	switch cfg.Encoding {
+	case "json":
+		encoderConfig := zap.NewProductionEncoderConfig()
+		encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	case "console":
+		encoder = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+	default:
+		return Logger{}, level, fmt.Errorf("unknown log encoding %q, it is json or console", cfg.Encoding)
+	}
+
+	if len(cfg.OutputPaths) == 0 {
+		return Logger{}, level, fmt.Errorf("the logs need at least one output path")
+	}
+	writers := make([]zapcore.WriteSyncer, 0, len(cfg.OutputPaths))
+	for _, path := range cfg.OutputPaths {
+		if cfg.Rotation.Enabled && path != "stdout" && path != "stderr" {
+			writers = append(writers, zapcore.AddSync(&lumberjack.Logger{
+				Filename:   path,
+				MaxSize:    cfg.Rotation.MaxSizeMB,
+				MaxBackups: cfg.Rotation.MaxBackups,
+				MaxAge:     cfg.Rotation.MaxAgeDays,
+				Compress:   cfg.Rotation.Compress,
+			}))
+			continue
+		}
+		writer, _, err := zap.Open(path)
+		if err != nil {
+			return Logger{}, level, err
+		}
+		writers = append(writers, writer)
+	}
+
+	output := zapcore.NewMultiWriteSyncer(writers...)
+	core := zapcore.NewCore(encoder, output, level)
+	if cfg.Sampling.Enabled {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, cfg.Sampling.Initial, cfg.Sampling.Thereafter)
+	}
+	core = &serviceCore{Core: core, audit: zapcore.NewCore(encoder, output, auditLevel)}
+	options := []zap.Option{zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel)}
+	if cfg.Development {
+		options = append(options, zap.Development(), zap.AddStacktrace(zapcore.WarnLevel))
+	}
+	return Logger{zap.New(core, options...).Sugar()}, level, nil
+}
+
+// auditLevel is the level of the audit logger, it does not follow the level of the service
+const auditLevel = zapcore.InfoLevel
+
+// serviceCore is the core of the loggers created by NewWithConfig. audit writes to the same outputs without
+// sampling and at auditLevel, see Logger.Audit.
+type serviceCore struct {
+	zapcore.Core
+	audit zapcore.Core
+}
+
+func (c *serviceCore) With(fields []zapcore.Field) zapcore.Core {
+	return &serviceCore{Core: c.Core.With(fields), audit: c.audit.With(fields)}
+}
+
+// Audit returns a logger writing every entry from the info level, whatever the sampling and the level of the
+// service are, so that no audit entry is dropped. It returns l for the loggers not created by NewWithConfig.
+func (l Logger) Audit() Logger {
+	return Logger{l.Desugar().WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if service, ok := core.(*serviceCore); ok {
+			return service.audit
+		}
+		return core
+	})).Sugar()}
+}
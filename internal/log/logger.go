@@ -2,6 +2,8 @@
 package log
 
 import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -16,9 +18,50 @@ const (
 	correlationIDKey
 )
 
-// New creates a new logger using the default configuration.
+// New creates a development logger, it is used until the configuration is loaded and in the tests.
+// NewWithConfig creates the logger of the service.
 func New() Logger {
-	//TODO add logging based on Production using an environment variable
 	l, _ := zap.NewDevelopment()
 	return Logger{l.Sugar()}
 }
+
+// WithContext returns a logger adding the request id, the correlation id and the trace of ctx to every entry.
+func (l Logger) WithContext(ctx context.Context) Logger {
+	var fields []interface{}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		fields = append(fields, "traceId", spanContext.TraceID().String(), "spanId", spanContext.SpanID().String())
+	}
+	if id := RequestID(ctx); id != "" {
+		fields = append(fields, "requestId", id)
+	}
+	if id := CorrelationID(ctx); id != "" {
+		fields = append(fields, "correlationId", id)
+	}
+	if len(fields) == 0 {
+		return l
+	}
+	return Logger{l.With(fields...)}
+}
+
+// WithRequestID returns a copy of ctx carrying the id of the request.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the id of the request of ctx, it is empty outside of a request.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithCorrelationID returns a copy of ctx carrying the correlation id, which is shared by the requests of a
+// single operation across services.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey, id)
+}
+
+// CorrelationID returns the correlation id of ctx, it is empty outside of a request.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey).(string)
+	return id
+}
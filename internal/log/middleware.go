@@ -0,0 +1,57 @@
+package log
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+const (
+	// HeaderRequestID identifies a single request, it is generated unless the caller sends a valid one
+	HeaderRequestID = "X-Request-ID"
+	// HeaderCorrelationID identifies the operation a request takes part in, it defaults to the request id
+	HeaderCorrelationID = "X-Correlation-ID"
+)
+
+// maxIDLength bounds the ids sent by the callers, as they are written to every log entry
+const maxIDLength = 128
+
+// Middleware reads the request id and the correlation id of the request, or generates them, adds them to the
+// context of the request and echoes them in the response headers. The request id is also the one of chi, so that
+// middleware.GetReqID returns it.
+func Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(HeaderRequestID)
+			if !validID(requestID) {
+				requestID = uuid.New().String()
+			}
+			correlationID := r.Header.Get(HeaderCorrelationID)
+			if !validID(correlationID) {
+				correlationID = requestID
+			}
+			w.Header().Set(HeaderRequestID, requestID)
+			w.Header().Set(HeaderCorrelationID, correlationID)
+
+			ctx := WithCorrelationID(WithRequestID(r.Context(), requestID), correlationID)
+			ctx = context.WithValue(ctx, middleware.RequestIDKey, requestID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// validID accepts the ids made of letters, digits and -._: so that a caller can not forge log entries
+func validID(id string) bool {
+	if id == "" || len(id) > maxIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '.', c == '_', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
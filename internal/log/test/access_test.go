@@ -0,0 +1,49 @@
+package logtest
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccessLog(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	router := chi.NewRouter()
+	router.Use(log.Middleware())
+	router.Use(log.AccessLog(log.Logger{SugaredLogger: zap.New(core).Sugar()}))
+	router.Get("/risks", func(w http.ResponseWriter, r *http.Request) {
+		log.AddAccessFields(r.Context(), "subject", "ada")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})
+	router.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	rq := httptest.NewRequest(http.MethodGet, "/risks", nil)
+	rq.Header.Set(log.HeaderRequestID, "req-1")
+	router.ServeHTTP(httptest.NewRecorder(), rq)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	entries := logs.All()
+	if assert.Len(t, entries, 2) {
+		fields := entries[0].ContextMap()
+		assert.Equal(t, zapcore.InfoLevel, entries[0].Level)
+		assert.Equal(t, "GET", fields["method"])
+		assert.Equal(t, "/risks", fields["path"])
+		assert.EqualValues(t, http.StatusCreated, fields["status"])
+		assert.EqualValues(t, 5, fields["bytes"])
+		assert.Contains(t, fields, "latency")
+		assert.Equal(t, "ada", fields["subject"])
+		assert.Equal(t, "req-1", fields["requestId"])
+
+		assert.Equal(t, zapcore.ErrorLevel, entries[1].Level)
+		assert.EqualValues(t, http.StatusBadGateway, entries[1].ContextMap()["status"])
+	}
+}
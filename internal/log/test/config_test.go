@@ -0,0 +1,69 @@
+package logtest
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewWithConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "service.log")
+	logger, level, err := log.NewWithConfig(log.Config{
+		Level:       "info",
+		Encoding:    "json",
+		OutputPaths: []string{path},
+		Rotation:    log.RotationConfig{Enabled: true, MaxSizeMB: 1},
+	})
+	assert.NoError(t, err)
+
+	logger.Debug("hidden")
+	logger.Infow("shown", "riskId", "1")
+	level.SetLevel(zap.DebugLevel)
+	logger.Debug("shown at runtime")
+	assert.NoError(t, logger.Sync())
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if assert.Len(t, lines, 2) {
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+		assert.Equal(t, "shown", entry["msg"])
+		assert.Equal(t, "info", entry["level"])
+		assert.Equal(t, "1", entry["riskId"])
+		assert.Contains(t, lines[1], "shown at runtime")
+	}
+}
+
+func TestNewWithConfigSamples(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "service.log")
+	logger, _, err := log.NewWithConfig(log.Config{
+		Level:       "info",
+		Encoding:    "console",
+		OutputPaths: []string{path},
+		Sampling:    log.SamplingConfig{Enabled: true, Initial: 2, Thereafter: 100},
+	})
+	assert.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		logger.Info("repeated")
+	}
+	assert.NoError(t, logger.Sync())
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(content), "repeated"))
+}
+
+func TestNewWithConfigErrors(t *testing.T) {
+	_, _, err := log.NewWithConfig(log.Config{Level: "verbose", Encoding: "json", OutputPaths: []string{"stderr"}})
+	assert.Error(t, err)
+	_, _, err = log.NewWithConfig(log.Config{Level: "info", Encoding: "xml", OutputPaths: []string{"stderr"}})
+	assert.Error(t, err)
+	_, _, err = log.NewWithConfig(log.Config{Level: "info", Encoding: "json"})
+	assert.Error(t, err)
+}
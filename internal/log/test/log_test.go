@@ -0,0 +1,112 @@
+package logtest
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/stretchr/testify/assert"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newRouter(seen *context.Context) *chi.Mux {
+	router := chi.NewRouter()
+	router.Use(log.Middleware())
+	router.Get("/ok", func(w http.ResponseWriter, r *http.Request) {
+		*seen = r.Context()
+	})
+	router.Get("/missing", func(w http.ResponseWriter, r *http.Request) {
+		render.Render(w, r, errorstype.ErrResponseNotFound())
+	})
+	return router
+}
+
+func TestMiddlewareGeneratesTheIDs(t *testing.T) {
+	var ctx context.Context
+	rs := httptest.NewRecorder()
+	newRouter(&ctx).ServeHTTP(rs, httptest.NewRequest(http.MethodGet, "/ok", nil))
+
+	requestID := rs.Header().Get(log.HeaderRequestID)
+	assert.NotEmpty(t, requestID)
+	assert.Equal(t, requestID, rs.Header().Get(log.HeaderCorrelationID), "the correlation id defaults to the request id")
+	assert.Equal(t, requestID, log.RequestID(ctx))
+	assert.Equal(t, requestID, log.CorrelationID(ctx))
+	assert.Equal(t, requestID, middleware.GetReqID(ctx))
+}
+
+func TestMiddlewareKeepsTheIDsOfTheCaller(t *testing.T) {
+	var ctx context.Context
+	rq := httptest.NewRequest(http.MethodGet, "/ok", nil)
+	rq.Header.Set(log.HeaderRequestID, "req-1")
+	rq.Header.Set(log.HeaderCorrelationID, "checkout-42")
+	rs := httptest.NewRecorder()
+	newRouter(&ctx).ServeHTTP(rs, rq)
+
+	assert.Equal(t, "req-1", rs.Header().Get(log.HeaderRequestID))
+	assert.Equal(t, "checkout-42", rs.Header().Get(log.HeaderCorrelationID))
+	assert.Equal(t, "checkout-42", log.CorrelationID(ctx))
+}
+
+func TestMiddlewareReplacesInvalidIDs(t *testing.T) {
+	for _, id := range []string{"forged\nentry", strings.Repeat("a", 129), "a b"} {
+		var ctx context.Context
+		rq := httptest.NewRequest(http.MethodGet, "/ok", nil)
+		rq.Header.Set(log.HeaderRequestID, id)
+		rs := httptest.NewRecorder()
+		newRouter(&ctx).ServeHTTP(rs, rq)
+
+		assert.NotEqual(t, id, rs.Header().Get(log.HeaderRequestID))
+		assert.NotEmpty(t, rs.Header().Get(log.HeaderRequestID))
+	}
+}
+
+func TestErrorBodiesHaveTheIDs(t *testing.T) {
+	var ctx context.Context
+	rq := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	rq.Header.Set(log.HeaderRequestID, "req-1")
+	rq.Header.Set(log.HeaderCorrelationID, "checkout-42")
+	rs := httptest.NewRecorder()
+	newRouter(&ctx).ServeHTTP(rs, rq)
+
+	assert.Equal(t, http.StatusNotFound, rs.Code)
+	assert.JSONEq(t, `{"status":"Resource not found.","requestId":"req-1","correlationId":"checkout-42"}`, rs.Body.String())
+}
+
+func TestWithContext(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger := log.Logger{SugaredLogger: zap.New(core).Sugar()}
+
+	ctx := log.WithCorrelationID(log.WithRequestID(context.Background(), "req-1"), "checkout-42")
+	logger.WithContext(ctx).Info("with ids")
+	logger.WithContext(context.Background()).Info("without ids")
+
+	entries := logs.All()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, map[string]interface{}{"requestId": "req-1", "correlationId": "checkout-42"}, entries[0].ContextMap())
+		assert.Empty(t, entries[1].ContextMap())
+	}
+}
+
+func TestWithContextLinksTheTrace(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger := log.Logger{SugaredLogger: zap.New(core).Sugar()}
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled,
+	}))
+
+	logger.WithContext(ctx).Info("traced")
+	if assert.Equal(t, 1, logs.Len()) {
+		assert.Equal(t, map[string]interface{}{"traceId": "4bf92f3577b34da6a3ce929d0e0e4736", "spanId": "00f067aa0ba902b7"},
+			logs.All()[0].ContextMap())
+	}
+}
@@ -0,0 +1,95 @@
+// Package metrics exposes the Prometheus metrics of the service: the HTTP requests, the repository operations,
+// the risk register and the Go runtime.
+package metrics
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Namespace prefixes the names of the metrics of the service
+const Namespace = "riskyplumbers"
+
+// unmatchedRoute labels the requests matching no route, so that unknown paths do not create new series
+const unmatchedRoute = "unmatched"
+
+// otherMethod labels the requests with a non-standard method, so that made-up methods do not create new series
+const otherMethod = "OTHER"
+
+// methods are the standard HTTP methods, used as they are in the method label
+var methods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// NewRegistry returns a registry with the Go runtime and process collectors.
+func NewRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return registry
+}
+
+// Handler serves the metrics of the registry in the Prometheus exposition format.
+func Handler(registry *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// Middleware counts the requests and observes their latency by method, route pattern and status. The route is
+// the pattern of chi, e.g. /api/v1/risks/{id}, so it must run on the root router. The non-standard methods are
+// labelled OTHER. The streams of the change feed are observed once they are closed.
+func Middleware(registerer prometheus.Registerer) func(http.Handler) http.Handler {
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests served, by method, route and status.",
+	}, []string{"method", "route", "status"})
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the HTTP requests, by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+	registerer.MustRegister(requests, duration)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				route := unmatchedRoute
+				if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+					route = rctx.RoutePattern()
+				}
+				method := r.Method
+				if !methods[method] {
+					method = otherMethod
+				}
+				requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+				duration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+			}()
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"time"
+)
+
+// collectTimeout bounds the time a scrape waits for the store
+const collectTimeout = 5 * time.Second
+
+type riskCollector struct {
+	store  risk.Store
+	risks  *prometheus.Desc
+	logger log.Logger
+}
+
+// NewRiskCollector returns a collector reading the number of risks of every tenant by state from the store on
+// each scrape. The risks have neither a severity nor a due date yet, so they are only counted by state.
+func NewRiskCollector(store risk.Store, logger log.Logger) prometheus.Collector {
+	return &riskCollector{
+		store: store,
+		risks: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", "risks"),
+			"Number of risks in the register, by tenant and state.", []string{"tenant", "state"}, nil),
+		logger: logger,
+	}
+}
+
+func (c *riskCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.risks
+}
+
+func (c *riskCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+	counts, err := c.store.CountByState(ctx)
+	if err != nil {
+		c.logger.Errorf("failed to count the risks: %v", err)
+		ch <- prometheus.NewInvalidMetric(c.risks, err)
+		return
+	}
+	for tenantID, states := range counts {
+		for state, count := range states {
+			ch <- prometheus.MustNewConstMetric(c.risks, prometheus.GaugeValue, float64(count), tenantID, state)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vikasgithub/risky-plumbers/internal/entity"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"time"
+)
+
+// Outcomes of the repository operations
+const (
+	OutcomeOK       = "ok"
+	OutcomeNotFound = "not_found"
+	OutcomeError    = "error"
+)
+
+// operations observes the duration of the operations of the store
+type operations struct {
+	duration *prometheus.HistogramVec
+}
+
+// observe is deferred by the operations, err points to their named result
+func (o operations) observe(operation string, start time.Time, err *error) {
+	outcome := OutcomeOK
+	switch {
+	case errors.Is(*err, errorstype.ErrRecordNotFound):
+		outcome = OutcomeNotFound
+	case *err != nil:
+		outcome = OutcomeError
+	}
+	o.duration.WithLabelValues(operation, outcome).Observe(time.Since(start).Seconds())
+}
+
+type store struct {
+	risk.Store
+	operations operations
+}
+
+// InstrumentStore returns a store observing the duration of the operations of store, by operation and outcome.
+// A transaction is observed as a whole.
+func InstrumentStore(s risk.Store, registerer prometheus.Registerer) risk.Store {
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "repository",
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of the operations of the risk repository, by operation and outcome.",
+		Buckets:   []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1, 5},
+	}, []string{"operation", "outcome"})
+	registerer.MustRegister(duration)
+	return &store{Store: s, operations: operations{duration}}
+}
+
+func (s *store) ForTenant(tenantID string) risk.Repository {
+	return &repository{Repository: s.Store.ForTenant(tenantID), operations: s.operations}
+}
+
+func (s *store) CountByState(ctx context.Context) (counts map[string]map[string]int, err error) {
+	defer s.operations.observe("count_by_state", time.Now(), &err)
+	return s.Store.CountByState(ctx)
+}
+
+func (s *store) PendingEvents(ctx context.Context, offset, limit int) (events []risk.Event, err error) {
+	defer s.operations.observe("pending_events", time.Now(), &err)
+	return s.Store.PendingEvents(ctx, offset, limit)
+}
+
+func (s *store) MarkPublished(ctx context.Context, ids []string) (err error) {
+	defer s.operations.observe("mark_published", time.Now(), &err)
+	return s.Store.MarkPublished(ctx, ids)
+}
+
+type repository struct {
+	risk.Repository
+	operations operations
+}
+
+func (r *repository) Get(ctx context.Context, id string) (found *entity.Risk, err error) {
+	defer r.operations.observe("get", time.Now(), &err)
+	return r.Repository.Get(ctx, id)
+}
+
+func (r *repository) GetMany(ctx context.Context, ids []string) (found map[string]*entity.Risk, err error) {
+	defer r.operations.observe("get_many", time.Now(), &err)
+	return r.Repository.GetMany(ctx, ids)
+}
+
+func (r *repository) Query(ctx context.Context, offset, limit int) (found []*entity.Risk, err error) {
+	defer r.operations.observe("query", time.Now(), &err)
+	return r.Repository.Query(ctx, offset, limit)
+}
+
+func (r *repository) Create(ctx context.Context, created *entity.Risk) (err error) {
+	defer r.operations.observe("create", time.Now(), &err)
+	return r.Repository.Create(ctx, created)
+}
+
+func (r *repository) Comments(ctx context.Context, riskIDs []string) (found map[string][]*entity.Comment, err error) {
+	defer r.operations.observe("comments", time.Now(), &err)
+	return r.Repository.Comments(ctx, riskIDs)
+}
+
+func (r *repository) Mitigations(ctx context.Context, riskIDs []string) (found map[string][]*entity.Mitigation, err error) {
+	defer r.operations.observe("mitigations", time.Now(), &err)
+	return r.Repository.Mitigations(ctx, riskIDs)
+}
+
+func (r *repository) History(ctx context.Context, riskIDs []string) (found map[string][]risk.Event, err error) {
+	defer r.operations.observe("history", time.Now(), &err)
+	return r.Repository.History(ctx, riskIDs)
+}
+
+func (r *repository) Transaction(ctx context.Context, fn func(tx risk.Tx) error) (err error) {
+	defer r.operations.observe("transaction", time.Now(), &err)
+	return r.Repository.Transaction(ctx, fn)
+}
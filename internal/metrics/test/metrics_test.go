@@ -0,0 +1,134 @@
+package metricstest
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vikasgithub/risky-plumbers/internal/entity"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/metrics"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	mocks "github.com/vikasgithub/risky-plumbers/internal/risk/mocks"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	router := chi.NewRouter()
+	router.Use(metrics.Middleware(registry))
+	api := chi.NewRouter()
+	api.Get("/risks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	router.Mount("/api/v1", api)
+
+	for _, path := range []string{"/api/v1/risks/1", "/api/v1/risks/2", "/unknown"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	for _, method := range []string{"BREW", "get"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/api/v1/risks/1", nil))
+	}
+
+	expected := `
+# HELP riskyplumbers_http_requests_total Number of HTTP requests served, by method, route and status.
+# TYPE riskyplumbers_http_requests_total counter
+riskyplumbers_http_requests_total{method="GET",route="/api/v1/risks/{id}",status="404"} 2
+riskyplumbers_http_requests_total{method="GET",route="unmatched",status="404"} 1
+riskyplumbers_http_requests_total{method="OTHER",route="unmatched",status="405"} 2
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "riskyplumbers_http_requests_total"))
+	assert.Equal(t, 3, testutil.CollectAndCount(registry, "riskyplumbers_http_request_duration_seconds"))
+}
+
+func TestInstrumentStore(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	store := metrics.InstrumentStore(risk.NewStore(log.New()), registry)
+	repo := store.ForTenant("acme")
+	ctx := context.Background()
+
+	assert.NoError(t, repo.Create(ctx, &entity.Risk{ID: "1", State: "open"}))
+	_, err := repo.Get(ctx, "1")
+	assert.NoError(t, err)
+	_, err = repo.Get(ctx, "2")
+	assert.Error(t, err)
+	_, err = store.PendingEvents(ctx, 0, 10)
+	assert.NoError(t, err)
+
+	count := func(operation, outcome string) uint64 {
+		families, err := registry.Gather()
+		assert.NoError(t, err)
+		for _, family := range families {
+			for _, metric := range family.GetMetric() {
+				labels := map[string]string{}
+				for _, label := range metric.GetLabel() {
+					labels[label.GetName()] = label.GetValue()
+				}
+				if labels["operation"] == operation && labels["outcome"] == outcome {
+					return metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+		return 0
+	}
+	assert.Equal(t, uint64(1), count("create", metrics.OutcomeOK))
+	assert.Equal(t, uint64(1), count("get", metrics.OutcomeOK))
+	assert.Equal(t, uint64(1), count("get", metrics.OutcomeNotFound))
+	assert.Equal(t, uint64(1), count("pending_events", metrics.OutcomeOK))
+
+	t.Run("Failed Operations", func(t *testing.T) {
+		failing := &mocks.Store{}
+		failing.On("MarkPublished", mock.Anything, mock.Anything).Return(errors.New("connection refused"))
+		registry := prometheus.NewRegistry()
+		assert.Error(t, metrics.InstrumentStore(failing, registry).MarkPublished(ctx, []string{"1"}))
+		families, err := registry.Gather()
+		assert.NoError(t, err)
+		if assert.Len(t, families, 1) {
+			assert.Contains(t, families[0].GetMetric()[0].String(), metrics.OutcomeError)
+		}
+	})
+}
+
+func TestRiskCollector(t *testing.T) {
+	store := risk.NewStore(log.New())
+	ctx := context.Background()
+	store.ForTenant("acme").Create(ctx, &entity.Risk{ID: "1", State: "open"})
+	store.ForTenant("acme").Create(ctx, &entity.Risk{ID: "2", State: "open"})
+	store.ForTenant("acme").Create(ctx, &entity.Risk{ID: "3", State: "closed"})
+	store.ForTenant("globex").Create(ctx, &entity.Risk{ID: "4", State: "accepted"})
+
+	expected := `
+# HELP riskyplumbers_risks Number of risks in the register, by tenant and state.
+# TYPE riskyplumbers_risks gauge
+riskyplumbers_risks{state="accepted",tenant="globex"} 1
+riskyplumbers_risks{state="closed",tenant="acme"} 1
+riskyplumbers_risks{state="open",tenant="acme"} 2
+`
+	assert.NoError(t, testutil.CollectAndCompare(metrics.NewRiskCollector(store, log.New()), strings.NewReader(expected)))
+
+	t.Run("Store Failure", func(t *testing.T) {
+		failing := &mocks.Store{}
+		failing.On("CountByState", mock.Anything).Return(nil, errors.New("connection refused"))
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(metrics.NewRiskCollector(failing, log.New()))
+		_, err := registry.Gather()
+		assert.Error(t, err)
+	})
+}
+
+func TestNewRegistry(t *testing.T) {
+	families, err := metrics.NewRegistry().Gather()
+	assert.NoError(t, err)
+	names := map[string]bool{}
+	for _, family := range families {
+		names[family.GetName()] = true
+	}
+	assert.True(t, names["go_goroutines"])
+	assert.True(t, names["process_cpu_seconds_total"])
+}
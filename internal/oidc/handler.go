@@ -0,0 +1,226 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// LoginCookie is the cookie binding the login in progress to the browser which started it
+const LoginCookie = "rp_login"
+
+// loginTTL is how long the user has to log in at the provider
+const loginTTL = 10 * time.Minute
+
+// pendingLogin is a login started by a browser and not completed yet
+type pendingLogin struct {
+	codeVerifier string
+	nonce        string
+	returnTo     string
+	expiresAt    time.Time
+}
+
+type resource struct {
+	provider *Provider
+	sessions SessionStore
+	config   Config
+	logger   log.Logger
+
+	mu     sync.Mutex
+	logins map[string]pendingLogin
+}
+
+// SessionResponse describes the user of the session.
+type SessionResponse struct {
+	Subject   string    `json:"subject"`
+	Roles     []string  `json:"roles"`
+	Tenant    string    `json:"tenant,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (sr *SessionResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// RegisterHandlers registers the login endpoints under /auth. GET /auth/login sends the browser to the provider,
+// which returns it to GET /auth/callback, POST /auth/logout ends the session and GET /auth/session describes it.
+func RegisterHandlers(r chi.Router, provider *Provider, sessions SessionStore, config Config, logger log.Logger) {
+	if config.MaxPendingLogins <= 0 {
+		config.MaxPendingLogins = DefaultMaxPendingLogins
+	}
+	res := &resource{provider: provider, sessions: sessions, config: config, logger: logger, logins: map[string]pendingLogin{}}
+
+	r.Get("/auth/login", res.login)
+	r.Get("/auth/callback", res.callback)
+	r.Post("/auth/logout", res.logout)
+	r.Get("/auth/session", res.session)
+}
+
+func (res *resource) login(w http.ResponseWriter, r *http.Request) {
+	state, nonce, codeVerifier := randomString(), randomString(), randomString()
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	authURL, err := res.provider.AuthCodeURL(r.Context(), state, nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		res.logger.WithContext(r.Context()).Errorf("failed to discover the oidc provider: %v", err)
+		render.Render(w, r, errorstype.ErrInternal(err))
+		return
+	}
+	added := res.addLogin(state, pendingLogin{
+		codeVerifier: codeVerifier,
+		nonce:        nonce,
+		returnTo:     localPath(r.URL.Query().Get("returnTo")),
+		expiresAt:    time.Now().Add(loginTTL),
+	})
+	if !added {
+		res.logger.WithContext(r.Context()).Warnf("refused a login, %d logins are in progress", res.config.MaxPendingLogins)
+		render.Render(w, r, errorstype.ErrTooManyRequests())
+		return
+	}
+	http.SetCookie(w, res.cookie(LoginCookie, state, "/auth", int(loginTTL.Seconds())))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (res *resource) callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	// the login cookie makes sure the browser completing the login is the one which started it
+	cookie, err := r.Cookie(LoginCookie)
+	if err != nil || cookie.Value != query.Get("state") {
+		render.Render(w, r, errorstype.ErrInvalidRequest(errors.New("the login state does not match, please log in again")))
+		return
+	}
+	http.SetCookie(w, res.cookie(LoginCookie, "", "/auth", -1))
+	login, ok := res.takeLogin(cookie.Value)
+	if !ok {
+		render.Render(w, r, errorstype.ErrInvalidRequest(errors.New("the login expired, please log in again")))
+		return
+	}
+	if providerErr := query.Get("error"); providerErr != "" {
+		render.Render(w, r, errorstype.ErrUnauthorized(fmt.Errorf("the provider refused the login: %s", providerErr)))
+		return
+	}
+	principal, err := res.provider.Exchange(r.Context(), query.Get("code"), login.codeVerifier, login.nonce)
+	if err != nil {
+		if !errors.Is(err, auth.ErrInvalidCredentials) {
+			res.logger.WithContext(r.Context()).Errorf("failed to complete the oidc login: %v", err)
+		}
+		render.Render(w, r, errorstype.ErrUnauthorized(err))
+		return
+	}
+	session := &Session{ID: randomString(), Principal: principal, ExpiresAt: time.Now().Add(res.config.SessionTTL)}
+	if err := res.sessions.Create(r.Context(), session); err != nil {
+		render.Render(w, r, errorstype.ErrInternal(err))
+		return
+	}
+	http.SetCookie(w, res.cookie(auth.SessionCookie, session.ID, "/", int(res.config.SessionTTL.Seconds())))
+	returnTo := login.returnTo
+	if returnTo == "" {
+		returnTo = res.config.PostLoginURL
+	}
+	http.Redirect(w, r, returnTo, http.StatusFound)
+}
+
+func (res *resource) logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(auth.SessionCookie); err == nil {
+		if err := res.sessions.Delete(r.Context(), cookie.Value); err != nil {
+			render.Render(w, r, errorstype.ErrInternal(err))
+			return
+		}
+	}
+	http.SetCookie(w, res.cookie(auth.SessionCookie, "", "/", -1))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (res *resource) session(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(auth.SessionCookie)
+	if err != nil {
+		render.Render(w, r, errorstype.ErrUnauthorized(err))
+		return
+	}
+	session, err := res.sessions.Get(r.Context(), cookie.Value)
+	if err != nil {
+		if errors.Is(err, errorstype.ErrRecordNotFound) {
+			render.Render(w, r, errorstype.ErrUnauthorized(err))
+			return
+		}
+		render.Render(w, r, errorstype.ErrInternal(err))
+		return
+	}
+	render.Render(w, r, &SessionResponse{
+		Subject:   session.Principal.Subject,
+		Roles:     session.Principal.Roles,
+		Tenant:    session.Principal.Tenant,
+		ExpiresAt: session.ExpiresAt,
+	})
+}
+
+// cookie returns an HttpOnly cookie, SameSite=Lax keeps the other sites from sending it with their forms
+func (res *resource) cookie(name, value, path string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   res.config.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// addLogin keeps the login until it is completed or expires, it returns false when MaxPendingLogins logins are
+// already in progress
+func (res *resource) addLogin(state string, login pendingLogin) bool {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	if len(res.logins) >= res.config.MaxPendingLogins {
+		now := time.Now()
+		for key, existing := range res.logins {
+			if now.After(existing.expiresAt) {
+				delete(res.logins, key)
+			}
+		}
+		if len(res.logins) >= res.config.MaxPendingLogins {
+			return false
+		}
+	}
+	res.logins[state] = login
+	return true
+}
+
+// takeLogin removes the login of the state, so that it is completed only once
+func (res *resource) takeLogin(state string) (pendingLogin, bool) {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	login, ok := res.logins[state]
+	delete(res.logins, state)
+	if !ok || time.Now().After(login.expiresAt) {
+		return pendingLogin{}, false
+	}
+	return login, true
+}
+
+// localPath returns the path if it stays on this site, so that the login can not redirect to other sites. The
+// browsers drop the tabs and newlines of the urls and read the backslashes as slashes, so "/\t/evil.example.com"
+// would leave the site.
+func localPath(path string) string {
+	if strings.ContainsFunc(path, func(r rune) bool { return unicode.IsControl(r) || unicode.IsSpace(r) || r == '\\' }) {
+		return ""
+	}
+	parsed, err := url.Parse(path)
+	if err != nil || parsed.Scheme != "" || parsed.Host != "" ||
+		!strings.HasPrefix(parsed.Path, "/") || strings.HasPrefix(parsed.Path, "//") {
+		return ""
+	}
+	return path
+}
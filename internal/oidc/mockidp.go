@@ -0,0 +1,243 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MockUser is a user of the mock identity provider.
+type MockUser struct {
+	Subject string
+	Name    string
+	Email   string
+	Groups  []string
+	Tenant  string
+}
+
+// DefaultMockUsers are the users of the mock identity provider when none are configured, one per group of the
+// local configuration.
+func DefaultMockUsers() []MockUser {
+	return []MockUser{
+		{Subject: "admin", Name: "Ada Admin", Email: "admin@example.com", Groups: []string{"risk-admins"}},
+		{Subject: "owner", Name: "Olu Owner", Email: "owner@example.com", Groups: []string{"risk-owners"}},
+		{Subject: "viewer", Name: "Vic Viewer", Email: "viewer@example.com", Groups: []string{"risk-viewers"}},
+	}
+}
+
+// mockCodeTTL is how long an authorization code of the mock identity provider can be redeemed
+const mockCodeTTL = time.Minute
+
+// mockCode is an authorization code issued by the mock identity provider
+type mockCode struct {
+	user          MockUser
+	clientID      string
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	expiresAt     time.Time
+}
+
+// MockProvider is an OpenID Connect provider for development and tests. It logs in any of its users without a
+// password, so it must never be enabled in production.
+type MockProvider struct {
+	issuer string
+	users  []MockUser
+	key    *rsa.PrivateKey
+	keyID  string
+
+	mu    sync.Mutex
+	codes map[string]mockCode
+}
+
+// NewMockProvider returns a mock provider for the issuer, signing its tokens with a key generated at startup.
+func NewMockProvider(issuer string, users []MockUser) (*MockProvider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		users = DefaultMockUsers()
+	}
+	return &MockProvider{
+		issuer: strings.TrimSuffix(issuer, "/"),
+		users:  users,
+		key:    key,
+		keyID:  randomString()[:8],
+		codes:  map[string]mockCode{},
+	}, nil
+}
+
+// Handler returns the endpoints of the provider, to be mounted at the path of the issuer.
+func (m *MockProvider) Handler() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/.well-known/openid-configuration", m.discovery)
+	r.Get("/jwks", m.jwks)
+	r.Get("/authorize", m.authorize)
+	r.Post("/token", m.token)
+	return r
+}
+
+func (m *MockProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, map[string]interface{}{
+		"issuer":                                m.issuer,
+		"authorization_endpoint":                m.issuer + "/authorize",
+		"token_endpoint":                        m.issuer + "/token",
+		"jwks_uri":                              m.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{string(jose.RS256)},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (m *MockProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &m.key.PublicKey, KeyID: m.keyID, Algorithm: string(jose.RS256), Use: "sig"},
+	}})
+}
+
+var mockLoginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html><head><title>Mock identity provider</title></head>
+<body>
+<h1>Log in as</h1>
+<ul>{{range .Users}}
+<li><a href="{{.URL}}">{{.Name}}</a> ({{.Subject}}, groups: {{.Groups}})</li>{{end}}
+</ul>
+</body></html>
+`))
+
+// authorize logs in the user named by the login_hint parameter, without it the user is picked from a page
+func (m *MockProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || !redirectURI.IsAbs() {
+		http.Error(w, "redirect_uri must be an absolute url", http.StatusBadRequest)
+		return
+	}
+	if query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		m.redirect(w, r, redirectURI, url.Values{"error": {"invalid_request"}, "state": {query.Get("state")}})
+		return
+	}
+	hint := query.Get("login_hint")
+	if hint == "" {
+		m.renderLoginPage(w, r)
+		return
+	}
+	user, ok := m.user(hint)
+	if !ok {
+		m.redirect(w, r, redirectURI, url.Values{"error": {"access_denied"}, "state": {query.Get("state")}})
+		return
+	}
+	code := randomString()
+	m.mu.Lock()
+	m.codes[code] = mockCode{
+		user:          user,
+		clientID:      query.Get("client_id"),
+		redirectURI:   redirectURI.String(),
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+		expiresAt:     time.Now().Add(mockCodeTTL),
+	}
+	m.mu.Unlock()
+	m.redirect(w, r, redirectURI, url.Values{"code": {code}, "state": {query.Get("state")}})
+}
+
+func (m *MockProvider) renderLoginPage(w http.ResponseWriter, r *http.Request) {
+	type link struct {
+		MockUser
+		URL string
+	}
+	links := []link{}
+	for _, user := range m.users {
+		query := r.URL.Query()
+		query.Set("login_hint", user.Subject)
+		links = append(links, link{user, m.issuer + "/authorize?" + query.Encode()})
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	mockLoginPage.Execute(w, map[string]interface{}{"Users": links})
+}
+
+func (m *MockProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, r, "invalid_request")
+		return
+	}
+	m.mu.Lock()
+	code, ok := m.codes[r.PostForm.Get("code")]
+	// a code is redeemed only once
+	delete(m.codes, r.PostForm.Get("code"))
+	m.mu.Unlock()
+	clientID := r.PostForm.Get("client_id")
+	if basicID, _, found := r.BasicAuth(); found {
+		clientID, _ = url.QueryUnescape(basicID)
+	}
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || time.Now().After(code.expiresAt) || code.clientID != clientID ||
+		code.redirectURI != r.PostForm.Get("redirect_uri") ||
+		code.codeChallenge != base64.RawURLEncoding.EncodeToString(verifier[:]) {
+		tokenError(w, r, "invalid_grant")
+		return
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: m.key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", m.keyID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	now := time.Now()
+	idToken, err := jwt.Signed(signer).Claims(jwt.Claims{
+		Issuer:   m.issuer,
+		Subject:  code.user.Subject,
+		Audience: jwt.Audience{code.clientID},
+		Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+		IssuedAt: jwt.NewNumericDate(now),
+	}).Claims(map[string]interface{}{
+		"nonce":  code.nonce,
+		"name":   code.user.Name,
+		"email":  code.user.Email,
+		"groups": code.user.Groups,
+		"tenant": code.user.Tenant,
+	}).Serialize()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	render.JSON(w, r, tokenResponse{IDToken: idToken, AccessToken: randomString(), TokenType: "Bearer", ExpiresIn: 3600})
+}
+
+func (m *MockProvider) user(subject string) (MockUser, bool) {
+	for _, user := range m.users {
+		if user.Subject == subject {
+			return user, true
+		}
+	}
+	return MockUser{}, false
+}
+
+func (m *MockProvider) redirect(w http.ResponseWriter, r *http.Request, redirectURI *url.URL, params url.Values) {
+	target := *redirectURI
+	query := target.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	target.RawQuery = query.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func tokenError(w http.ResponseWriter, r *http.Request, code string) {
+	render.Status(r, http.StatusBadRequest)
+	render.JSON(w, r, map[string]string{"error": code})
+}
@@ -0,0 +1,209 @@
+// Package oidc logs browsers in with the OpenID Connect authorization code flow with PKCE and keeps them logged in
+// with a session cookie.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config configures the login of the browsers.
+type Config struct {
+	// Issuer is the url of the provider, its metadata is read from /.well-known/openid-configuration
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback url registered at the provider, e.g. http://localhost:8080/auth/callback
+	RedirectURL string
+	Scopes      []string
+	// GroupsClaim is the claim of the ID token holding the groups of the user
+	GroupsClaim string
+	// TenantClaim is the claim of the ID token holding the tenant of the user
+	TenantClaim string
+	// GroupRoles maps the groups of the users to roles, the group names are compared case-insensitively
+	GroupRoles map[string][]string
+	// SessionTTL is how long a login lasts
+	SessionTTL time.Duration
+	// CookieSecure marks the cookies Secure, it may only be disabled to develop over plain http
+	CookieSecure bool
+	// PostLoginURL is where the browser goes after the login when it did not ask for a page
+	PostLoginURL string
+	// MaxPendingLogins limits the logins started and not completed yet, the next ones are refused with 429. It
+	// defaults to DefaultMaxPendingLogins
+	MaxPendingLogins int
+}
+
+// DefaultMaxPendingLogins is the default of Config.MaxPendingLogins
+const DefaultMaxPendingLogins = 10000
+
+// Roles returns the roles the groups are mapped to, without duplicates.
+func (c Config) Roles(groups []string) []string {
+	roles := []string{}
+	seen := map[string]bool{}
+	for _, group := range groups {
+		for name, mapped := range c.GroupRoles {
+			if !strings.EqualFold(name, group) {
+				continue
+			}
+			for _, role := range mapped {
+				if !seen[role] {
+					seen[role] = true
+					roles = append(roles, role)
+				}
+			}
+		}
+	}
+	return roles
+}
+
+// tokenResponse is the response of the token endpoint
+type tokenResponse struct {
+	IDToken     string `json:"id_token"`
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// metadata is the part of the provider metadata used by the login
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider is an OpenID Connect provider. Its metadata is discovered on first use, so that the service starts
+// while the provider is not reachable.
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mu       sync.Mutex
+	metadata *metadata
+	keys     auth.KeySource
+}
+
+// keysRefreshInterval is how often the signing keys of the provider are fetched again
+const keysRefreshInterval = time.Hour
+
+// NewProvider returns the provider of the configured issuer.
+func NewProvider(config Config, client *http.Client) *Provider {
+	return &Provider{config: config, client: client}
+}
+
+func (p *Provider) discover(ctx context.Context) (*metadata, auth.KeySource, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, p.keys, nil
+	}
+	wellKnown := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	rq, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	rs, err := p.client.Do(rq)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rs.Body.Close()
+	if rs.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("fetching %s returned status %d", wellKnown, rs.StatusCode)
+	}
+	var m metadata
+	if err := json.NewDecoder(io.LimitReader(rs.Body, 1<<20)).Decode(&m); err != nil {
+		return nil, nil, err
+	}
+	if m.Issuer != p.config.Issuer {
+		return nil, nil, fmt.Errorf("the provider metadata names the issuer %q instead of %q", m.Issuer, p.config.Issuer)
+	}
+	keys, err := auth.NewKeySource(auth.JWTConfig{JWKSURL: m.JWKSURI, RefreshInterval: keysRefreshInterval}, p.client)
+	if err != nil {
+		return nil, nil, err
+	}
+	p.metadata, p.keys = &m, keys
+	return p.metadata, p.keys, nil
+}
+
+// AuthCodeURL returns the url of the authorization endpoint the browser is sent to.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	m, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.config.RedirectURL)
+	query.Set("scope", strings.Join(p.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	separator := "?"
+	if strings.Contains(m.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return m.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems the authorization code and returns the principal of the verified ID token.
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*auth.Principal, error) {
+	m, keys, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("client_id", p.config.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	rq, err := http.NewRequestWithContext(ctx, http.MethodPost, m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	rq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rq.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		rq.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+	rs, err := p.client.Do(rq)
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Body.Close()
+	if rs.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: the token endpoint returned status %d", auth.ErrInvalidCredentials, rs.StatusCode)
+	}
+	var token tokenResponse
+	if err := json.NewDecoder(io.LimitReader(rs.Body, 1<<20)).Decode(&token); err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("the token response has no id token")
+	}
+	claims, custom, err := auth.VerifyJWT(ctx, keys, token.IDToken, m.Issuer, p.config.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if tokenNonce, _ := custom["nonce"].(string); tokenNonce != nonce {
+		return nil, fmt.Errorf("%w: the id token nonce does not match", auth.ErrInvalidCredentials)
+	}
+	tenant, _ := custom[p.config.TenantClaim].(string)
+	return &auth.Principal{
+		Subject: claims.Subject,
+		Method:  auth.MethodSession,
+		Roles:   p.config.Roles(auth.StringList(custom[p.config.GroupsClaim])),
+		Tenant:  tenant,
+	}, nil
+}
@@ -0,0 +1,103 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"sync"
+	"time"
+)
+
+// Session is the login of a browser.
+type Session struct {
+	ID        string
+	Principal *auth.Principal
+	ExpiresAt time.Time
+}
+
+// SessionStore keeps the sessions. Get returns errorstype.ErrRecordNotFound for unknown and expired sessions.
+type SessionStore interface {
+	Create(ctx context.Context, session *Session) error
+	Get(ctx context.Context, id string) (*Session, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type memorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// NewMemorySessionStore returns a SessionStore keeping the sessions in memory.
+func NewMemorySessionStore() SessionStore {
+	return &memorySessionStore{sessions: map[string]*Session{}}
+}
+
+func (s *memorySessionStore) Create(ctx context.Context, session *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// drop the expired sessions, so that abandoned logins do not pile up
+	now := time.Now()
+	for id, existing := range s.sessions {
+		if now.After(existing.ExpiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+	s.sessions[session.ID] = session
+	return nil
+}
+
+func (s *memorySessionStore) Get(ctx context.Context, id string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, errorstype.ErrRecordNotFound
+	}
+	if time.Now().After(session.ExpiresAt) {
+		delete(s.sessions, id)
+		return nil, errorstype.ErrRecordNotFound
+	}
+	return session, nil
+}
+
+func (s *memorySessionStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+type sessionAuthenticator struct {
+	store SessionStore
+}
+
+// NewSessionAuthenticator returns an Authenticator accepting the session cookies of the logged in browsers.
+func NewSessionAuthenticator(store SessionStore) auth.Authenticator {
+	return &sessionAuthenticator{store}
+}
+
+func (a *sessionAuthenticator) Authenticate(ctx context.Context, credentials auth.Credentials) (*auth.Principal, error) {
+	if credentials.SessionID == "" {
+		return nil, auth.ErrNoCredentials
+	}
+	session, err := a.store.Get(ctx, credentials.SessionID)
+	if err != nil {
+		if errors.Is(err, errorstype.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: unknown or expired session", auth.ErrInvalidCredentials)
+		}
+		return nil, err
+	}
+	return session.Principal, nil
+}
+
+// randomString returns a random url-safe string, 43 characters long, used for the ids, states, nonces and
+// code verifiers
+func randomString() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,259 @@
+package oidctest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/oidc"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newServer starts a server with the mock identity provider, the login endpoints and /whoami, which requires a
+// session
+func newServer(t *testing.T) (*httptest.Server, oidc.Config) {
+	return newServerWith(t, func(*oidc.Config) {})
+}
+
+// newServerWith starts the server of newServer with the config changed by configure
+func newServerWith(t *testing.T, configure func(*oidc.Config)) (*httptest.Server, oidc.Config) {
+	server := httptest.NewServer(nil)
+	t.Cleanup(server.Close)
+	config := oidc.Config{
+		Issuer:       server.URL + "/mock-idp",
+		ClientID:     "ui",
+		RedirectURL:  server.URL + "/auth/callback",
+		Scopes:       []string{"openid", "profile"},
+		GroupsClaim:  "groups",
+		TenantClaim:  "tenant",
+		GroupRoles:   map[string][]string{"risk-admins": {"admin"}, "Risk-Viewers": {"viewer"}},
+		SessionTTL:   time.Hour,
+		PostLoginURL: "/",
+	}
+	configure(&config)
+	mock, err := oidc.NewMockProvider(config.Issuer, []oidc.MockUser{
+		{Subject: "ada", Groups: []string{"risk-admins", "risk-viewers"}, Tenant: "acme"},
+	})
+	assert.NoError(t, err)
+	logger := log.New()
+	sessions := oidc.NewMemorySessionStore()
+
+	r := chi.NewRouter()
+	r.Mount("/mock-idp", mock.Handler())
+	oidc.RegisterHandlers(r, oidc.NewProvider(config, server.Client()), sessions, config, logger)
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+	r.With(auth.Middleware(oidc.NewSessionAuthenticator(sessions), logger)).Get("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := auth.PrincipalFrom(r.Context())
+		json.NewEncoder(w).Encode(principal)
+	})
+	server.Config.Handler = r
+	return server, config
+}
+
+func newBrowser(t *testing.T) *http.Client {
+	jar, err := cookiejar.New(nil)
+	assert.NoError(t, err)
+	return &http.Client{Jar: jar}
+}
+
+// login starts a login and returns the url of the authorization endpoint
+func login(t *testing.T, browser *http.Client, server *httptest.Server, returnTo string) *url.URL {
+	noRedirect := *browser
+	noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	rs, err := noRedirect.Get(server.URL + "/auth/login?returnTo=" + url.QueryEscape(returnTo))
+	assert.NoError(t, err)
+	rs.Body.Close()
+	assert.Equal(t, http.StatusFound, rs.StatusCode)
+	location, err := url.Parse(rs.Header.Get("Location"))
+	assert.NoError(t, err)
+	return location
+}
+
+func TestLogin(t *testing.T) {
+	server, _ := newServer(t)
+	browser := newBrowser(t)
+
+	rs, err := browser.Get(server.URL + "/whoami")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, rs.StatusCode)
+
+	authorize := login(t, browser, server, "/whoami")
+	query := authorize.Query()
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.NotEmpty(t, query.Get("code_challenge"))
+	assert.NotEmpty(t, query.Get("nonce"))
+	assert.Equal(t, "openid profile", query.Get("scope"))
+
+	// the login page of the mock provider lists its users
+	rs, err = browser.Get(authorize.String())
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rs.StatusCode)
+	rs.Body.Close()
+
+	query.Set("login_hint", "ada")
+	authorize.RawQuery = query.Encode()
+	rs, err = browser.Get(authorize.String())
+	assert.NoError(t, err)
+	defer rs.Body.Close()
+	assert.Equal(t, http.StatusOK, rs.StatusCode)
+	assert.Equal(t, "/whoami", rs.Request.URL.Path)
+	var principal auth.Principal
+	assert.NoError(t, json.NewDecoder(rs.Body).Decode(&principal))
+	assert.Equal(t, auth.Principal{Subject: "ada", Method: auth.MethodSession, Roles: []string{"admin", "viewer"}, Tenant: "acme"}, principal)
+
+	rs, err = browser.Get(server.URL + "/auth/session")
+	assert.NoError(t, err)
+	var session oidc.SessionResponse
+	assert.NoError(t, json.NewDecoder(rs.Body).Decode(&session))
+	rs.Body.Close()
+	assert.Equal(t, "ada", session.Subject)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), session.ExpiresAt, time.Minute)
+
+	rs, err = browser.Post(server.URL+"/auth/logout", "", nil)
+	assert.NoError(t, err)
+	rs.Body.Close()
+	assert.Equal(t, http.StatusNoContent, rs.StatusCode)
+	rs, err = browser.Get(server.URL + "/whoami")
+	assert.NoError(t, err)
+	rs.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, rs.StatusCode)
+}
+
+func TestLoginRedirectsToThisSiteOnly(t *testing.T) {
+	server, _ := newServer(t)
+	for _, returnTo := range []string{
+		"//evil.example.com/",
+		"/\\evil.example.com/",
+		"/\t/evil.example.com/",
+		"/\n/evil.example.com/",
+		"/%2F/evil.example.com/",
+		" //evil.example.com/",
+		"https://evil.example.com/",
+		"https:/evil.example.com/",
+		"whoami",
+	} {
+		browser := newBrowser(t)
+		authorize := login(t, browser, server, returnTo)
+		query := authorize.Query()
+		query.Set("login_hint", "ada")
+		authorize.RawQuery = query.Encode()
+
+		rs, err := browser.Get(authorize.String())
+		assert.NoError(t, err)
+		rs.Body.Close()
+		assert.Equal(t, server.URL+"/", rs.Request.URL.String(), returnTo)
+	}
+}
+
+func TestPendingLoginsAreLimited(t *testing.T) {
+	server, _ := newServerWith(t, func(config *oidc.Config) { config.MaxPendingLogins = 2 })
+	browser := newBrowser(t)
+	login(t, browser, server, "/")
+	login(t, browser, server, "/")
+
+	rs, err := http.Get(server.URL + "/auth/login")
+	assert.NoError(t, err)
+	rs.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, rs.StatusCode)
+}
+
+func TestCallbackRejectsAnotherBrowser(t *testing.T) {
+	server, _ := newServer(t)
+	authorize := login(t, newBrowser(t), server, "/")
+	query := authorize.Query()
+	query.Set("login_hint", "ada")
+	authorize.RawQuery = query.Encode()
+
+	// the code reaches a browser which did not start the login
+	rs, err := newBrowser(t).Get(authorize.String())
+	assert.NoError(t, err)
+	rs.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, rs.StatusCode)
+}
+
+func TestCallbackRejectsUnknownUser(t *testing.T) {
+	server, _ := newServer(t)
+	browser := newBrowser(t)
+	authorize := login(t, browser, server, "/")
+	query := authorize.Query()
+	query.Set("login_hint", "mallory")
+	authorize.RawQuery = query.Encode()
+
+	rs, err := browser.Get(authorize.String())
+	assert.NoError(t, err)
+	rs.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, rs.StatusCode)
+}
+
+func TestMockProviderChecksTheCodeVerifier(t *testing.T) {
+	_, config := newServer(t)
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	challenge := sha256.Sum256([]byte("the-verifier"))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {config.ClientID},
+		"redirect_uri":          {config.RedirectURL},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+		"login_hint":            {"ada"},
+	}
+	rs, err := noRedirect.Get(config.Issuer + "/authorize?" + query.Encode())
+	assert.NoError(t, err)
+	rs.Body.Close()
+	location, err := url.Parse(rs.Header.Get("Location"))
+	assert.NoError(t, err)
+	code := location.Query().Get("code")
+	assert.NotEmpty(t, code)
+
+	redeem := func(verifier string) int {
+		form := url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"client_id":     {config.ClientID},
+			"redirect_uri":  {config.RedirectURL},
+			"code_verifier": {verifier},
+		}
+		rs, err := http.Post(config.Issuer+"/token", "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+		assert.NoError(t, err)
+		rs.Body.Close()
+		return rs.StatusCode
+	}
+	assert.Equal(t, http.StatusBadRequest, redeem("another-verifier"))
+	// a code is redeemed only once, even after a failed attempt
+	assert.Equal(t, http.StatusBadRequest, redeem("the-verifier"))
+}
+
+func TestRoles(t *testing.T) {
+	config := oidc.Config{GroupRoles: map[string][]string{"admins": {"admin", "viewer"}, "viewers": {"viewer"}}}
+	assert.Equal(t, []string{"admin", "viewer"}, config.Roles([]string{"Admins", "viewers", "unknown"}))
+	assert.Equal(t, []string{}, config.Roles(nil))
+}
+
+func TestSessionAuthenticator(t *testing.T) {
+	ctx := context.Background()
+	store := oidc.NewMemorySessionStore()
+	principal := &auth.Principal{Subject: "ada", Method: auth.MethodSession}
+	assert.NoError(t, store.Create(ctx, &oidc.Session{ID: "valid", Principal: principal, ExpiresAt: time.Now().Add(time.Hour)}))
+	assert.NoError(t, store.Create(ctx, &oidc.Session{ID: "expired", Principal: principal, ExpiresAt: time.Now().Add(-time.Second)}))
+	authenticator := oidc.NewSessionAuthenticator(store)
+
+	found, err := authenticator.Authenticate(ctx, auth.Credentials{SessionID: "valid"})
+	assert.NoError(t, err)
+	assert.Equal(t, principal, found)
+	_, err = authenticator.Authenticate(ctx, auth.Credentials{SessionID: "expired"})
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+	_, err = authenticator.Authenticate(ctx, auth.Credentials{SessionID: "unknown"})
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+	_, err = authenticator.Authenticate(ctx, auth.Credentials{APIKey: "key"})
+	assert.ErrorIs(t, err, auth.ErrNoCredentials)
+}
@@ -0,0 +1,32 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// RedocScript is the pinned Redoc bundle loaded by the documentation page, the only script the page may run
+const RedocScript = "https://cdn.jsdelivr.net/npm/redoc@2.1.5/bundles/redoc.standalone.js"
+
+// docsPolicy keeps the documentation page from running any script but RedocScript. Redoc renders with inline styles
+// and a worker created from a blob.
+const docsPolicy = "default-src 'self'; script-src " + RedocScript + "; style-src 'self' 'unsafe-inline'; " +
+	"img-src 'self' data:; worker-src blob:; object-src 'none'; base-uri 'none'"
+
+// RegisterHandlers serves the document at /openapi.json and its Redoc documentation page at /docs.
+func RegisterHandlers(r chi.Router, doc *Document) {
+	r.Get("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(doc)
+	})
+	r.Get("/docs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", docsPolicy)
+		w.Write(docsPage)
+	})
+}
@@ -0,0 +1,184 @@
+// Package ratelimit throttles the api callers with token buckets, keyed by principal or IP address.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-chi/render"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"math"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests per Period on average, in bursts of up to Burst requests.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	// Burst is the size of the bucket, it defaults to Requests
+	Burst int
+}
+
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+func (l Limit) burst() int {
+	if l.Burst <= 0 {
+		return l.Requests
+	}
+	return l.Burst
+}
+
+// Valid reports whether the limit allows at least one request.
+func (l Limit) Valid() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// Result is the state of a bucket after taking a token.
+type Result struct {
+	Allowed bool
+	// Limit is the size of the bucket
+	Limit int
+	// Remaining is the number of requests allowed right now
+	Remaining int
+	// RetryAfter is the delay until the next request is allowed, zero when the request was allowed
+	RetryAfter time.Duration
+	// Reset is the delay until the bucket is full again
+	Reset time.Duration
+}
+
+// newResult returns the result of a bucket holding tokens after the request
+func newResult(allowed bool, tokens float64, limit Limit) Result {
+	rate := limit.rate()
+	result := Result{
+		Allowed:   allowed,
+		Limit:     limit.burst(),
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.burst()) - tokens) / rate),
+	}
+	if !allowed {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	return result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Store keeps the token buckets.
+type Store interface {
+	// Take takes a token from the bucket of key, which is refilled according to limit
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Rule limits the requests matching Method and Path.
+type Rule struct {
+	// Name identifies the bucket of the rule
+	Name string
+	// Method matches every method when empty
+	Method string
+	// Path is a pattern of path.Match on the request path, e.g. /api/v1/risks/*
+	Path string
+	Limit
+}
+
+func (r Rule) matches(rq *http.Request) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, rq.Method) {
+		return false
+	}
+	matched, _ := path.Match(r.Path, rq.URL.Path)
+	return matched
+}
+
+// Config holds the limits of the routes.
+type Config struct {
+	// Rules are checked in order, the first rule matching a request applies
+	Rules []Rule
+	// Default applies to the requests matching no rule
+	Default Limit
+}
+
+// Middleware takes a token from the bucket of the caller for each request, and rejects the request with 429 when
+// the bucket is empty. The callers are identified by their principal, so it must run after the authentication.
+// The requests are allowed when the store fails, so that an outage of the store does not take the api down.
+func Middleware(store Store, config Config, logger log.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rule := Rule{Name: "default", Limit: config.Default}
+			for _, candidate := range config.Rules {
+				if candidate.matches(r) {
+					rule = candidate
+					break
+				}
+			}
+			if take(w, r, store, rule.Name+":"+ClientKey(r), rule.Limit, logger) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// AddressMiddleware takes a token from the bucket of the IP address of each request, whatever its route. It runs
+// before the authentication, so that the requests with invalid credentials are throttled too.
+func AddressMiddleware(store Store, limit Limit, logger log.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if take(w, r, store, "address:"+addressKey(r), limit, logger) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// take takes a token from the bucket of key and sets the rate limit headers. It renders 429 and returns false when
+// the bucket is empty.
+func take(w http.ResponseWriter, r *http.Request, store Store, key string, limit Limit, logger log.Logger) bool {
+	if !limit.Valid() {
+		return true
+	}
+	result, err := store.Take(r.Context(), key, limit)
+	if err != nil {
+		logger.WithContext(r.Context()).Errorf("failed to check the rate limit, the request is allowed: %v", err)
+		return true
+	}
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.burst(), ceilSeconds(limit.Period)))
+	if !result.Allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		render.Render(w, r, errorstype.ErrTooManyRequests())
+		return false
+	}
+	return true
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// ClientKey identifies the caller of the request: its principal, else its IP address. The credentials of an
+// unauthenticated request are not used, a caller could otherwise get a new bucket with each made up API key.
+func ClientKey(r *http.Request) string {
+	if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+		return "principal:" + principal.Tenant + "/" + principal.Method + "/" + principal.Subject
+	}
+	return addressKey(r)
+}
+
+// addressKey identifies the caller of the request by its IP address
+func addressKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
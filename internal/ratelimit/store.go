@@ -0,0 +1,117 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store drops the buckets which are full again
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+type memoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewMemoryStore returns a Store keeping the buckets in memory, each instance of the service limits on its own.
+func NewMemoryStore() Store {
+	return &memoryStore{buckets: map[string]*bucket{}, lastSweep: time.Now()}
+}
+
+func (s *memoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.lastSweep) > sweepInterval {
+		s.sweep(now)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.burst()), updated: now}
+		s.buckets[key] = b
+	}
+	b.limit = limit
+	b.tokens = math.Min(float64(limit.burst()), b.tokens+now.Sub(b.updated).Seconds()*limit.rate())
+	b.updated = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return newResult(allowed, b.tokens, limit), nil
+}
+
+// sweep drops the buckets which are full again, they are created again on the next request
+func (s *memoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*b.limit.rate() >= float64(b.limit.burst()) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// takeScript refills the bucket of KEYS[1] at ARGV[1] tokens per second up to ARGV[2] tokens and takes a token.
+// It uses the clock of redis, so that the instances of the service share the same clock. It returns whether the
+// token was taken and the tokens left.
+var takeScript = redis.NewScript(`
+local time = redis.call('TIME')
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(bucket[1])
+local updated = tonumber(bucket[2])
+if tokens == nil or updated == nil then
+  tokens = burst
+  updated = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - updated) * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+type redisStore struct {
+	client    redis.Scripter
+	keyPrefix string
+}
+
+// NewRedisStore returns a Store keeping the buckets in redis, so that the instances of the service share them.
+// The buckets expire once they are full again.
+func NewRedisStore(client redis.Scripter, keyPrefix string) Store {
+	return &redisStore{client: client, keyPrefix: keyPrefix}
+}
+
+func (s *redisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	reply, err := takeScript.Run(ctx, s.client, []string{s.keyPrefix + key}, limit.rate(), limit.burst()).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(reply) != 2 {
+		return Result{}, fmt.Errorf("unexpected reply of the rate limit script: %v", reply)
+	}
+	allowed, _ := reply[0].(int64)
+	remaining, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(remaining, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected reply of the rate limit script: %v", reply)
+	}
+	return newResult(allowed == 1, tokens, limit), nil
+}
@@ -0,0 +1,173 @@
+package ratelimittest
+
+import (
+	"context"
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/ratelimit"
+	mocks "github.com/vikasgithub/risky-plumbers/internal/ratelimit/mocks"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var config = ratelimit.Config{
+	Rules: []ratelimit.Rule{
+		{Name: "list", Method: http.MethodGet, Path: "/api/v1/risks", Limit: ratelimit.Limit{Requests: 2, Period: time.Hour}},
+	},
+	Default: ratelimit.Limit{Requests: 100, Period: time.Hour, Burst: 10},
+}
+
+func newRouter(store ratelimit.Store) *chi.Mux {
+	router := chi.NewRouter()
+	router.Use(ratelimit.Middleware(store, config, log.New()))
+	router.Get("/api/v1/risks", func(w http.ResponseWriter, r *http.Request) {})
+	router.Get("/api/v1/risks/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	return router
+}
+
+func get(router http.Handler, path, remoteAddr string) *httptest.ResponseRecorder {
+	rq := httptest.NewRequest(http.MethodGet, path, nil)
+	rq.RemoteAddr = remoteAddr
+	rs := httptest.NewRecorder()
+	router.ServeHTTP(rs, rq)
+	return rs
+}
+
+func TestMiddleware(t *testing.T) {
+	router := newRouter(ratelimit.NewMemoryStore())
+
+	rs := get(router, "/api/v1/risks", "10.0.0.1:1234")
+	assert.Equal(t, http.StatusOK, rs.Code)
+	assert.Equal(t, "2", rs.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rs.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2;w=3600", rs.Header().Get("RateLimit-Policy"))
+	assert.Empty(t, rs.Header().Get("Retry-After"))
+
+	rs = get(router, "/api/v1/risks", "10.0.0.1:1235")
+	assert.Equal(t, http.StatusOK, rs.Code)
+	assert.Equal(t, "0", rs.Header().Get("RateLimit-Remaining"))
+
+	rs = get(router, "/api/v1/risks", "10.0.0.1:1236")
+	assert.Equal(t, http.StatusTooManyRequests, rs.Code)
+	assert.JSONEq(t, `{"status":"Too many requests."}`, rs.Body.String())
+	assert.Equal(t, "0", rs.Header().Get("RateLimit-Remaining"))
+	retryAfter, err := strconv.Atoi(rs.Header().Get("Retry-After"))
+	assert.NoError(t, err)
+	assert.InDelta(t, 1800, retryAfter, 1, "a token is added every 30 minutes")
+
+	t.Run("Other Routes Have Their Own Bucket", func(t *testing.T) {
+		rs := get(router, "/api/v1/risks/1", "10.0.0.1:1234")
+		assert.Equal(t, http.StatusOK, rs.Code)
+		assert.Equal(t, "10", rs.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "9", rs.Header().Get("RateLimit-Remaining"))
+	})
+
+	t.Run("Other Clients Have Their Own Bucket", func(t *testing.T) {
+		rs := get(router, "/api/v1/risks", "10.0.0.2:1234")
+		assert.Equal(t, http.StatusOK, rs.Code)
+	})
+}
+
+func TestBucketRefills(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	limit := ratelimit.Limit{Requests: 1, Period: 50 * time.Millisecond}
+
+	result, err := store.Take(context.Background(), "k", limit)
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	result, err = store.Take(context.Background(), "k", limit)
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.InDelta(t, 50*time.Millisecond, result.RetryAfter, float64(10*time.Millisecond))
+
+	time.Sleep(60 * time.Millisecond)
+	result, err = store.Take(context.Background(), "k", limit)
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestStoreFailureAllowsTheRequest(t *testing.T) {
+	store := mocks.NewStore(t)
+	store.On("Take", mock.Anything, "list:ip:10.0.0.1", config.Rules[0].Limit).Return(ratelimit.Result{}, errors.New("connection refused"))
+
+	rs := get(newRouter(store), "/api/v1/risks", "10.0.0.1:1234")
+	assert.Equal(t, http.StatusOK, rs.Code)
+	assert.Empty(t, rs.Header().Get("RateLimit-Remaining"))
+}
+
+func TestClientKey(t *testing.T) {
+	rq := httptest.NewRequest(http.MethodGet, "/", nil)
+	rq.RemoteAddr = "192.0.2.1:4321"
+	assert.Equal(t, "ip:192.0.2.1", ratelimit.ClientKey(rq))
+
+	// the credentials are not trusted until they are authenticated
+	rq.Header.Set(auth.HeaderAPIKey, "secret")
+	assert.Equal(t, "ip:192.0.2.1", ratelimit.ClientKey(rq))
+
+	principal := &auth.Principal{Subject: "ci", Method: auth.MethodAPIKey, Tenant: "acme"}
+	rq = rq.WithContext(auth.WithPrincipal(rq.Context(), principal))
+	assert.Equal(t, "principal:acme/"+auth.MethodAPIKey+"/ci", ratelimit.ClientKey(rq))
+}
+
+func TestAddressMiddleware(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(ratelimit.AddressMiddleware(ratelimit.NewMemoryStore(), ratelimit.Limit{Requests: 2, Period: time.Hour}, log.New()))
+	// every request fails the authentication
+	router.Get("/api/v1/risks", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusUnauthorized) })
+
+	for i, key := range []string{"guess-1", "guess-2", "guess-3"} {
+		rq := httptest.NewRequest(http.MethodGet, "/api/v1/risks", nil)
+		rq.RemoteAddr = "10.0.0.1:1234"
+		rq.Header.Set(auth.HeaderAPIKey, key)
+		rs := httptest.NewRecorder()
+		router.ServeHTTP(rs, rq)
+		if i < 2 {
+			assert.Equal(t, http.StatusUnauthorized, rs.Code)
+		} else {
+			assert.Equal(t, http.StatusTooManyRequests, rs.Code, "each address has one bucket whatever its credentials")
+		}
+	}
+	assert.Equal(t, http.StatusUnauthorized, get(router, "/api/v1/risks", "10.0.0.2:1234").Code)
+}
+
+func TestRedisStore(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	store := ratelimit.NewRedisStore(client, "test:")
+	limit := ratelimit.Limit{Requests: 2, Period: time.Hour}
+	ctx := context.Background()
+
+	for _, remaining := range []int{1, 0} {
+		result, err := store.Take(ctx, "k", limit)
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, remaining, result.Remaining)
+		assert.Equal(t, 2, result.Limit)
+	}
+	result, err := store.Take(ctx, "k", limit)
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.InDelta(t, 30*time.Minute, result.RetryAfter, float64(time.Second))
+
+	// the bucket expires once it is full again
+	assert.True(t, server.Exists("test:k"))
+	assert.InDelta(t, time.Hour, server.TTL("test:k"), float64(2*time.Second))
+
+	other, err := store.Take(ctx, "other", limit)
+	assert.NoError(t, err)
+	assert.True(t, other.Allowed)
+
+	server.Close()
+	_, err = store.Take(ctx, "k", limit)
+	assert.Error(t, err)
+}
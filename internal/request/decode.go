@@ -0,0 +1,86 @@
+// Package request provides helpers for reading and validating request payloads.
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/render"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// BindJSON strictly decodes the JSON body of r into v and then calls v.Bind.
+// The request is rejected if its content type is not JSON, or if the body contains unknown fields,
+// duplicate keys or trailing data. The body size is expected to be limited by the caller, e.g. using
+// http.MaxBytesReader, in which case the returned error wraps *http.MaxBytesError.
+func BindJSON(r *http.Request, v render.Binder) error {
+	if !isJSON(r.Header.Get("Content-Type")) {
+		return errorstype.ErrUnsupportedMediaType
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if err := checkDuplicateKeys(json.NewDecoder(bytes.NewReader(body))); err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("request body must contain a single JSON object")
+	}
+	return v.Bind(r)
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
+// checkDuplicateKeys walks the next JSON value of the decoder and returns an error
+// if any object contains the same key more than once.
+func checkDuplicateKeys(decoder *json.Decoder) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return nil
+	}
+	switch delim {
+	case '{':
+		keys := map[string]bool{}
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			key := token.(string)
+			if keys[key] {
+				return fmt.Errorf("duplicate key %q", key)
+			}
+			keys[key] = true
+			if err := checkDuplicateKeys(decoder); err != nil {
+				return err
+			}
+		}
+	case '[':
+		for decoder.More() {
+			if err := checkDuplicateKeys(decoder); err != nil {
+				return err
+			}
+		}
+	}
+	// consume the closing delimiter
+	_, err = decoder.Token()
+	return err
+}
@@ -7,9 +7,14 @@ import (
 	"github.com/go-chi/render"
 	"github.com/vikasgithub/risky-plumbers/internal/entity"
 	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/i18n"
 	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/request"
+	"github.com/vikasgithub/risky-plumbers/internal/tenant"
+	"github.com/vikasgithub/risky-plumbers/internal/tracing"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type resource struct {
@@ -37,19 +42,40 @@ func NewRiskListResponse(articles []*entity.Risk) []render.Renderer {
 	return list
 }
 
-func RegisterHandlers(r *chi.Mux, service Service) {
-	res := resource{service, log.New()}
+// ExportResponse holds all the risks of a tenant.
+type ExportResponse struct {
+	TenantID   string         `json:"tenantId"`
+	ExportedAt time.Time      `json:"exportedAt"`
+	Risks      []*entity.Risk `json:"risks"`
+}
+
+func (er *ExportResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
 
+func RegisterHandlers(r chi.Router, service Service, logger log.Logger) {
+	res := resource{service, logger}
+
+	r.Get("/risks/export", res.export)
 	r.Get("/risks/{id}", res.get)
 	r.Get("/risks", res.getAll)
 	r.Post("/risks", res.post)
+	r.Put("/risks/{id}", res.put)
+	r.Post("/risks/{id}/transition", res.transition)
 }
 
 func (res resource) get(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Tracer().Start(r.Context(), "risk.resource/get")
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	risk, err := res.service.Get(r.Context(), chi.URLParam(r, "id"))
 	if err != nil {
+		if renderForbidden(w, r, err) {
+			return
+		}
 		if errors.Is(err, errorstype.ErrRecordNotFound) {
-			render.Render(w, r, errorstype.ErrResponseNotFound)
+			render.Render(w, r, errorstype.ErrResponseNotFound())
 		} else {
 			render.Render(w, r, errorstype.ErrInvalidRequest(err))
 		}
@@ -59,13 +85,17 @@ func (res resource) get(w http.ResponseWriter, r *http.Request) {
 	render.Render(w, r, NewRiskResponse(risk))
 }
 
-// TODO need to implement paging and enhance the response with offset and limit
+// TODO enhance the response with offset and limit
 func (res resource) getAll(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Tracer().Start(r.Context(), "risk.resource/getAll")
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	offset := 0
 	limit := 100
 	if r.URL.Query().Get("offset") != "" {
 		offsetParam, err := strconv.Atoi(r.URL.Query().Get("offset"))
-		if err != nil {
+		if err != nil || offsetParam < 0 {
 			render.Render(w, r,
 				errorstype.ErrInvalidRequest(
 					fmt.Errorf("invalid offset: %s", r.URL.Query().Get("offset"))))
@@ -75,7 +105,7 @@ func (res resource) getAll(w http.ResponseWriter, r *http.Request) {
 	}
 	if r.URL.Query().Get("limit") != "" {
 		limitParam, err := strconv.Atoi(r.URL.Query().Get("limit"))
-		if err != nil {
+		if err != nil || limitParam < 0 {
 			render.Render(w, r,
 				errorstype.ErrInvalidRequest(
 					fmt.Errorf("invalid limit: %s", r.URL.Query().Get("limit"))))
@@ -84,25 +114,53 @@ func (res resource) getAll(w http.ResponseWriter, r *http.Request) {
 		limit = limitParam
 	}
 
-	res.logger.Infof("offset: %d, limit: %d", offset, limit)
+	res.logger.WithContext(r.Context()).Infof("offset: %d, limit: %d", offset, limit)
 	risks, err := res.service.GetAll(r.Context(), offset, limit)
 	if err != nil {
+		if renderForbidden(w, r, err) {
+			return
+		}
 		render.Render(w, r, errorstype.ErrInvalidRequest(err))
 		return
 	}
 	render.RenderList(w, r, NewRiskListResponse(risks))
 }
 
+func (res resource) export(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Tracer().Start(r.Context(), "risk.resource/export")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	risks, err := res.service.Export(r.Context())
+	if err != nil {
+		if renderForbidden(w, r, err) {
+			return
+		}
+		render.Render(w, r, errorstype.ErrInternal(err))
+		return
+	}
+	tenantID := tenant.FromContext(r.Context())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="risks-%s.json"`, tenantID))
+	render.Render(w, r, &ExportResponse{TenantID: tenantID, ExportedAt: time.Now().UTC(), Risks: risks})
+}
+
 func (res resource) post(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Tracer().Start(r.Context(), "risk.resource/post")
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	createRequest := &CreateRiskRequest{}
-	if err := render.Bind(r, createRequest); err != nil {
-		render.Render(w, r, errorstype.ErrInvalidRequest(err))
+	if err := request.BindJSON(r, createRequest); err != nil {
+		render.Render(w, r, errorstype.ErrBind(err))
 		return
 	}
 
 	risk, err := res.service.Create(r.Context(), createRequest)
 	if err != nil {
-		render.Render(w, r, errorstype.ErrInvalidRequest(err))
+		if renderForbidden(w, r, err) {
+			return
+		}
+		render.Render(w, r, errorstype.ErrValidation(err, i18n.RequestLanguage(r)))
 		return
 	}
 	render.Status(r, http.StatusCreated)
@@ -111,3 +169,72 @@ func (res resource) post(w http.ResponseWriter, r *http.Request) {
 		render.Render(w, r, errorstype.ErrRender(err))
 	}
 }
+
+func (res resource) put(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Tracer().Start(r.Context(), "risk.resource/put")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	updateRequest := &UpdateRiskRequest{}
+	if err := request.BindJSON(r, updateRequest); err != nil {
+		render.Render(w, r, errorstype.ErrBind(err))
+		return
+	}
+	res.update(w, r, updateRequest)
+}
+
+// transition moves the risk to the state of the request, accepting a risk requires the transition-to-accepted
+// permission
+func (res resource) transition(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Tracer().Start(r.Context(), "risk.resource/transition")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	transitionRequest := &TransitionRiskRequest{}
+	if err := request.BindJSON(r, transitionRequest); err != nil {
+		render.Render(w, r, errorstype.ErrBind(err))
+		return
+	}
+	res.update(w, r, &UpdateRiskRequest{State: &transitionRequest.State})
+}
+
+func (res resource) update(w http.ResponseWriter, r *http.Request, updateRequest *UpdateRiskRequest) {
+	risk, err := res.service.Update(r.Context(), chi.URLParam(r, "id"), updateRequest)
+	if err != nil {
+		if renderForbidden(w, r, err) {
+			return
+		}
+		if errors.Is(err, errorstype.ErrRecordNotFound) {
+			render.Render(w, r, errorstype.ErrResponseNotFound())
+		} else {
+			render.Render(w, r, errorstype.ErrValidation(err, i18n.RequestLanguage(r)))
+		}
+		return
+	}
+	render.Render(w, r, NewRiskResponse(risk))
+}
+
+// renderForbidden renders a 403 and returns true if the service denied the operation
+func renderForbidden(w http.ResponseWriter, r *http.Request, err error) bool {
+	var forbidden *errorstype.ForbiddenError
+	if !errors.As(err, &forbidden) {
+		return false
+	}
+	render.Render(w, r, errorstype.ErrForbidden(forbidden))
+	return true
+}
+
+// Authorized returns a middleware rejecting the requests whose principal may not perform action with a 403.
+func Authorized(service Service, action string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := service.Authorize(r.Context(), action); err != nil {
+				if !renderForbidden(w, r, err) {
+					render.Render(w, r, errorstype.ErrInternal(err))
+				}
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package risk
+
+import (
+	"context"
+	"github.com/vikasgithub/risky-plumbers/internal/entity"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	EventCreated = "risk.created"
+	EventUpdated = "risk.updated"
+)
+
+// Event describes a change of a risk. The ID stays the same when the event is published again, consumers use it
+// to ignore duplicates.
+type Event struct {
+	ID   string    `json:"id"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// TenantID is the tenant of the risk, it is set by the repository writing the event
+	TenantID string       `json:"tenantId"`
+	Risk     *entity.Risk `json:"risk"`
+	// CorrelationID is the one of the request writing the event, it is set by the repository
+	CorrelationID string `json:"correlationId,omitempty"`
+}
+
+// NewEvent returns an event with a new id describing a change of the given type of risk. The events reach the
+// streams, webhooks and message bus, so a confidential risk is redacted: its readers fetch it from the service.
+func NewEvent(eventType string, risk *entity.Risk) Event {
+	if risk.Confidential {
+		risk = redacted(risk)
+	}
+	return Event{ID: entity.GenerateID(), Type: eventType, Time: time.Now().UTC(), Risk: risk}
+}
+
+// Publisher is a sink the Relay publishes the events to.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Broker fans the published events out to the in-process subscribers. The latest events are kept in a
+// bounded replay buffer, so that subscribers can resume after a disconnect.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+	replay      []Event
+	replaySize  int
+	// closed ends the subscriptions, the streams return once the broker is closed
+	closed bool
+}
+
+// subscriberBuffer is the number of events buffered per subscriber. Slow subscribers miss the events
+// published while their buffer is full, instead of blocking the service.
+const subscriberBuffer = 64
+
+// NewBroker creates a broker keeping the latest replaySize events for replay.
+func NewBroker(replaySize int) *Broker {
+	return &Broker{subscribers: map[chan Event]struct{}{}, replaySize: replaySize}
+}
+
+// Publish sends the event to every subscriber.
+func (b *Broker) Publish(ctx context.Context, event Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.replaySize > 0 {
+		if len(b.replay) == b.replaySize {
+			b.replay = append(b.replay[:0], b.replay[1:]...)
+		}
+		b.replay = append(b.replay, event)
+	}
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+	return nil
+}
+
+// Subscribe returns a channel receiving the events published from now on, and a function which
+// must be called to unsubscribe.
+func (b *Broker) Subscribe() (<-chan Event, func()) {
+	_, ch, unsubscribe := b.SubscribeAfter("")
+	return ch, unsubscribe
+}
+
+// SubscribeAfter subscribes like Subscribe and also returns the buffered events published after the event with
+// the given id. All the buffered events are returned when the id is no longer, or was never, in the buffer.
+// No events are replayed when lastEventID is empty.
+func (b *Broker) SubscribeAfter(lastEventID string) ([]Event, <-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		close(ch)
+		return nil, ch, func() {}
+	}
+	var replay []Event
+	if lastEventID != "" {
+		start := 0
+		for i, event := range b.replay {
+			if event.ID == lastEventID {
+				start = i + 1
+				break
+			}
+		}
+		replay = append(replay, b.replay[start:]...)
+	}
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return replay, ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close closes the channels of the subscribers, so that the streams end and the server can shut down.
+// The subscriptions made afterwards receive a closed channel.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
@@ -7,6 +7,8 @@ import (
 
 	mock "github.com/stretchr/testify/mock"
 	entity "github.com/vikasgithub/risky-plumbers/internal/entity"
+
+	risk "github.com/vikasgithub/risky-plumbers/internal/risk"
 )
 
 // Repository is an autogenerated mock type for the Repository type
@@ -14,6 +16,36 @@ type Repository struct {
 	mock.Mock
 }
 
+// Comments provides a mock function with given fields: ctx, riskIDs
+func (_m *Repository) Comments(ctx context.Context, riskIDs []string) (map[string][]*entity.Comment, error) {
+	ret := _m.Called(ctx, riskIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Comments")
+	}
+
+	var r0 map[string][]*entity.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) (map[string][]*entity.Comment, error)); ok {
+		return rf(ctx, riskIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) map[string][]*entity.Comment); ok {
+		r0 = rf(ctx, riskIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string][]*entity.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, riskIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Create provides a mock function with given fields: ctx, _a1
 func (_m *Repository) Create(ctx context.Context, _a1 *entity.Risk) error {
 	ret := _m.Called(ctx, _a1)
@@ -62,6 +94,96 @@ func (_m *Repository) Get(ctx context.Context, id string) (*entity.Risk, error)
 	return r0, r1
 }
 
+// GetMany provides a mock function with given fields: ctx, ids
+func (_m *Repository) GetMany(ctx context.Context, ids []string) (map[string]*entity.Risk, error) {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMany")
+	}
+
+	var r0 map[string]*entity.Risk
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) (map[string]*entity.Risk, error)); ok {
+		return rf(ctx, ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) map[string]*entity.Risk); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]*entity.Risk)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// History provides a mock function with given fields: ctx, riskIDs
+func (_m *Repository) History(ctx context.Context, riskIDs []string) (map[string][]risk.Event, error) {
+	ret := _m.Called(ctx, riskIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for History")
+	}
+
+	var r0 map[string][]risk.Event
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) (map[string][]risk.Event, error)); ok {
+		return rf(ctx, riskIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) map[string][]risk.Event); ok {
+		r0 = rf(ctx, riskIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string][]risk.Event)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, riskIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Mitigations provides a mock function with given fields: ctx, riskIDs
+func (_m *Repository) Mitigations(ctx context.Context, riskIDs []string) (map[string][]*entity.Mitigation, error) {
+	ret := _m.Called(ctx, riskIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Mitigations")
+	}
+
+	var r0 map[string][]*entity.Mitigation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) (map[string][]*entity.Mitigation, error)); ok {
+		return rf(ctx, riskIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) map[string][]*entity.Mitigation); ok {
+		r0 = rf(ctx, riskIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string][]*entity.Mitigation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, riskIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Query provides a mock function with given fields: ctx, offset, limit
 func (_m *Repository) Query(ctx context.Context, offset int, limit int) ([]*entity.Risk, error) {
 	ret := _m.Called(ctx, offset, limit)
@@ -92,6 +214,24 @@ func (_m *Repository) Query(ctx context.Context, offset int, limit int) ([]*enti
 	return r0, r1
 }
 
+// Transaction provides a mock function with given fields: ctx, fn
+func (_m *Repository) Transaction(ctx context.Context, fn func(risk.Tx) error) error {
+	ret := _m.Called(ctx, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Transaction")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(risk.Tx) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewRepository creates a new instance of Repository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewRepository(t interface {
@@ -16,6 +16,114 @@ type Service struct {
 	mock.Mock
 }
 
+// AddComment provides a mock function with given fields: ctx, riskID, input
+func (_m *Service) AddComment(ctx context.Context, riskID string, input *risk.AddCommentRequest) (*entity.Comment, error) {
+	ret := _m.Called(ctx, riskID, input)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddComment")
+	}
+
+	var r0 *entity.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *risk.AddCommentRequest) (*entity.Comment, error)); ok {
+		return rf(ctx, riskID, input)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *risk.AddCommentRequest) *entity.Comment); ok {
+		r0 = rf(ctx, riskID, input)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, *risk.AddCommentRequest) error); ok {
+		r1 = rf(ctx, riskID, input)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AddMitigation provides a mock function with given fields: ctx, riskID, input
+func (_m *Service) AddMitigation(ctx context.Context, riskID string, input *risk.AddMitigationRequest) (*entity.Mitigation, error) {
+	ret := _m.Called(ctx, riskID, input)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddMitigation")
+	}
+
+	var r0 *entity.Mitigation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *risk.AddMitigationRequest) (*entity.Mitigation, error)); ok {
+		return rf(ctx, riskID, input)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *risk.AddMitigationRequest) *entity.Mitigation); ok {
+		r0 = rf(ctx, riskID, input)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.Mitigation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, *risk.AddMitigationRequest) error); ok {
+		r1 = rf(ctx, riskID, input)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Authorize provides a mock function with given fields: ctx, action
+func (_m *Service) Authorize(ctx context.Context, action string) error {
+	ret := _m.Called(ctx, action)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Authorize")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, action)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Comments provides a mock function with given fields: ctx, riskIDs
+func (_m *Service) Comments(ctx context.Context, riskIDs []string) (map[string][]*entity.Comment, error) {
+	ret := _m.Called(ctx, riskIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Comments")
+	}
+
+	var r0 map[string][]*entity.Comment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) (map[string][]*entity.Comment, error)); ok {
+		return rf(ctx, riskIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) map[string][]*entity.Comment); ok {
+		r0 = rf(ctx, riskIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string][]*entity.Comment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, riskIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Create provides a mock function with given fields: ctx, input
 func (_m *Service) Create(ctx context.Context, input *risk.CreateRiskRequest) (*entity.Risk, error) {
 	ret := _m.Called(ctx, input)
@@ -46,6 +154,36 @@ func (_m *Service) Create(ctx context.Context, input *risk.CreateRiskRequest) (*
 	return r0, r1
 }
 
+// Export provides a mock function with given fields: ctx
+func (_m *Service) Export(ctx context.Context) ([]*entity.Risk, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Export")
+	}
+
+	var r0 []*entity.Risk
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*entity.Risk, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []*entity.Risk); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entity.Risk)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Get provides a mock function with given fields: ctx, id
 func (_m *Service) Get(ctx context.Context, id string) (*entity.Risk, error) {
 	ret := _m.Called(ctx, id)
@@ -106,6 +244,126 @@ func (_m *Service) GetAll(ctx context.Context, offset int, limit int) ([]*entity
 	return r0, r1
 }
 
+// GetMany provides a mock function with given fields: ctx, ids
+func (_m *Service) GetMany(ctx context.Context, ids []string) (map[string]*entity.Risk, error) {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMany")
+	}
+
+	var r0 map[string]*entity.Risk
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) (map[string]*entity.Risk, error)); ok {
+		return rf(ctx, ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) map[string]*entity.Risk); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]*entity.Risk)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// History provides a mock function with given fields: ctx, riskIDs
+func (_m *Service) History(ctx context.Context, riskIDs []string) (map[string][]risk.Event, error) {
+	ret := _m.Called(ctx, riskIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for History")
+	}
+
+	var r0 map[string][]risk.Event
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) (map[string][]risk.Event, error)); ok {
+		return rf(ctx, riskIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) map[string][]risk.Event); ok {
+		r0 = rf(ctx, riskIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string][]risk.Event)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, riskIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Mitigations provides a mock function with given fields: ctx, riskIDs
+func (_m *Service) Mitigations(ctx context.Context, riskIDs []string) (map[string][]*entity.Mitigation, error) {
+	ret := _m.Called(ctx, riskIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Mitigations")
+	}
+
+	var r0 map[string][]*entity.Mitigation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) (map[string][]*entity.Mitigation, error)); ok {
+		return rf(ctx, riskIDs)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) map[string][]*entity.Mitigation); ok {
+		r0 = rf(ctx, riskIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string][]*entity.Mitigation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, riskIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Update provides a mock function with given fields: ctx, id, input
+func (_m *Service) Update(ctx context.Context, id string, input *risk.UpdateRiskRequest) (*entity.Risk, error) {
+	ret := _m.Called(ctx, id, input)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 *entity.Risk
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *risk.UpdateRiskRequest) (*entity.Risk, error)); ok {
+		return rf(ctx, id, input)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *risk.UpdateRiskRequest) *entity.Risk); ok {
+		r0 = rf(ctx, id, input)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.Risk)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, *risk.UpdateRiskRequest) error); ok {
+		r1 = rf(ctx, id, input)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewService creates a new instance of Service. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewService(t interface {
@@ -0,0 +1,167 @@
+package risk
+
+import (
+	"github.com/vikasgithub/risky-plumbers/internal/entity"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/idempotency"
+	"github.com/vikasgithub/risky-plumbers/internal/openapi"
+	"net/http"
+)
+
+// Describe adds the operations registered by RegisterHandlers to the OpenAPI document.
+// Keep it in sync with RegisterHandlers, the coverage is verified by the tests.
+func Describe(doc *openapi.Document) {
+	riskRef := doc.AddSchema("Risk", riskSchema())
+	errRef := doc.AddSchema("ErrResponse", openapi.SchemaOf(errorstype.ErrResponse{}))
+	createRef := doc.AddSchema("CreateRiskRequest", createRiskRequestSchema())
+	exportRef := doc.AddSchema("RiskExport", openapi.SchemaOf(ExportResponse{}))
+	updateRef := doc.AddSchema("UpdateRiskRequest", updateRiskRequestSchema())
+	transitionRef := doc.AddSchema("TransitionRiskRequest", transitionRiskRequestSchema())
+
+	notFound := openapi.JSON("Risk not found", errRef)
+	invalid := openapi.JSON("Invalid request", errRef)
+	forbidden := openapi.JSON("The caller lacks the permission named in the response", errRef)
+
+	doc.AddOperation(http.MethodGet, "/risks/{id}", &openapi.Operation{
+		OperationID: "getRisk",
+		Summary:     "Get a risk by id",
+		Tags:        []string{"risks"},
+		Parameters: []openapi.Parameter{
+			{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "string", Format: "uuid"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSON("The risk", riskRef),
+			"400": invalid,
+			"403": forbidden,
+			"404": notFound,
+		},
+	})
+	doc.AddOperation(http.MethodGet, "/risks/export", &openapi.Operation{
+		OperationID: "exportRisks",
+		Summary:     "Export all the risks of the tenant of the caller except the confidential ones",
+		Tags:        []string{"risks"},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSON("The risks of the tenant", exportRef),
+			"403": forbidden,
+		},
+	})
+	doc.AddOperation(http.MethodGet, "/risks", &openapi.Operation{
+		OperationID: "listRisks",
+		Summary:     "List risks",
+		Tags:        []string{"risks"},
+		Parameters: []openapi.Parameter{
+			{Name: "offset", In: "query", Schema: &openapi.Schema{Type: "integer", Minimum: intPtr(0)}},
+			{Name: "limit", In: "query", Schema: &openapi.Schema{Type: "integer", Minimum: intPtr(0)}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSON("The risks", &openapi.Schema{Type: "array", Items: riskRef}),
+			"400": invalid,
+			"403": forbidden,
+		},
+	})
+	doc.AddOperation(http.MethodPost, "/risks", &openapi.Operation{
+		OperationID: "createRisk",
+		Summary:     "Create a risk",
+		Tags:        []string{"risks"},
+		Parameters: []openapi.Parameter{
+			{
+				Name:        idempotency.HeaderKey,
+				In:          "header",
+				Description: "Replays the first response when the request is retried with the same key",
+				Schema:      &openapi.Schema{Type: "string", MaxLength: intPtr(255)},
+			},
+		},
+		RequestBody: &openapi.RequestBody{
+			Required: true,
+			Content:  map[string]openapi.MediaType{"application/json": {Schema: createRef}},
+		},
+		Responses: map[string]openapi.Response{
+			"201": openapi.JSON("The created risk", riskRef),
+			"400": invalid,
+			"403": forbidden,
+			"409": openapi.JSON("A request with the same idempotency key is in progress", errRef),
+			"413": openapi.JSON("Request body too large", errRef),
+			"415": openapi.JSON("Unsupported media type", errRef),
+			"422": openapi.JSON("Idempotency key reused with a different request", errRef),
+		},
+	})
+	idParameter := openapi.Parameter{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "string", Format: "uuid"}}
+	doc.AddOperation(http.MethodPut, "/risks/{id}", &openapi.Operation{
+		OperationID: "updateRisk",
+		Summary:     "Update the fields of a risk which are set in the request, the others keep their value",
+		Tags:        []string{"risks"},
+		Parameters:  []openapi.Parameter{idParameter},
+		RequestBody: &openapi.RequestBody{
+			Required: true,
+			Content:  map[string]openapi.MediaType{"application/json": {Schema: updateRef}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSON("The updated risk", riskRef),
+			"400": invalid,
+			"403": forbidden,
+			"404": notFound,
+			"413": openapi.JSON("Request body too large", errRef),
+			"415": openapi.JSON("Unsupported media type", errRef),
+		},
+	})
+	doc.AddOperation(http.MethodPost, "/risks/{id}/transition", &openapi.Operation{
+		OperationID: "transitionRisk",
+		Summary:     "Move a risk to another state, accepting a risk requires the transition-to-accepted permission",
+		Tags:        []string{"risks"},
+		Parameters:  []openapi.Parameter{idParameter},
+		RequestBody: &openapi.RequestBody{
+			Required: true,
+			Content:  map[string]openapi.MediaType{"application/json": {Schema: transitionRef}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSON("The risk in its new state", riskRef),
+			"400": invalid,
+			"403": forbidden,
+			"404": notFound,
+			"413": openapi.JSON("Request body too large", errRef),
+			"415": openapi.JSON("Unsupported media type", errRef),
+		},
+	})
+}
+
+// riskSchema returns the schema of entity.Risk describing the redaction of the confidential risks
+func riskSchema() *openapi.Schema {
+	schema := openapi.SchemaOf(entity.Risk{})
+	schema.Properties["description"].Description = "Is " + RedactedDescription +
+		" when the risk is confidential and the caller lacks the read-confidential permission"
+	return schema
+}
+
+// createRiskRequestSchema returns the schema of CreateRiskRequest including the constraints checked by Validate.
+// The tenants overriding the limits are not described.
+func createRiskRequestSchema() *openapi.Schema {
+	schema := openapi.SchemaOf(CreateRiskRequest{})
+	schema.Required = []string{"state", "title", "description"}
+	schema.Properties["state"].Enum = states
+	schema.Properties["title"].MinLength = intPtr(1)
+	schema.Properties["title"].MaxLength = intPtr(maxTitleLength)
+	schema.Properties["description"].MinLength = intPtr(1)
+	schema.Properties["description"].MaxLength = intPtr(maxDescriptionLength)
+	schema.Properties["tags"].MaxItems = intPtr(maxTags)
+	schema.Properties["tags"].Items.MaxLength = intPtr(maxTagLength)
+	return schema
+}
+
+// updateRiskRequestSchema returns the schema of UpdateRiskRequest, its fields have the constraints of
+// CreateRiskRequest but none is required
+func updateRiskRequestSchema() *openapi.Schema {
+	schema := createRiskRequestSchema()
+	schema.Required = nil
+	return schema
+}
+
+func transitionRiskRequestSchema() *openapi.Schema {
+	schema := openapi.SchemaOf(TransitionRiskRequest{})
+	schema.Required = []string{"state"}
+	schema.Properties["state"].Enum = states
+	return schema
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,123 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"time"
+)
+
+// Outbox keeps the events written by the transactions until they are published.
+type Outbox interface {
+	// PendingEvents returns up to limit events which are not published yet, the oldest first, skipping the offset
+	// oldest ones
+	PendingEvents(ctx context.Context, offset, limit int) ([]Event, error)
+	// MarkPublished removes the given events from the outbox
+	MarkPublished(ctx context.Context, ids []string) error
+}
+
+// RelayConfig configures how often and how many events the relay publishes.
+type RelayConfig struct {
+	// PollInterval is the delay between two reads of the outbox
+	PollInterval time.Duration
+	// BatchSize is the maximum number of events read at once
+	BatchSize int
+}
+
+// Relay publishes the events of the outbox to the sinks, in the order they were written. Delivery is at-least-once:
+// an event stays in the outbox until every sink accepted it, so a sink may receive an event again after a crash.
+// Sinks use Event.ID to ignore duplicates. The progress is tracked per sink, so that a sink which is down does not
+// hold back the others.
+type Relay struct {
+	outbox Outbox
+	sinks  []Publisher
+	config RelayConfig
+	logger log.Logger
+	// acked records the sinks which accepted an event still in the outbox, so that they are not retried
+	acked map[string]map[int]bool
+}
+
+// NewRelay creates a relay publishing the events of the outbox to the given sinks.
+func NewRelay(outbox Outbox, config RelayConfig, logger log.Logger, sinks ...Publisher) *Relay {
+	return &Relay{outbox: outbox, sinks: sinks, config: config, logger: logger, acked: map[string]map[int]bool{}}
+}
+
+// Run publishes the pending events every PollInterval until ctx is done. Run must not be called concurrently
+// with itself or Flush.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := r.Flush(ctx); err != nil && ctx.Err() == nil {
+			r.logger.Errorf("failed to relay the outbox events: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush publishes the pending events until the outbox is empty. Each sink receives the events in the order they
+// were written: a sink rejecting an event receives none of the following ones until the next call, which retries it.
+// The other sinks keep receiving the events, which stay in the outbox until the rejecting sink accepted them.
+// Flush returns the errors of the rejecting sinks.
+func (r *Relay) Flush(ctx context.Context) error {
+	// failed holds the errors of the sinks which rejected an event during this call, by sink
+	failed := map[int]error{}
+	// offset skips the events read before which are still waiting for a sink
+	offset := 0
+	for len(r.sinks) == 0 || len(failed) < len(r.sinks) {
+		events, err := r.outbox.PendingEvents(ctx, offset, r.config.BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			break
+		}
+		var published []string
+		for _, event := range events {
+			if r.publish(ctx, event, failed) {
+				published = append(published, event.ID)
+			}
+		}
+		if len(published) > 0 {
+			if err := r.outbox.MarkPublished(ctx, published); err != nil {
+				return err
+			}
+			for _, id := range published {
+				delete(r.acked, id)
+			}
+		}
+		offset += len(events) - len(published)
+	}
+	errs := make([]error, 0, len(failed))
+	for i := range r.sinks {
+		if err, ok := failed[i]; ok {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// publish sends the event to the sinks which did not accept it yet, except the ones which failed during this flush.
+// It reports whether every sink accepted the event.
+func (r *Relay) publish(ctx context.Context, event Event, failed map[int]error) bool {
+	acked := r.acked[event.ID]
+	if acked == nil {
+		acked = map[int]bool{}
+		r.acked[event.ID] = acked
+	}
+	for i, sink := range r.sinks {
+		if _, ok := failed[i]; ok || acked[i] {
+			continue
+		}
+		if err := sink.Publish(ctx, event); err != nil {
+			failed[i] = err
+			continue
+		}
+		acked[i] = true
+	}
+	return len(acked) == len(r.sinks)
+}
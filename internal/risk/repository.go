@@ -5,46 +5,300 @@ import (
 	"github.com/vikasgithub/risky-plumbers/internal/entity"
 	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
 	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"sort"
 	"sync"
 )
 
+// Store keeps the risks of every tenant apart. The Repository of a tenant can not reach the risks of another
+// tenant, so that the tenants are isolated by construction.
+type Store interface {
+	// ForTenant returns the repository of the risks of the tenant
+	ForTenant(tenantID string) Repository
+	// CountByState returns the number of risks of every tenant by state, keyed by tenant id and then by state
+	CountByState(ctx context.Context) (map[string]map[string]int, error)
+	// Ping returns an error when the store can not be reached
+	Ping(ctx context.Context) error
+	// Close releases the connections of the store once the events of the outbox were relayed
+	Close() error
+	Outbox
+}
+
+// Repository gives access to the risks of a single tenant.
 type Repository interface {
 	Get(ctx context.Context, id string) (*entity.Risk, error)
+	// GetMany returns the risks with the given ids, indexed by id. Unknown ids are missing from the result.
+	GetMany(ctx context.Context, ids []string) (map[string]*entity.Risk, error)
 	Query(ctx context.Context, offset, limit int) ([]*entity.Risk, error)
 	Create(ctx context.Context, risk *entity.Risk) error
+	// Comments returns the comments of the risks with the given ids in the order they were added, indexed by risk id
+	Comments(ctx context.Context, riskIDs []string) (map[string][]*entity.Comment, error)
+	// Mitigations returns the mitigations of the risks with the given ids in the order they were added, indexed by
+	// risk id
+	Mitigations(ctx context.Context, riskIDs []string) (map[string][]*entity.Mitigation, error)
+	// History returns the events written for the risks with the given ids, oldest first, indexed by risk id
+	History(ctx context.Context, riskIDs []string) (map[string][]Event, error)
+	// Transaction runs fn and applies its writes, including the outbox events, atomically when fn returns nil.
+	// Nothing is written when fn returns an error. The events get the tenant of the repository and the
+	// correlation id of ctx.
+	Transaction(ctx context.Context, fn func(tx Tx) error) error
+}
+
+// Tx holds the writes of a transaction.
+type Tx interface {
+	Create(ctx context.Context, risk *entity.Risk) error
+	// Update replaces a risk written before
+	Update(ctx context.Context, risk *entity.Risk) error
+	AddComment(ctx context.Context, comment *entity.Comment) error
+	AddMitigation(ctx context.Context, mitigation *entity.Mitigation) error
+	// AddEvent writes the event to the outbox, it is published by the Relay once the transaction is committed
+	AddEvent(ctx context.Context, event Event) error
 }
 
 // when connecting with real db, the following struct will contain db context
+type store struct {
+	tenants sync.Map
+	logger  log.Logger
+	// mu guards the outbox and serializes the commits
+	mu     sync.Mutex
+	outbox []Event
+}
+
+// ForTenant does not keep the repository of a tenant until its first write, so that reading the risks of unknown
+// tenants does not grow the store
+func (s *store) ForTenant(tenantID string) Repository {
+	if value, ok := s.tenants.Load(tenantID); ok {
+		return value.(*repository)
+	}
+	return &repository{tenantID: tenantID, store: s}
+}
+
+func (s *store) CountByState(ctx context.Context) (map[string]map[string]int, error) {
+	counts := map[string]map[string]int{}
+	s.tenants.Range(func(key, value interface{}) bool {
+		states := map[string]int{}
+		value.(*repository).cache.Range(func(_, risk interface{}) bool {
+			states[risk.(*entity.Risk).State]++
+			return true
+		})
+		counts[key.(string)] = states
+		return true
+	})
+	return counts, nil
+}
+
+// Ping always succeeds, the risks are kept in memory
+func (s *store) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close has nothing to release, the risks are kept in memory
+func (s *store) Close() error {
+	return nil
+}
+
+func (s *store) PendingEvents(ctx context.Context, offset, limit int) ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if offset >= len(s.outbox) {
+		return nil, nil
+	}
+	pending := s.outbox[offset:]
+	if limit > len(pending) {
+		limit = len(pending)
+	}
+	return append([]Event(nil), pending[:limit]...), nil
+}
+
+func (s *store) MarkPublished(ctx context.Context, ids []string) error {
+	published := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		published[id] = true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := s.outbox[:0]
+	for _, event := range s.outbox {
+		if !published[event.ID] {
+			pending = append(pending, event)
+		}
+	}
+	s.outbox = pending
+	return nil
+}
+
+// repository holds the risks of one tenant
 type repository struct {
-	cache  sync.Map
-	logger log.Logger
+	tenantID string
+	cache    sync.Map
+	store    *store
+	// comments, mitigations and history are indexed by risk id and guarded by the mutex of the store
+	comments    map[string][]*entity.Comment
+	mitigations map[string][]*entity.Mitigation
+	history     map[string][]Event
+}
+
+// loaded returns the repository kept by the store for the tenant, or r when the tenant has no risks yet
+func (r *repository) loaded() *repository {
+	if value, ok := r.store.tenants.Load(r.tenantID); ok {
+		return value.(*repository)
+	}
+	return r
+}
+
+// stored returns the repository kept by the store for the tenant, it keeps r when the tenant has none yet
+func (r *repository) stored() *repository {
+	value, _ := r.store.tenants.LoadOrStore(r.tenantID, r)
+	return value.(*repository)
 }
 
 func (r *repository) Get(ctx context.Context, id string) (*entity.Risk, error) {
-	value, ok := r.cache.Load(id)
+	value, ok := r.loaded().cache.Load(id)
 	if !ok {
 		return nil, errorstype.ErrRecordNotFound
 	}
 	return value.(*entity.Risk), nil
 }
 
+func (r *repository) GetMany(ctx context.Context, ids []string) (map[string]*entity.Risk, error) {
+	risks := make(map[string]*entity.Risk, len(ids))
+	cache := &r.loaded().cache
+	for _, id := range ids {
+		if value, ok := cache.Load(id); ok {
+			risks[id] = value.(*entity.Risk)
+		}
+	}
+	return risks, nil
+}
+
 func (r *repository) Query(ctx context.Context, offset, limit int) ([]*entity.Risk, error) {
 	var entities []*entity.Risk
-	//TODO offset and limit is not implemented due to time constraints
-	r.cache.Range(func(key, value interface{}) bool {
+	r.loaded().cache.Range(func(key, value interface{}) bool {
 		entities = append(entities, value.(*entity.Risk))
 		return true
 	})
+	// sync.Map does not keep any order, sort by id so that the pages are stable
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i].ID < entities[j].ID
+	})
+	if offset >= len(entities) {
+		return nil, nil
+	}
+	entities = entities[offset:]
+	if limit < len(entities) {
+		entities = entities[:limit]
+	}
 	return entities, nil
 }
 
+func (r *repository) Comments(ctx context.Context, riskIDs []string) (map[string][]*entity.Comment, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return related(r.loaded().comments, riskIDs), nil
+}
+
+func (r *repository) Mitigations(ctx context.Context, riskIDs []string) (map[string][]*entity.Mitigation, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return related(r.loaded().mitigations, riskIDs), nil
+}
+
+func (r *repository) History(ctx context.Context, riskIDs []string) (map[string][]Event, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return related(r.loaded().history, riskIDs), nil
+}
+
+// related returns copies of the values of the given risks, the risks without values are missing from the result
+func related[T any](values map[string][]T, riskIDs []string) map[string][]T {
+	found := make(map[string][]T, len(riskIDs))
+	for _, id := range riskIDs {
+		if len(values[id]) > 0 {
+			found[id] = append([]T(nil), values[id]...)
+		}
+	}
+	return found
+}
+
 func (r *repository) Create(ctx context.Context, risk *entity.Risk) error {
-	r.cache.Store(risk.ID, risk)
+	r.stored().cache.Store(risk.ID, risk)
 
 	//Store does not throw any error, therefore returning nil here for the error
 	return nil
 }
 
-func NewRepository(logger log.Logger) Repository {
-	return &repository{logger: logger}
+func (r *repository) Transaction(ctx context.Context, fn func(tx Tx) error) error {
+	t := &tx{}
+	if err := fn(t); err != nil {
+		return err
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if len(t.risks) > 0 || len(t.comments) > 0 || len(t.mitigations) > 0 {
+		stored := r.stored()
+		for _, risk := range t.risks {
+			stored.cache.Store(risk.ID, risk)
+		}
+		if stored.comments == nil {
+			stored.comments = map[string][]*entity.Comment{}
+			stored.mitigations = map[string][]*entity.Mitigation{}
+			stored.history = map[string][]Event{}
+		}
+		for _, comment := range t.comments {
+			stored.comments[comment.RiskID] = append(stored.comments[comment.RiskID], comment)
+		}
+		for _, mitigation := range t.mitigations {
+			stored.mitigations[mitigation.RiskID] = append(stored.mitigations[mitigation.RiskID], mitigation)
+		}
+	}
+	for _, event := range t.events {
+		event.TenantID = r.tenantID
+		event.CorrelationID = log.CorrelationID(ctx)
+		r.store.outbox = append(r.store.outbox, event)
+		if event.Risk != nil {
+			if stored := r.loaded(); stored.history != nil {
+				stored.history[event.Risk.ID] = append(stored.history[event.Risk.ID], event)
+			}
+		}
+	}
+	r.store.logger.WithContext(ctx).Debugf("committed %d risks, %d comments, %d mitigations and %d events of tenant %s",
+		len(t.risks), len(t.comments), len(t.mitigations), len(t.events), r.tenantID)
+	return nil
+}
+
+// tx stages the writes until the transaction is committed
+type tx struct {
+	risks       []*entity.Risk
+	comments    []*entity.Comment
+	mitigations []*entity.Mitigation
+	events      []Event
+}
+
+func (t *tx) Create(ctx context.Context, risk *entity.Risk) error {
+	t.risks = append(t.risks, risk)
+	return nil
+}
+
+func (t *tx) Update(ctx context.Context, risk *entity.Risk) error {
+	t.risks = append(t.risks, risk)
+	return nil
+}
+
+func (t *tx) AddComment(ctx context.Context, comment *entity.Comment) error {
+	t.comments = append(t.comments, comment)
+	return nil
+}
+
+func (t *tx) AddMitigation(ctx context.Context, mitigation *entity.Mitigation) error {
+	t.mitigations = append(t.mitigations, mitigation)
+	return nil
+}
+
+func (t *tx) AddEvent(ctx context.Context, event Event) error {
+	t.events = append(t.events, event)
+	return nil
+}
+
+// NewStore returns a Store keeping the risks in memory.
+func NewStore(logger log.Logger) Store {
+	return &store{logger: logger}
 }
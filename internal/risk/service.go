@@ -2,67 +2,640 @@ package risk
 
 import (
 	"context"
+	"fmt"
 	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/vikasgithub/risky-plumbers/internal/audit"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
 	"github.com/vikasgithub/risky-plumbers/internal/entity"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
 	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/tenant"
+	"github.com/vikasgithub/risky-plumbers/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"math"
 	"net/http"
+	"strings"
+	"time"
 )
 
+const (
+	maxTitleLength       = 128
+	maxDescriptionLength = 4096
+	maxTags              = 20
+	maxTagLength         = 32
+)
+
+const stateAccepted = "accepted"
+
+// MitigationPlanned is the status of a mitigation added without status
+const MitigationPlanned = "planned"
+
+// mitigationStatuses lists the values accepted for the status of a mitigation
+var mitigationStatuses = []interface{}{MitigationPlanned, "in-progress", "done"}
+
+// RedactedDescription replaces the description of a confidential risk for the callers who may not read it
+const RedactedDescription = "[redacted]"
+
+// states lists the values accepted for the state of a risk
+var states = []interface{}{"open", "closed", stateAccepted, "investigating"}
+
+// Limits are the constraints checked when a risk is created. A tenant may override them, see WithTenantLimits.
+type Limits struct {
+	MaxTitleLength       int
+	MaxDescriptionLength int
+	// States lists the values accepted for the state of a risk
+	States []string
+}
+
+// DefaultLimits returns the limits of the tenants without overrides.
+func DefaultLimits() Limits {
+	limits := Limits{MaxTitleLength: maxTitleLength, MaxDescriptionLength: maxDescriptionLength}
+	for _, state := range states {
+		limits.States = append(limits.States, state.(string))
+	}
+	return limits
+}
+
+// withDefaults returns the limits with the unset ones replaced by the default limits
+func (l Limits) withDefaults() Limits {
+	defaults := DefaultLimits()
+	if l.MaxTitleLength <= 0 {
+		l.MaxTitleLength = defaults.MaxTitleLength
+	}
+	if l.MaxDescriptionLength <= 0 {
+		l.MaxDescriptionLength = defaults.MaxDescriptionLength
+	}
+	if len(l.States) == 0 {
+		l.States = defaults.States
+	}
+	return l
+}
+
+// Service gives access to the risks of the tenant of the context. The confidential risks are redacted for the
+// principals who may not perform auth.ActionReadConfidential, and every read of a confidential risk is audited.
 type Service interface {
 	Get(ctx context.Context, id string) (*entity.Risk, error)
+	GetMany(ctx context.Context, ids []string) (map[string]*entity.Risk, error)
 	GetAll(ctx context.Context, offset, limit int) ([]*entity.Risk, error)
 	Create(ctx context.Context, input *CreateRiskRequest) (*entity.Risk, error)
+	// Update changes the fields of the risk which are set in input
+	Update(ctx context.Context, id string, input *UpdateRiskRequest) (*entity.Risk, error)
+	// Comments returns the comments of the risks with the given ids, indexed by risk id. The comments of a
+	// confidential risk are redacted like its description.
+	Comments(ctx context.Context, riskIDs []string) (map[string][]*entity.Comment, error)
+	// AddComment adds a comment written by the principal of ctx to the risk
+	AddComment(ctx context.Context, riskID string, input *AddCommentRequest) (*entity.Comment, error)
+	// Mitigations returns the mitigations of the risks with the given ids, indexed by risk id
+	Mitigations(ctx context.Context, riskIDs []string) (map[string][]*entity.Mitigation, error)
+	AddMitigation(ctx context.Context, riskID string, input *AddMitigationRequest) (*entity.Mitigation, error)
+	// History returns the events of the risks with the given ids, oldest first, indexed by risk id. Their risks are
+	// redacted like the events of the streams.
+	History(ctx context.Context, riskIDs []string) (map[string][]Event, error)
+	// Export returns all the risks of the tenant of ctx except the confidential ones
+	Export(ctx context.Context) ([]*entity.Risk, error)
+	// Authorize returns the error of an operation requiring action which the principal of ctx may not perform.
+	// It is used by the streams of risk changes, which do not go through the service.
+	Authorize(ctx context.Context, action string) error
 }
 
 type CreateRiskRequest struct {
 	State       string `json:"state"`
 	Title       string `json:"title"`
 	Description string `json:"description"`
+	// Confidential redacts the description for the callers who may not read confidential risks
+	Confidential bool `json:"confidential"`
+	// Tags are stored in lower case without duplicates
+	Tags []string `json:"tags"`
 }
 
+// Bind trims the surrounding whitespace so that blank values do not pass the required checks
 func (cr *CreateRiskRequest) Bind(r *http.Request) error {
+	cr.State = strings.TrimSpace(cr.State)
+	cr.Title = strings.TrimSpace(cr.Title)
+	cr.Description = strings.TrimSpace(cr.Description)
+	cr.Tags = normalizeTags(cr.Tags)
 	return nil
 }
 
+// normalizeTags returns the tags in lower case, without surrounding whitespace, blank tags and duplicates
+func normalizeTags(tags []string) []string {
+	var normalized []string
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// validTags checks the length of every tag
+var validTags = validation.By(func(value interface{}) error {
+	for _, tag := range value.([]string) {
+		if len(tag) > maxTagLength {
+			return fmt.Errorf("the length of a tag must be no more than %d", maxTagLength)
+		}
+	}
+	return nil
+})
+
 func (cr *CreateRiskRequest) Validate() error {
+	return cr.ValidateWith(DefaultLimits())
+}
+
+// ValidateWith validates the request against the limits of a tenant.
+func (cr *CreateRiskRequest) ValidateWith(limits Limits) error {
+	allowed := make([]interface{}, 0, len(limits.States))
+	for _, state := range limits.States {
+		allowed = append(allowed, state)
+	}
 	return validation.ValidateStruct(cr,
-		validation.Field(&cr.Title, validation.Required, validation.Length(0, 128)),
-		validation.Field(&cr.Description, validation.Required, validation.Length(0, 4096)),
-		validation.Field(&cr.State, validation.Required,
-			validation.In("open", "closed", "accepted", "investigating")),
+		validation.Field(&cr.Title,
+			errorstype.Coded(validation.Required, errorstype.CodeRequired, nil),
+			errorstype.Coded(validation.Length(0, limits.MaxTitleLength), errorstype.CodeLengthMax,
+				map[string]interface{}{"max": limits.MaxTitleLength})),
+		validation.Field(&cr.Description,
+			errorstype.Coded(validation.Required, errorstype.CodeRequired, nil),
+			errorstype.Coded(validation.Length(0, limits.MaxDescriptionLength), errorstype.CodeLengthMax,
+				map[string]interface{}{"max": limits.MaxDescriptionLength})),
+		validation.Field(&cr.State,
+			errorstype.Coded(validation.Required, errorstype.CodeRequired, nil),
+			errorstype.Coded(validation.In(allowed...), errorstype.CodeInvalidValue,
+				map[string]interface{}{"allowed": allowed})),
+		validation.Field(&cr.Tags,
+			errorstype.Coded(validation.Length(0, maxTags), errorstype.CodeLengthMax, map[string]interface{}{"max": maxTags}),
+			errorstype.Coded(validTags, errorstype.CodeInvalidValue, map[string]interface{}{"maxTagLength": maxTagLength})),
+	)
+}
+
+// UpdateRiskRequest changes the fields of a risk which are set, the others keep their value
+type UpdateRiskRequest struct {
+	State        *string `json:"state,omitempty"`
+	Title        *string `json:"title,omitempty"`
+	Description  *string `json:"description,omitempty"`
+	Confidential *bool   `json:"confidential,omitempty"`
+	// Tags replace the tags of the risk when set
+	Tags *[]string `json:"tags,omitempty"`
+}
+
+// Bind keeps the fields as they are, merge trims them once the current risk is known
+func (ur *UpdateRiskRequest) Bind(r *http.Request) error {
+	return nil
+}
+
+// TransitionRiskRequest moves a risk to another state
+type TransitionRiskRequest struct {
+	State string `json:"state"`
+}
+
+func (tr *TransitionRiskRequest) Bind(r *http.Request) error {
+	tr.State = strings.TrimSpace(tr.State)
+	return nil
+}
+
+// merge returns the fields of risk with the ones set in the request, as a request to validate
+func (ur *UpdateRiskRequest) merge(risk *entity.Risk) *CreateRiskRequest {
+	merged := &CreateRiskRequest{
+		State:        risk.State,
+		Title:        risk.Title,
+		Description:  risk.Description,
+		Confidential: risk.Confidential,
+		Tags:         risk.Tags,
+	}
+	if ur.State != nil {
+		merged.State = strings.TrimSpace(*ur.State)
+	}
+	if ur.Title != nil {
+		merged.Title = strings.TrimSpace(*ur.Title)
+	}
+	if ur.Description != nil {
+		merged.Description = strings.TrimSpace(*ur.Description)
+	}
+	if ur.Confidential != nil {
+		merged.Confidential = *ur.Confidential
+	}
+	if ur.Tags != nil {
+		merged.Tags = normalizeTags(*ur.Tags)
+	}
+	return merged
+}
+
+type AddCommentRequest struct {
+	Body string `json:"body"`
+}
+
+// ValidateWith validates the request against the limits of a tenant, a comment is as long as a description at most.
+func (cr *AddCommentRequest) ValidateWith(limits Limits) error {
+	cr.Body = strings.TrimSpace(cr.Body)
+	return validation.ValidateStruct(cr,
+		validation.Field(&cr.Body,
+			errorstype.Coded(validation.Required, errorstype.CodeRequired, nil),
+			errorstype.Coded(validation.Length(0, limits.MaxDescriptionLength), errorstype.CodeLengthMax,
+				map[string]interface{}{"max": limits.MaxDescriptionLength})),
+	)
+}
+
+type AddMitigationRequest struct {
+	Title string `json:"title"`
+	// Status defaults to MitigationPlanned
+	Status string `json:"status"`
+}
+
+// ValidateWith validates the request against the limits of a tenant, a mitigation title is as long as a risk title
+// at most.
+func (mr *AddMitigationRequest) ValidateWith(limits Limits) error {
+	mr.Title = strings.TrimSpace(mr.Title)
+	mr.Status = strings.TrimSpace(mr.Status)
+	if mr.Status == "" {
+		mr.Status = MitigationPlanned
+	}
+	return validation.ValidateStruct(mr,
+		validation.Field(&mr.Title,
+			errorstype.Coded(validation.Required, errorstype.CodeRequired, nil),
+			errorstype.Coded(validation.Length(0, limits.MaxTitleLength), errorstype.CodeLengthMax,
+				map[string]interface{}{"max": limits.MaxTitleLength})),
+		validation.Field(&mr.Status,
+			errorstype.Coded(validation.In(mitigationStatuses...), errorstype.CodeInvalidValue,
+				map[string]interface{}{"allowed": mitigationStatuses})),
 	)
 }
 
 type service struct {
-	repo   Repository
+	store  Store
 	logger log.Logger
+	// policy authorizes the operations, every operation is allowed without a policy
+	policy auth.Policy
+	// limits holds the limits of the tenants overriding the default ones
+	limits map[string]Limits
+	// auditor records the reads of the confidential risks
+	auditor audit.Logger
+}
+
+// ServiceOption configures the service created by NewService
+type ServiceOption func(*service)
+
+// WithPolicy requires the principal of the context to have a role allowing each operation
+func WithPolicy(policy auth.Policy) ServiceOption {
+	return func(s *service) {
+		s.policy = policy
+	}
+}
+
+// WithTenantLimits overrides the limits of the given tenants. The unset limits keep their default value.
+func WithTenantLimits(limits map[string]Limits) ServiceOption {
+	return func(s *service) {
+		s.limits = make(map[string]Limits, len(limits))
+		for id, l := range limits {
+			s.limits[id] = l.withDefaults()
+		}
+	}
+}
+
+// WithAuditLogger records the reads of the confidential risks with auditor instead of the application log
+func WithAuditLogger(auditor audit.Logger) ServiceOption {
+	return func(s *service) {
+		s.auditor = auditor
+	}
+}
+
+// repository returns the repository of the tenant of ctx, the only tenant an operation can reach
+func (s service) repository(ctx context.Context) Repository {
+	tenantID := tenant.FromContext(ctx)
+	return &tracedRepository{Repository: s.store.ForTenant(tenantID), tenantID: tenantID}
+}
+
+// startSpan starts the span of an operation of the service
+func startSpan(ctx context.Context, operation string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	attributes = append(attributes, AttributeTenantID.String(tenant.FromContext(ctx)))
+	return tracing.Tracer().Start(ctx, "risk.Service/"+operation, trace.WithAttributes(attributes...))
 }
 
-func (s service) Get(ctx context.Context, id string) (*entity.Risk, error) {
-	return s.repo.Get(ctx, id)
+func (s service) tenantLimits(ctx context.Context) Limits {
+	if limits, ok := s.limits[tenant.FromContext(ctx)]; ok {
+		return limits
+	}
+	return DefaultLimits()
+}
+
+func (s service) Authorize(ctx context.Context, action string) error {
+	return s.authorize(ctx, action)
+}
+
+func (s service) authorize(ctx context.Context, action string) error {
+	if s.policy == nil {
+		return nil
+	}
+	return s.policy.Authorize(ctx, action)
 }
 
-func (s service) GetAll(ctx context.Context, offset, limit int) ([]*entity.Risk, error) {
-	return s.repo.Query(ctx, offset, limit)
+func (s service) Get(ctx context.Context, id string) (risk *entity.Risk, err error) {
+	ctx, span := startSpan(ctx, "Get", AttributeRiskID.String(id))
+	defer func() { tracing.End(span, err) }()
+	if err := s.authorize(ctx, auth.ActionRead); err != nil {
+		return nil, err
+	}
+	risk, err = s.repository(ctx).Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.reveal(ctx, risk), nil
 }
 
-func (s service) Create(ctx context.Context, input *CreateRiskRequest) (*entity.Risk, error) {
-	if err := input.Validate(); err != nil {
+func (s service) GetMany(ctx context.Context, ids []string) (risks map[string]*entity.Risk, err error) {
+	ctx, span := startSpan(ctx, "GetMany")
+	defer func() { tracing.End(span, err) }()
+	if err := s.authorize(ctx, auth.ActionRead); err != nil {
 		return nil, err
 	}
-	id := entity.GenerateID()
-	err := s.repo.Create(ctx, &entity.Risk{
-		ID:          id,
-		State:       input.State,
-		Title:       input.Title,
-		Description: input.Description,
+	risks, err = s.repository(ctx).GetMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for id, risk := range risks {
+		risks[id] = s.reveal(ctx, risk)
+	}
+	return risks, nil
+}
+
+func (s service) GetAll(ctx context.Context, offset, limit int) (risks []*entity.Risk, err error) {
+	ctx, span := startSpan(ctx, "GetAll")
+	defer func() { tracing.End(span, err) }()
+	if err := s.authorize(ctx, auth.ActionRead); err != nil {
+		return nil, err
+	}
+	risks, err = s.repository(ctx).Query(ctx, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i, risk := range risks {
+		risks[i] = s.reveal(ctx, risk)
+	}
+	return risks, nil
+}
+
+func (s service) Create(ctx context.Context, input *CreateRiskRequest) (created *entity.Risk, err error) {
+	ctx, span := startSpan(ctx, "Create")
+	defer func() { tracing.End(span, err) }()
+	if err := s.authorize(ctx, auth.ActionCreate); err != nil {
+		return nil, err
+	}
+	_, validationSpan := startSpan(ctx, "Create.validate")
+	err = input.ValidateWith(s.tenantLimits(ctx))
+	tracing.End(validationSpan, err)
+	if err != nil {
+		return nil, err
+	}
+	// creating an accepted risk accepts it
+	if input.State == stateAccepted {
+		if err := s.authorize(ctx, auth.ActionTransitionToAccepted); err != nil {
+			return nil, err
+		}
+	}
+	risk := &entity.Risk{
+		ID:           entity.GenerateID(),
+		State:        input.State,
+		Title:        input.Title,
+		Description:  input.Description,
+		Confidential: input.Confidential,
+		Tags:         normalizeTags(input.Tags),
+	}
+	repo := s.repository(ctx)
+	// the event is written in the same transaction, so that it is published if and only if the risk was created
+	err = repo.Transaction(ctx, func(tx Tx) error {
+		if err := tx.Create(ctx, risk); err != nil {
+			return err
+		}
+		return tx.AddEvent(ctx, NewEvent(EventCreated, risk))
+	})
+	if err != nil {
+		s.logger.WithContext(ctx).Errorf("failed to create the risk: %v", err)
+		return nil, err
+	}
+	s.logger.WithContext(ctx).Infof("created risk %s", risk.ID)
+	created, err = repo.Get(ctx, risk.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.reveal(ctx, created), nil
+}
+
+func (s service) Update(ctx context.Context, id string, input *UpdateRiskRequest) (updated *entity.Risk, err error) {
+	ctx, span := startSpan(ctx, "Update", AttributeRiskID.String(id))
+	defer func() { tracing.End(span, err) }()
+	if err := s.authorize(ctx, auth.ActionUpdate); err != nil {
+		return nil, err
+	}
+	repo := s.repository(ctx)
+	current, err := repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	merged := input.merge(current)
+	if err := merged.ValidateWith(s.tenantLimits(ctx)); err != nil {
+		return nil, err
+	}
+	if merged.State == stateAccepted && current.State != stateAccepted {
+		if err := s.authorize(ctx, auth.ActionTransitionToAccepted); err != nil {
+			return nil, err
+		}
+	}
+	// lifting the confidentiality reveals the description to every reader, only its readers may lift it
+	if current.Confidential && !merged.Confidential {
+		if err := s.authorize(ctx, auth.ActionReadConfidential); err != nil {
+			return nil, err
+		}
+	}
+	risk := &entity.Risk{
+		ID:           id,
+		State:        merged.State,
+		Title:        merged.Title,
+		Description:  merged.Description,
+		Confidential: merged.Confidential,
+		Tags:         merged.Tags,
+	}
+	err = repo.Transaction(ctx, func(tx Tx) error {
+		if err := tx.Update(ctx, risk); err != nil {
+			return err
+		}
+		return tx.AddEvent(ctx, NewEvent(EventUpdated, risk))
+	})
+	if err != nil {
+		s.logger.WithContext(ctx).Errorf("failed to update the risk %s: %v", id, err)
+		return nil, err
+	}
+	s.logger.WithContext(ctx).Infof("updated risk %s", id)
+	return s.reveal(ctx, risk), nil
+}
+
+func (s service) Comments(ctx context.Context, riskIDs []string) (comments map[string][]*entity.Comment, err error) {
+	ctx, span := startSpan(ctx, "Comments")
+	defer func() { tracing.End(span, err) }()
+	if err := s.authorize(ctx, auth.ActionRead); err != nil {
+		return nil, err
+	}
+	repo := s.repository(ctx)
+	comments, err = repo.Comments(ctx, riskIDs)
+	if err != nil || len(comments) == 0 {
+		return comments, err
+	}
+	ids := make([]string, 0, len(comments))
+	for id := range comments {
+		ids = append(ids, id)
+	}
+	risks, err := repo.GetMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for id, risk := range risks {
+		if risk.Confidential && !s.readsConfidential(ctx, risk) {
+			for i, comment := range comments[id] {
+				comments[id][i] = redactedComment(comment)
+			}
+		}
+	}
+	return comments, nil
+}
+
+func (s service) AddComment(ctx context.Context, riskID string, input *AddCommentRequest) (added *entity.Comment, err error) {
+	ctx, span := startSpan(ctx, "AddComment", AttributeRiskID.String(riskID))
+	defer func() { tracing.End(span, err) }()
+	if err := s.authorize(ctx, auth.ActionUpdate); err != nil {
+		return nil, err
+	}
+	if err := input.ValidateWith(s.tenantLimits(ctx)); err != nil {
+		return nil, err
+	}
+	repo := s.repository(ctx)
+	risk, err := repo.Get(ctx, riskID)
+	if err != nil {
+		return nil, err
+	}
+	comment := &entity.Comment{ID: entity.GenerateID(), RiskID: riskID, Body: input.Body, CreatedAt: time.Now().UTC()}
+	if principal, ok := auth.PrincipalFrom(ctx); ok {
+		comment.Author = principal.Subject
+	}
+	err = repo.Transaction(ctx, func(tx Tx) error {
+		return tx.AddComment(ctx, comment)
+	})
+	if err != nil {
+		s.logger.WithContext(ctx).Errorf("failed to comment the risk %s: %v", riskID, err)
+		return nil, err
+	}
+	s.logger.WithContext(ctx).Infof("commented risk %s", riskID)
+	if risk.Confidential && !s.readsConfidential(ctx, risk) {
+		return redactedComment(comment), nil
+	}
+	return comment, nil
+}
+
+func (s service) Mitigations(ctx context.Context, riskIDs []string) (mitigations map[string][]*entity.Mitigation, err error) {
+	ctx, span := startSpan(ctx, "Mitigations")
+	defer func() { tracing.End(span, err) }()
+	if err := s.authorize(ctx, auth.ActionRead); err != nil {
+		return nil, err
+	}
+	return s.repository(ctx).Mitigations(ctx, riskIDs)
+}
+
+func (s service) AddMitigation(ctx context.Context, riskID string, input *AddMitigationRequest) (added *entity.Mitigation, err error) {
+	ctx, span := startSpan(ctx, "AddMitigation", AttributeRiskID.String(riskID))
+	defer func() { tracing.End(span, err) }()
+	if err := s.authorize(ctx, auth.ActionUpdate); err != nil {
+		return nil, err
+	}
+	if err := input.ValidateWith(s.tenantLimits(ctx)); err != nil {
+		return nil, err
+	}
+	repo := s.repository(ctx)
+	if _, err := repo.Get(ctx, riskID); err != nil {
+		return nil, err
+	}
+	mitigation := &entity.Mitigation{
+		ID:        entity.GenerateID(),
+		RiskID:    riskID,
+		Title:     input.Title,
+		Status:    input.Status,
+		CreatedAt: time.Now().UTC(),
+	}
+	err = repo.Transaction(ctx, func(tx Tx) error {
+		return tx.AddMitigation(ctx, mitigation)
 	})
 	if err != nil {
+		s.logger.WithContext(ctx).Errorf("failed to add a mitigation to the risk %s: %v", riskID, err)
+		return nil, err
+	}
+	s.logger.WithContext(ctx).Infof("added mitigation %s to risk %s", mitigation.ID, riskID)
+	return mitigation, nil
+}
+
+func (s service) History(ctx context.Context, riskIDs []string) (history map[string][]Event, err error) {
+	ctx, span := startSpan(ctx, "History")
+	defer func() { tracing.End(span, err) }()
+	if err := s.authorize(ctx, auth.ActionRead); err != nil {
 		return nil, err
 	}
-	return s.repo.Get(ctx, id)
+	return s.repository(ctx).History(ctx, riskIDs)
 }
 
-func NewService(repo Repository, logger log.Logger) Service {
-	return service{repo, logger}
+func (s service) Export(ctx context.Context) (exported []*entity.Risk, err error) {
+	ctx, span := startSpan(ctx, "Export")
+	defer func() { tracing.End(span, err) }()
+	if err := s.authorize(ctx, auth.ActionRead); err != nil {
+		return nil, err
+	}
+	risks, err := s.repository(ctx).Query(ctx, 0, math.MaxInt)
+	if err != nil {
+		return nil, err
+	}
+	// the exports leave the service, so the confidential risks never take part in them
+	exported = []*entity.Risk{}
+	for _, risk := range risks {
+		if !risk.Confidential {
+			exported = append(exported, risk)
+		}
+	}
+	return exported, nil
+}
+
+// reveal returns the risk as the principal of ctx may see it, and audits the reads of the confidential risks
+func (s service) reveal(ctx context.Context, risk *entity.Risk) *entity.Risk {
+	if !risk.Confidential || s.readsConfidential(ctx, risk) {
+		return risk
+	}
+	return redacted(risk)
+}
+
+// readsConfidential reports whether the principal of ctx may read the confidential risk, and audits the read
+func (s service) readsConfidential(ctx context.Context, risk *entity.Risk) bool {
+	allowed := s.authorize(ctx, auth.ActionReadConfidential) == nil
+	entry := audit.NewEntry(ctx, audit.ActionReadConfidentialRisk, risk.ID)
+	entry.Redacted = !allowed
+	s.auditor.Record(ctx, entry)
+	return allowed
+}
+
+// redacted returns a copy of the risk without its confidential fields
+func redacted(risk *entity.Risk) *entity.Risk {
+	copy := *risk
+	copy.Description = RedactedDescription
+	return &copy
+}
+
+// redactedComment returns a copy of the comment of a confidential risk without its body
+func redactedComment(comment *entity.Comment) *entity.Comment {
+	copy := *comment
+	copy.Body = RedactedDescription
+	return &copy
+}
+
+// NewService creates the service, the operations act on the repository of the tenant of their context.
+func NewService(store Store, logger log.Logger, opts ...ServiceOption) Service {
+	s := &service{store: store, logger: logger, auditor: audit.NewLogger(logger)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return *s
 }
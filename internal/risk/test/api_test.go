@@ -5,10 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/vikasgithub/risky-plumbers/internal/entity"
 	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
 	"github.com/vikasgithub/risky-plumbers/internal/risk"
 	mocks "github.com/vikasgithub/risky-plumbers/internal/risk/mocks"
 	"net/http"
@@ -20,7 +22,7 @@ import (
 func TestGet(t *testing.T) {
 	router := chi.NewRouter()
 	riskService := &mocks.Service{}
-	risk.RegisterHandlers(router, riskService)
+	risk.RegisterHandlers(router, riskService, log.New())
 
 	t.Run("Risk Not Found", func(t *testing.T) {
 		riskService.On("Get", mock.Anything, mock.AnythingOfType("string")).Return(nil, errorstype.ErrRecordNotFound).Once()
@@ -48,7 +50,7 @@ func TestGet(t *testing.T) {
 func TestGetAll(t *testing.T) {
 	router := chi.NewRouter()
 	riskService := &mocks.Service{}
-	risk.RegisterHandlers(router, riskService)
+	risk.RegisterHandlers(router, riskService, log.New())
 
 	t.Run("Invalid Offset", func(t *testing.T) {
 		rq, _ := http.NewRequest("GET", "/risks?offset=a", nil)
@@ -102,7 +104,7 @@ func TestGetAll(t *testing.T) {
 func TestCreate(t *testing.T) {
 	router := chi.NewRouter()
 	riskService := &mocks.Service{}
-	risk.RegisterHandlers(router, riskService)
+	risk.RegisterHandlers(router, riskService, log.New())
 
 	t.Run("Invalid Risk Request Parameters", func(t *testing.T) {
 		riskService.On("Create", mock.Anything, mock.Anything).
@@ -116,6 +118,37 @@ func TestCreate(t *testing.T) {
 		assert.Equal(t, `{"status":"Invalid request.","error":"invalid request"}`, strings.Trim(rs.Body.String(), "\n"))
 	})
 
+	t.Run("Field Level Validation Errors", func(t *testing.T) {
+		validationErr := (&risk.CreateRiskRequest{State: "o", Title: "t"}).Validate()
+		riskService.On("Create", mock.Anything, mock.Anything).
+			Return(nil, validationErr).Once()
+		rq, _ := http.NewRequest("POST", "/risks",
+			bytes.NewBufferString(`{"state":"o","title":"t"}`))
+		rq.Header.Set("Content-Type", "application/json")
+		rs := httptest.NewRecorder()
+		router.ServeHTTP(rs, rq)
+		assert.Equal(t, http.StatusBadRequest, rs.Result().StatusCode)
+		assert.Equal(t, `{"status":"Invalid request.","error":"description: cannot be blank; state: must be a valid value.",`+
+			`"errors":[{"field":"description","code":"required","message":"cannot be blank"},`+
+			`{"field":"state","code":"invalid_value","message":"must be a valid value","params":{"allowed":["open","closed","accepted","investigating"]}}]}`,
+			strings.Trim(rs.Body.String(), "\n"))
+	})
+
+	t.Run("Localized Validation Errors", func(t *testing.T) {
+		validationErr := (&risk.CreateRiskRequest{State: "open", Title: "t", Description: strings.Repeat("d", 4097)}).Validate()
+		riskService.On("Create", mock.Anything, mock.Anything).
+			Return(nil, validationErr).Once()
+		rq, _ := http.NewRequest("POST", "/risks",
+			bytes.NewBufferString(`{"state":"open","title":"t","description":"d"}`))
+		rq.Header.Set("Content-Type", "application/json")
+		rq.Header.Set("Accept-Language", "de-CH, en;q=0.5")
+		rs := httptest.NewRecorder()
+		router.ServeHTTP(rs, rq)
+		assert.Equal(t, http.StatusBadRequest, rs.Result().StatusCode)
+		assert.Contains(t, rs.Body.String(),
+			`{"field":"description","code":"length_max","message":"die Länge darf höchstens 4096 betragen","params":{"max":4096}}`)
+	})
+
 	t.Run("Test Success", func(t *testing.T) {
 		riskEntity := &entity.Risk{ID: "2", State: "o", Title: "t", Description: "d"}
 		riskService.On("Create", mock.Anything, mock.Anything).
@@ -129,3 +162,135 @@ func TestCreate(t *testing.T) {
 		assert.Equal(t, `{"id":"2","state":"o","title":"t","description":"d"}`, strings.Trim(rs.Body.String(), "\n"))
 	})
 }
+
+func TestCreateStrictDecoding(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(middleware.RequestSize(64))
+	riskService := &mocks.Service{}
+	risk.RegisterHandlers(router, riskService, log.New())
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		response    string
+	}{
+		{"Unknown Field", "application/json", `{"state":"open","name":"t"}`, http.StatusBadRequest,
+			`{"status":"Invalid request.","error":"json: unknown field \"name\""}`},
+		{"Duplicate Key", "application/json", `{"state":"open","state":"closed"}`, http.StatusBadRequest,
+			`{"status":"Invalid request.","error":"duplicate key \"state\""}`},
+		{"Trailing Data", "application/json", `{"state":"open"} {}`, http.StatusBadRequest,
+			`{"status":"Invalid request.","error":"request body must contain a single JSON object"}`},
+		{"Non JSON Content Type", "text/plain", `{"state":"open"}`, http.StatusUnsupportedMediaType,
+			`{"status":"Unsupported media type.","error":"content type must be application/json"}`},
+		{"Missing Content Type", "", `{"state":"open"}`, http.StatusUnsupportedMediaType,
+			`{"status":"Unsupported media type.","error":"content type must be application/json"}`},
+		{"Body Too Large", "application/json", `{"description":"` + strings.Repeat("d", 64) + `"}`, http.StatusRequestEntityTooLarge,
+			`{"status":"Request body too large.","error":"request body must not be larger than 64 bytes"}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rq, _ := http.NewRequest("POST", "/risks", bytes.NewBufferString(tc.body))
+			if tc.contentType != "" {
+				rq.Header.Set("Content-Type", tc.contentType)
+			}
+			rs := httptest.NewRecorder()
+			router.ServeHTTP(rs, rq)
+			assert.Equal(t, tc.status, rs.Result().StatusCode)
+			assert.Equal(t, tc.response, strings.Trim(rs.Body.String(), "\n"))
+		})
+	}
+
+	t.Run("Whitespace Is Trimmed", func(t *testing.T) {
+		riskService.On("Create", mock.Anything, &risk.CreateRiskRequest{State: "open", Title: "t", Description: "d"}).
+			Return(&entity.Risk{ID: "1", State: "open", Title: "t", Description: "d"}, nil).Once()
+		rq, _ := http.NewRequest("POST", "/risks",
+			bytes.NewBufferString(`{"state":" open ","title":" t","description":"d "}`))
+		rq.Header.Set("Content-Type", "application/json; charset=utf-8")
+		rs := httptest.NewRecorder()
+		router.ServeHTTP(rs, rq)
+		assert.Equal(t, http.StatusCreated, rs.Result().StatusCode)
+		riskService.AssertExpectations(t)
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	router := chi.NewRouter()
+	riskService := &mocks.Service{}
+	risk.RegisterHandlers(router, riskService, log.New())
+	put := func(id, body string) *httptest.ResponseRecorder {
+		rq, _ := http.NewRequest("PUT", "/risks/"+id, bytes.NewBufferString(body))
+		rq.Header.Set("Content-Type", "application/json")
+		rs := httptest.NewRecorder()
+		router.ServeHTTP(rs, rq)
+		return rs
+	}
+
+	t.Run("Risk Not Found", func(t *testing.T) {
+		riskService.On("Update", mock.Anything, "1", mock.Anything).Return(nil, errorstype.ErrRecordNotFound).Once()
+		rs := put("1", `{"title":"t"}`)
+		assert.Equal(t, http.StatusNotFound, rs.Result().StatusCode)
+	})
+
+	t.Run("Field Level Validation Errors", func(t *testing.T) {
+		validationErr := (&risk.CreateRiskRequest{State: "open", Title: "t"}).Validate()
+		riskService.On("Update", mock.Anything, "1", mock.Anything).Return(nil, validationErr).Once()
+		rs := put("1", `{"description":""}`)
+		assert.Equal(t, http.StatusBadRequest, rs.Result().StatusCode)
+		assert.Contains(t, rs.Body.String(), `{"field":"description","code":"required","message":"cannot be blank"}`)
+	})
+
+	t.Run("Forbidden", func(t *testing.T) {
+		riskService.On("Update", mock.Anything, "1", mock.Anything).
+			Return(nil, &errorstype.ForbiddenError{Permission: "update"}).Once()
+		rs := put("1", `{"title":"t"}`)
+		assert.Equal(t, http.StatusForbidden, rs.Result().StatusCode)
+	})
+
+	t.Run("Only The Fields Sent Are Updated", func(t *testing.T) {
+		title := "new"
+		riskService.On("Update", mock.Anything, "1", &risk.UpdateRiskRequest{Title: &title}).
+			Return(&entity.Risk{ID: "1", State: "open", Title: "new", Description: "d"}, nil).Once()
+		rs := put("1", `{"title":"new"}`)
+		assert.Equal(t, http.StatusOK, rs.Result().StatusCode)
+		assert.Equal(t, `{"id":"1","state":"open","title":"new","description":"d"}`, strings.Trim(rs.Body.String(), "\n"))
+	})
+
+	t.Run("Unknown Field", func(t *testing.T) {
+		rs := put("1", `{"name":"t"}`)
+		assert.Equal(t, http.StatusBadRequest, rs.Result().StatusCode)
+	})
+	riskService.AssertExpectations(t)
+}
+
+func TestTransition(t *testing.T) {
+	router := chi.NewRouter()
+	riskService := &mocks.Service{}
+	risk.RegisterHandlers(router, riskService, log.New())
+	transition := func(body string) *httptest.ResponseRecorder {
+		rq, _ := http.NewRequest("POST", "/risks/1/transition", bytes.NewBufferString(body))
+		rq.Header.Set("Content-Type", "application/json")
+		rs := httptest.NewRecorder()
+		router.ServeHTTP(rs, rq)
+		return rs
+	}
+
+	t.Run("Only The State Is Updated", func(t *testing.T) {
+		state := "closed"
+		riskService.On("Update", mock.Anything, "1", &risk.UpdateRiskRequest{State: &state}).
+			Return(&entity.Risk{ID: "1", State: "closed", Title: "t", Description: "d"}, nil).Once()
+		rs := transition(`{"state":" closed "}`)
+		assert.Equal(t, http.StatusOK, rs.Result().StatusCode)
+		assert.Equal(t, `{"id":"1","state":"closed","title":"t","description":"d"}`, strings.Trim(rs.Body.String(), "\n"))
+	})
+
+	t.Run("Accepting Requires The Permission", func(t *testing.T) {
+		riskService.On("Update", mock.Anything, "1", mock.Anything).
+			Return(nil, &errorstype.ForbiddenError{Permission: "transition-to-accepted"}).Once()
+		rs := transition(`{"state":"accepted"}`)
+		assert.Equal(t, http.StatusForbidden, rs.Result().StatusCode)
+		assert.Contains(t, rs.Body.String(), `"permission":"transition-to-accepted"`)
+	})
+	riskService.AssertExpectations(t)
+}
@@ -0,0 +1,155 @@
+package risktest
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/vikasgithub/risky-plumbers/internal/audit"
+	auditmocks "github.com/vikasgithub/risky-plumbers/internal/audit/mocks"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"github.com/vikasgithub/risky-plumbers/internal/entity"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// expectAudit expects the read of the risk to be audited once, redacted or not
+func expectAudit(auditor *auditmocks.Logger, riskID string, redacted bool) {
+	auditor.On("Record", mock.Anything, mock.MatchedBy(func(entry audit.Entry) bool {
+		return entry.Action == audit.ActionReadConfidentialRisk && entry.ResourceID == riskID &&
+			entry.Subject == "test" && entry.Redacted == redacted
+	})).Once()
+}
+
+func TestConfidentialRisks(t *testing.T) {
+	store := risk.NewStore(log.New())
+	auditor := auditmocks.NewLogger(t)
+	service := risk.NewService(store, log.New(), risk.WithPolicy(auth.DefaultPolicy()), risk.WithAuditLogger(auditor))
+	viewer, security := withRoles(auth.RoleViewer), withRoles(auth.RoleSecurity)
+
+	// the created risk is read back, which is audited like any other read
+	auditor.On("Record", mock.Anything, mock.MatchedBy(func(entry audit.Entry) bool { return !entry.Redacted })).Once()
+	created, err := service.Create(withRoles(auth.RoleAdmin), &risk.CreateRiskRequest{
+		State: "open", Title: "t", Description: "CVE-2024-0001 is not patched", Confidential: true,
+	})
+	assert.NoError(t, err)
+	public, err := service.Create(withRoles(auth.RoleAdmin), &risk.CreateRiskRequest{State: "open", Title: "p", Description: "d"})
+	assert.NoError(t, err)
+
+	t.Run("Redacted For Readers Without Permission", func(t *testing.T) {
+		expectAudit(auditor, created.ID, true)
+		found, err := service.Get(viewer, created.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, risk.RedactedDescription, found.Description)
+		assert.True(t, found.Confidential)
+		assert.Equal(t, "t", found.Title)
+	})
+
+	t.Run("Revealed To Readers With Permission", func(t *testing.T) {
+		expectAudit(auditor, created.ID, false)
+		found, err := service.Get(security, created.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "CVE-2024-0001 is not patched", found.Description)
+	})
+
+	t.Run("Redacted In Listings", func(t *testing.T) {
+		expectAudit(auditor, created.ID, true)
+		all, err := service.GetAll(viewer, 0, 10)
+		assert.NoError(t, err)
+		descriptions := map[string]string{}
+		for _, r := range all {
+			descriptions[r.ID] = r.Description
+		}
+		assert.Equal(t, map[string]string{created.ID: risk.RedactedDescription, public.ID: "d"}, descriptions)
+
+		expectAudit(auditor, created.ID, true)
+		many, err := service.GetMany(viewer, []string{created.ID, public.ID})
+		assert.NoError(t, err)
+		assert.Equal(t, risk.RedactedDescription, many[created.ID].Description)
+		assert.Equal(t, "d", many[public.ID].Description)
+	})
+
+	t.Run("Comments Redacted For Readers Without Permission", func(t *testing.T) {
+		expectAudit(auditor, created.ID, false)
+		_, err := service.AddComment(withRoles(auth.RoleAdmin), created.ID, &risk.AddCommentRequest{Body: "patch it"})
+		assert.NoError(t, err)
+
+		expectAudit(auditor, created.ID, true)
+		comments, err := service.Comments(viewer, []string{created.ID, public.ID})
+		assert.NoError(t, err)
+		if assert.Len(t, comments[created.ID], 1) {
+			assert.Equal(t, risk.RedactedDescription, comments[created.ID][0].Body)
+		}
+
+		expectAudit(auditor, created.ID, false)
+		comments, err = service.Comments(security, []string{created.ID})
+		assert.NoError(t, err)
+		assert.Equal(t, "patch it", comments[created.ID][0].Body)
+	})
+
+	t.Run("Excluded From Exports", func(t *testing.T) {
+		exported, err := service.Export(withRoles(auth.RoleAdmin))
+		assert.NoError(t, err)
+		assert.Equal(t, []*entity.Risk{public}, exported)
+	})
+
+	t.Run("Redacted In Events", func(t *testing.T) {
+		events, err := store.PendingEvents(context.Background(), 0, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, risk.RedactedDescription, events[0].Risk.Description)
+		assert.True(t, events[0].Risk.Confidential)
+	})
+
+	t.Run("Stored Unredacted", func(t *testing.T) {
+		stored, err := store.ForTenant("default").Get(context.Background(), created.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "CVE-2024-0001 is not patched", stored.Description)
+	})
+}
+
+func TestConfidentialAPI(t *testing.T) {
+	auditor := auditmocks.NewLogger(t)
+	auditor.On("Record", mock.Anything, mock.Anything)
+	service := risk.NewService(risk.NewStore(log.New()), log.New(), risk.WithPolicy(auth.DefaultPolicy()), risk.WithAuditLogger(auditor))
+	created, err := service.Create(withRoles(auth.RoleAdmin), &risk.CreateRiskRequest{
+		State: "open", Title: "t", Description: "secret", Confidential: true,
+	})
+	assert.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := &auth.Principal{Subject: "test", Roles: []string{r.Header.Get("X-Role")}}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	})
+	risk.RegisterHandlers(router, service, log.New())
+	get := func(path, role string) *httptest.ResponseRecorder {
+		rq := httptest.NewRequest(http.MethodGet, path, nil)
+		rq.Header.Set("X-Role", role)
+		rs := httptest.NewRecorder()
+		router.ServeHTTP(rs, rq)
+		return rs
+	}
+
+	rs := get("/risks/"+created.ID, auth.RoleViewer)
+	assert.Equal(t, http.StatusOK, rs.Code)
+	assert.JSONEq(t, `{"id":"`+created.ID+`","state":"open","title":"t","description":"[redacted]","confidential":true}`, rs.Body.String())
+
+	rs = get("/risks", auth.RoleViewer)
+	var listed []entity.Risk
+	assert.NoError(t, json.Unmarshal(rs.Body.Bytes(), &listed))
+	assert.Equal(t, risk.RedactedDescription, listed[0].Description)
+
+	rs = get("/risks/"+created.ID, auth.RoleSecurity)
+	assert.JSONEq(t, `{"id":"`+created.ID+`","state":"open","title":"t","description":"secret","confidential":true}`, rs.Body.String())
+
+	rs = get("/risks/export", auth.RoleAdmin)
+	var export risk.ExportResponse
+	assert.NoError(t, json.Unmarshal(rs.Body.Bytes(), &export))
+	assert.Empty(t, export.Risks)
+}
@@ -0,0 +1,75 @@
+package risktest
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/openapi"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	mocks "github.com/vikasgithub/risky-plumbers/internal/risk/mocks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAPICoversAllRoutes(t *testing.T) {
+	router := chi.NewRouter()
+	risk.RegisterHandlers(router, &mocks.Service{}, log.New())
+	doc := openapi.New("test", "test", "/api/v1")
+	risk.Describe(doc)
+
+	routes := 0
+	chi.Walk(router, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		routes++
+		assert.True(t, doc.HasOperation(method, route), "%s %s is not described in the OpenAPI document", method, route)
+		return nil
+	})
+	assert.NotZero(t, routes)
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	router := chi.NewRouter()
+	doc := openapi.New("test", "test", "/api/v1")
+	risk.Describe(doc)
+	openapi.RegisterHandlers(router, doc)
+
+	rq, _ := http.NewRequest("GET", "/openapi.json", nil)
+	rs := httptest.NewRecorder()
+	router.ServeHTTP(rs, rq)
+	assert.Equal(t, http.StatusOK, rs.Result().StatusCode)
+
+	var body struct {
+		OpenAPI    string `json:"openapi"`
+		Components struct {
+			Schemas map[string]struct {
+				Required   []string `json:"required"`
+				Properties map[string]struct {
+					MaxLength int           `json:"maxLength"`
+					Enum      []interface{} `json:"enum"`
+				} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	assert.NoError(t, json.Unmarshal(rs.Body.Bytes(), &body))
+	assert.Equal(t, "3.1.0", body.OpenAPI)
+
+	createRequest := body.Components.Schemas["CreateRiskRequest"]
+	assert.ElementsMatch(t, []string{"state", "title", "description"}, createRequest.Required)
+	assert.Equal(t, 128, createRequest.Properties["title"].MaxLength)
+	assert.Equal(t, 4096, createRequest.Properties["description"].MaxLength)
+	assert.Equal(t, []interface{}{"open", "closed", "accepted", "investigating"}, createRequest.Properties["state"].Enum)
+
+	errResponse := body.Components.Schemas["ErrResponse"]
+	assert.Contains(t, errResponse.Properties, "status")
+	assert.Contains(t, errResponse.Properties, "errors")
+	assert.NotContains(t, errResponse.Properties, "Err")
+
+	rq, _ = http.NewRequest("GET", "/docs", nil)
+	rs = httptest.NewRecorder()
+	router.ServeHTTP(rs, rq)
+	assert.Equal(t, http.StatusOK, rs.Result().StatusCode)
+	assert.Contains(t, rs.Body.String(), `spec-url="openapi.json"`)
+	assert.Contains(t, rs.Body.String(), `<script src="`+openapi.RedocScript+`"`, "the page loads the pinned bundle")
+	assert.Contains(t, rs.Result().Header.Get("Content-Security-Policy"), "script-src "+openapi.RedocScript+";")
+}
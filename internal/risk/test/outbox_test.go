@@ -0,0 +1,115 @@
+package risktest
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"github.com/vikasgithub/risky-plumbers/internal/tenant"
+	"testing"
+	"time"
+)
+
+// sink records the published event ids and fails while failures is positive
+type sink struct {
+	ids      []string
+	failures int
+}
+
+func (s *sink) Publish(ctx context.Context, event risk.Event) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("unavailable")
+	}
+	s.ids = append(s.ids, event.ID)
+	return nil
+}
+
+func addEvents(t *testing.T, store risk.Store, ids ...string) {
+	ctx := context.Background()
+	repo := store.ForTenant(tenant.Default)
+	for _, id := range ids {
+		id := id
+		assert.NoError(t, repo.Transaction(ctx, func(tx risk.Tx) error {
+			return tx.AddEvent(ctx, risk.Event{ID: id, Type: risk.EventCreated})
+		}))
+	}
+}
+
+func TestRelayFlush(t *testing.T) {
+	store := risk.NewStore(log.New())
+	first, second := &sink{}, &sink{}
+	relay := risk.NewRelay(store, risk.RelayConfig{PollInterval: time.Hour, BatchSize: 2}, log.New(), first, second)
+	addEvents(t, store, "e1", "e2", "e3")
+
+	assert.NoError(t, relay.Flush(context.Background()))
+	assert.Equal(t, []string{"e1", "e2", "e3"}, first.ids)
+	assert.Equal(t, []string{"e1", "e2", "e3"}, second.ids)
+	events, _ := store.PendingEvents(context.Background(), 0, 10)
+	assert.Empty(t, events)
+}
+
+func TestRelayRetriesFailedSinks(t *testing.T) {
+	store := risk.NewStore(log.New())
+	first, second := &sink{}, &sink{failures: 1}
+	relay := risk.NewRelay(store, risk.RelayConfig{PollInterval: time.Hour, BatchSize: 10}, log.New(), first, second)
+	addEvents(t, store, "e1", "e2")
+
+	assert.Error(t, relay.Flush(context.Background()))
+	assert.Equal(t, []string{"e1", "e2"}, first.ids, "the failing sink does not hold back the others")
+	assert.Empty(t, second.ids)
+	events, _ := store.PendingEvents(context.Background(), 0, 10)
+	assert.Len(t, events, 2, "the events stay in the outbox until all sinks accepted them")
+
+	assert.NoError(t, relay.Flush(context.Background()))
+	assert.Equal(t, []string{"e1", "e2"}, first.ids, "the sinks which accepted an event do not receive it again")
+	assert.Equal(t, []string{"e1", "e2"}, second.ids)
+	events, _ = store.PendingEvents(context.Background(), 0, 10)
+	assert.Empty(t, events)
+}
+
+func TestRelayTracksEachSink(t *testing.T) {
+	store := risk.NewStore(log.New())
+	healthy, down := &sink{}, &sink{failures: 100}
+	relay := risk.NewRelay(store, risk.RelayConfig{PollInterval: time.Hour, BatchSize: 2}, log.New(), healthy, down)
+	addEvents(t, store, "e1", "e2", "e3", "e4", "e5")
+
+	assert.ErrorContains(t, relay.Flush(context.Background()), "unavailable")
+	assert.Equal(t, []string{"e1", "e2", "e3", "e4", "e5"}, healthy.ids, "the events beyond the first batch are read")
+	assert.Empty(t, down.ids)
+	assert.Equal(t, 99, down.failures, "the sink is not retried until the next flush")
+	events, _ := store.PendingEvents(context.Background(), 0, 10)
+	assert.Len(t, events, 5)
+
+	addEvents(t, store, "e6")
+	down.failures = 0
+	assert.NoError(t, relay.Flush(context.Background()))
+	assert.Equal(t, []string{"e1", "e2", "e3", "e4", "e5", "e6"}, healthy.ids)
+	assert.Equal(t, []string{"e1", "e2", "e3", "e4", "e5", "e6"}, down.ids, "the sink receives the events in order")
+	events, _ = store.PendingEvents(context.Background(), 0, 10)
+	assert.Empty(t, events)
+}
+
+func TestRelayRun(t *testing.T) {
+	store := risk.NewStore(log.New())
+	broker := risk.NewBroker(10)
+	events, unsubscribe := broker.Subscribe()
+	defer unsubscribe()
+	relay := risk.NewRelay(store, risk.RelayConfig{PollInterval: 10 * time.Millisecond, BatchSize: 10}, log.New(), broker)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go relay.Run(ctx)
+
+	created, err := risk.NewService(store, log.New()).
+		Create(context.Background(), &risk.CreateRiskRequest{State: "open", Title: "t", Description: "d"})
+	assert.NoError(t, err)
+
+	select {
+	case event := <-events:
+		assert.Equal(t, risk.EventCreated, event.Type)
+		assert.Equal(t, created, event.Risk)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the event was not relayed")
+	}
+}
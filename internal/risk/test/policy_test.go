@@ -0,0 +1,108 @@
+package risktest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func withRoles(roles ...string) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "test", Roles: roles})
+}
+
+func assertForbidden(t *testing.T, err error, permission string) {
+	var forbidden *errorstype.ForbiddenError
+	if assert.True(t, errors.As(err, &forbidden), "expected a forbidden error, got %v", err) {
+		assert.Equal(t, permission, forbidden.Permission)
+	}
+}
+
+func TestServicePolicy(t *testing.T) {
+	service := risk.NewService(risk.NewStore(log.New()), log.New(), risk.WithPolicy(auth.DefaultPolicy()))
+	open := &risk.CreateRiskRequest{State: "open", Title: "t", Description: "d"}
+	accepted := &risk.CreateRiskRequest{State: "accepted", Title: "t", Description: "d"}
+
+	t.Run("Viewer", func(t *testing.T) {
+		ctx := withRoles(auth.RoleViewer)
+		_, err := service.GetAll(ctx, 0, 10)
+		assert.NoError(t, err)
+		_, err = service.Create(ctx, open)
+		assertForbidden(t, err, auth.ActionCreate)
+	})
+
+	t.Run("Contributor", func(t *testing.T) {
+		ctx := withRoles(auth.RoleContributor)
+		created, err := service.Create(ctx, open)
+		assert.NoError(t, err)
+		_, err = service.Get(ctx, created.ID)
+		assert.NoError(t, err)
+		_, err = service.Create(ctx, accepted)
+		assertForbidden(t, err, auth.ActionTransitionToAccepted)
+	})
+
+	t.Run("Risk Owner", func(t *testing.T) {
+		_, err := service.Create(withRoles(auth.RoleRiskOwner), accepted)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Roles Are Combined And Not Case Sensitive", func(t *testing.T) {
+		_, err := service.Create(withRoles("unknown", "Admin"), accepted)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Without Principal", func(t *testing.T) {
+		_, err := service.GetMany(context.Background(), []string{"1"})
+		assertForbidden(t, err, auth.ActionRead)
+		assertForbidden(t, service.Authorize(context.Background(), auth.ActionRead), auth.ActionRead)
+	})
+
+	t.Run("Without Policy", func(t *testing.T) {
+		_, err := risk.NewService(risk.NewStore(log.New()), log.New()).Create(context.Background(), accepted)
+		assert.NoError(t, err)
+	})
+}
+
+func TestPolicyValidate(t *testing.T) {
+	assert.NoError(t, auth.DefaultPolicy().Validate())
+	err := auth.Policy{"auditor": {"read", "approve"}}.Validate()
+	assert.ErrorContains(t, err, `unknown action "approve"`)
+}
+
+func TestForbiddenResponse(t *testing.T) {
+	service := risk.NewService(risk.NewStore(log.New()), log.New(), risk.WithPolicy(auth.DefaultPolicy()))
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := &auth.Principal{Subject: "test", Roles: []string{auth.RoleContributor}}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	})
+	risk.RegisterHandlers(router, service, log.New())
+	router.With(risk.Authorized(service, auth.ActionAdminister)).Get("/admin", func(w http.ResponseWriter, r *http.Request) {})
+
+	rq, _ := http.NewRequest("POST", "/risks", strings.NewReader(`{"state":"accepted","title":"t","description":"d"}`))
+	rq.Header.Set("Content-Type", "application/json")
+	rs := httptest.NewRecorder()
+	router.ServeHTTP(rs, rq)
+	assert.Equal(t, http.StatusForbidden, rs.Code)
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rs.Body.Bytes(), &body))
+	assert.Equal(t, "transition-to-accepted", body["permission"])
+	assert.Equal(t, "Forbidden.", body["status"])
+
+	rq, _ = http.NewRequest("GET", "/admin", nil)
+	rs = httptest.NewRecorder()
+	router.ServeHTTP(rs, rq)
+	assert.Equal(t, http.StatusForbidden, rs.Code)
+	assert.Contains(t, rs.Body.String(), `"permission":"administer"`)
+}
@@ -0,0 +1,115 @@
+package risktest
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"github.com/vikasgithub/risky-plumbers/internal/entity"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"testing"
+)
+
+func stringOf(value string) *string {
+	return &value
+}
+
+func TestServiceUpdate(t *testing.T) {
+	service := risk.NewService(risk.NewStore(log.New()), log.New(), risk.WithPolicy(auth.DefaultPolicy()))
+	admin, contributor := withRoles(auth.RoleAdmin), withRoles(auth.RoleContributor)
+	created, err := service.Create(admin, &risk.CreateRiskRequest{State: "open", Title: "t", Description: "d"})
+	assert.NoError(t, err)
+
+	t.Run("Keeps The Fields Left Out", func(t *testing.T) {
+		updated, err := service.Update(contributor, created.ID, &risk.UpdateRiskRequest{Title: stringOf(" leak ")})
+		assert.NoError(t, err)
+		assert.Equal(t, "leak", updated.Title)
+		assert.Equal(t, "open", updated.State)
+		assert.Equal(t, "d", updated.Description)
+	})
+
+	t.Run("Validates The Result", func(t *testing.T) {
+		_, err := service.Update(contributor, created.ID, &risk.UpdateRiskRequest{State: stringOf("unknown")})
+		assert.Error(t, err)
+		_, err = service.Update(contributor, created.ID, &risk.UpdateRiskRequest{Description: stringOf(" ")})
+		assert.Error(t, err)
+	})
+
+	t.Run("Unknown Risk", func(t *testing.T) {
+		_, err := service.Update(contributor, "unknown", &risk.UpdateRiskRequest{Title: stringOf("t")})
+		assert.ErrorIs(t, err, errorstype.ErrRecordNotFound)
+	})
+
+	t.Run("Permissions", func(t *testing.T) {
+		_, err := service.Update(withRoles(auth.RoleViewer), created.ID, &risk.UpdateRiskRequest{Title: stringOf("t")})
+		assertForbidden(t, err, auth.ActionUpdate)
+		_, err = service.Update(contributor, created.ID, &risk.UpdateRiskRequest{State: stringOf("accepted")})
+		assertForbidden(t, err, auth.ActionTransitionToAccepted)
+		_, err = service.Update(withRoles(auth.RoleRiskOwner), created.ID, &risk.UpdateRiskRequest{State: stringOf("accepted")})
+		assert.NoError(t, err)
+
+		confidential := true
+		_, err = service.Update(contributor, created.ID, &risk.UpdateRiskRequest{Confidential: &confidential})
+		assert.NoError(t, err)
+		public := false
+		_, err = service.Update(contributor, created.ID, &risk.UpdateRiskRequest{Confidential: &public})
+		assertForbidden(t, err, auth.ActionReadConfidential)
+	})
+
+	t.Run("History", func(t *testing.T) {
+		history, err := service.History(contributor, []string{created.ID})
+		assert.NoError(t, err)
+		var types []string
+		for _, event := range history[created.ID] {
+			types = append(types, event.Type)
+		}
+		assert.Equal(t, []string{risk.EventCreated, risk.EventUpdated, risk.EventUpdated, risk.EventUpdated}, types)
+		assert.Equal(t, risk.RedactedDescription, history[created.ID][3].Risk.Description,
+			"the history is redacted like the events")
+	})
+}
+
+func TestServiceComments(t *testing.T) {
+	service := risk.NewService(risk.NewStore(log.New()), log.New(), risk.WithPolicy(auth.DefaultPolicy()))
+	contributor := withRoles(auth.RoleContributor)
+	created, err := service.Create(contributor, &risk.CreateRiskRequest{State: "open", Title: "t", Description: "d"})
+	assert.NoError(t, err)
+
+	comment, err := service.AddComment(contributor, created.ID, &risk.AddCommentRequest{Body: " first "})
+	assert.NoError(t, err)
+	assert.Equal(t, "test", comment.Author)
+	assert.Equal(t, "first", comment.Body)
+	_, err = service.AddComment(contributor, created.ID, &risk.AddCommentRequest{Body: "second"})
+	assert.NoError(t, err)
+
+	comments, err := service.Comments(withRoles(auth.RoleViewer), []string{created.ID})
+	assert.NoError(t, err)
+	if assert.Len(t, comments[created.ID], 2) {
+		assert.Equal(t, comment, comments[created.ID][0])
+		assert.Equal(t, "second", comments[created.ID][1].Body)
+	}
+
+	_, err = service.AddComment(contributor, created.ID, &risk.AddCommentRequest{Body: " "})
+	assert.Error(t, err)
+	_, err = service.AddComment(contributor, "unknown", &risk.AddCommentRequest{Body: "b"})
+	assert.ErrorIs(t, err, errorstype.ErrRecordNotFound)
+	_, err = service.AddComment(withRoles(auth.RoleViewer), created.ID, &risk.AddCommentRequest{Body: "b"})
+	assertForbidden(t, err, auth.ActionUpdate)
+}
+
+func TestServiceMitigations(t *testing.T) {
+	service := risk.NewService(risk.NewStore(log.New()), log.New(), risk.WithPolicy(auth.DefaultPolicy()))
+	contributor := withRoles(auth.RoleContributor)
+	created, err := service.Create(contributor, &risk.CreateRiskRequest{State: "open", Title: "t", Description: "d"})
+	assert.NoError(t, err)
+
+	mitigation, err := service.AddMitigation(contributor, created.ID, &risk.AddMitigationRequest{Title: "replace the pipe"})
+	assert.NoError(t, err)
+	assert.Equal(t, risk.MitigationPlanned, mitigation.Status)
+	_, err = service.AddMitigation(contributor, created.ID, &risk.AddMitigationRequest{Title: "t", Status: "later"})
+	assert.Error(t, err)
+
+	mitigations, err := service.Mitigations(withRoles(auth.RoleViewer), []string{created.ID, "unknown"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]*entity.Mitigation{created.ID: {mitigation}}, mitigations)
+}
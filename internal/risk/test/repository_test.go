@@ -2,16 +2,18 @@ package risktest
 
 import (
 	"context"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/vikasgithub/risky-plumbers/internal/entity"
 	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
 	"github.com/vikasgithub/risky-plumbers/internal/log"
 	risk2 "github.com/vikasgithub/risky-plumbers/internal/risk"
+	"github.com/vikasgithub/risky-plumbers/internal/tenant"
 	"testing"
 )
 
 func TestGetRecordFound(t *testing.T) {
-	repo := risk2.NewRepository(log.New())
+	repo := risk2.NewStore(log.New()).ForTenant(tenant.Default)
 	repo.Create(context.Background(), &entity.Risk{
 		ID:          "1",
 		State:       "open",
@@ -24,14 +26,14 @@ func TestGetRecordFound(t *testing.T) {
 }
 
 func TestGetRecordNotFound(t *testing.T) {
-	repo := risk2.NewRepository(log.New())
+	repo := risk2.NewStore(log.New()).ForTenant(tenant.Default)
 	_, err := repo.Get(context.Background(), "1")
 	assert.NotEmpty(t, err)
 	assert.IsType(t, errorstype.ErrRecordNotFound, err)
 }
 
 func TestQueryRecordsFound(t *testing.T) {
-	repo := risk2.NewRepository(log.New())
+	repo := risk2.NewStore(log.New()).ForTenant(tenant.Default)
 	repo.Create(context.Background(), &entity.Risk{ID: "1", State: "open", Title: "title", Description: "desc"})
 	repo.Create(context.Background(), &entity.Risk{ID: "2", State: "open", Title: "title", Description: "desc"})
 	repo.Create(context.Background(), &entity.Risk{ID: "3", State: "open", Title: "title", Description: "desc"})
@@ -41,8 +43,208 @@ func TestQueryRecordsFound(t *testing.T) {
 }
 
 func TestQueryRecordsNotFound(t *testing.T) {
-	repo := risk2.NewRepository(log.New())
+	repo := risk2.NewStore(log.New()).ForTenant(tenant.Default)
 	risks, _ := repo.Query(context.Background(), 0, 5)
 	assert.Empty(t, risks)
 	assert.Equal(t, 0, len(risks))
 }
+
+func TestQueryRecordsPaged(t *testing.T) {
+	repo := risk2.NewStore(log.New()).ForTenant(tenant.Default)
+	repo.Create(context.Background(), &entity.Risk{ID: "3", State: "open", Title: "title", Description: "desc"})
+	repo.Create(context.Background(), &entity.Risk{ID: "1", State: "open", Title: "title", Description: "desc"})
+	repo.Create(context.Background(), &entity.Risk{ID: "2", State: "open", Title: "title", Description: "desc"})
+	risks, _ := repo.Query(context.Background(), 1, 1)
+	assert.Equal(t, 1, len(risks))
+	assert.Equal(t, "2", risks[0].ID)
+
+	risks, _ = repo.Query(context.Background(), 2, 5)
+	assert.Equal(t, 1, len(risks))
+	assert.Equal(t, "3", risks[0].ID)
+
+	risks, _ = repo.Query(context.Background(), 3, 5)
+	assert.Empty(t, risks)
+}
+
+func TestGetMany(t *testing.T) {
+	repo := risk2.NewStore(log.New()).ForTenant(tenant.Default)
+	repo.Create(context.Background(), &entity.Risk{ID: "1", State: "open", Title: "title", Description: "desc"})
+	repo.Create(context.Background(), &entity.Risk{ID: "2", State: "open", Title: "title", Description: "desc"})
+	risks, err := repo.GetMany(context.Background(), []string{"1", "2", "3"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(risks))
+	assert.Equal(t, "2", risks["2"].ID)
+}
+
+func TestReadsDoNotKeepUnknownTenants(t *testing.T) {
+	store := risk2.NewStore(log.New())
+	ctx := context.Background()
+	for _, tenantID := range []string{"unknown-1", "unknown-2"} {
+		repo := store.ForTenant(tenantID)
+		_, err := repo.Get(ctx, "1")
+		assert.ErrorIs(t, err, errorstype.ErrRecordNotFound)
+		risks, err := repo.Query(ctx, 0, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, risks)
+	}
+	counts, err := store.CountByState(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, counts)
+
+	// the repositories of a tenant share its risks once it has some
+	reader, writer := store.ForTenant("acme"), store.ForTenant("acme")
+	assert.NoError(t, writer.Create(ctx, &entity.Risk{ID: "1", State: "open", Title: "title", Description: "desc"}))
+	risk, err := reader.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", risk.ID)
+	counts, err = store.CountByState(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]map[string]int{"acme": {"open": 1}}, counts)
+}
+
+func TestTransactionCommits(t *testing.T) {
+	store := risk2.NewStore(log.New())
+	repo := store.ForTenant(tenant.Default)
+	ctx := context.Background()
+	err := repo.Transaction(ctx, func(tx risk2.Tx) error {
+		riskEntity := &entity.Risk{ID: "1", State: "open", Title: "title", Description: "desc"}
+		assert.NoError(t, tx.Create(ctx, riskEntity))
+		assert.NoError(t, tx.AddEvent(ctx, risk2.Event{ID: "e1", Type: risk2.EventCreated, Risk: riskEntity}))
+		// the writes are not visible before the commit
+		_, err := repo.Get(ctx, "1")
+		assert.ErrorIs(t, err, errorstype.ErrRecordNotFound)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	risk, err := repo.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", risk.ID)
+	events, _ := store.PendingEvents(ctx, 0, 10)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "e1", events[0].ID)
+	}
+}
+
+func TestTransactionCommitsTheRelatedRecords(t *testing.T) {
+	store := risk2.NewStore(log.New())
+	repo := store.ForTenant(tenant.Default)
+	ctx := context.Background()
+	riskEntity := &entity.Risk{ID: "1", State: "open", Title: "title", Description: "desc"}
+	assert.NoError(t, repo.Transaction(ctx, func(tx risk2.Tx) error {
+		assert.NoError(t, tx.Create(ctx, riskEntity))
+		return tx.AddEvent(ctx, risk2.Event{ID: "e1", Type: risk2.EventCreated, Risk: riskEntity})
+	}))
+	updated := &entity.Risk{ID: "1", State: "closed", Title: "title", Description: "desc"}
+	assert.NoError(t, repo.Transaction(ctx, func(tx risk2.Tx) error {
+		assert.NoError(t, tx.Update(ctx, updated))
+		assert.NoError(t, tx.AddComment(ctx, &entity.Comment{ID: "c1", RiskID: "1", Body: "b"}))
+		assert.NoError(t, tx.AddMitigation(ctx, &entity.Mitigation{ID: "m1", RiskID: "1", Title: "t", Status: "planned"}))
+		return tx.AddEvent(ctx, risk2.Event{ID: "e2", Type: risk2.EventUpdated, Risk: updated})
+	}))
+
+	found, err := repo.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "closed", found.State)
+	comments, err := repo.Comments(ctx, []string{"1", "2"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]*entity.Comment{"1": {{ID: "c1", RiskID: "1", Body: "b"}}}, comments)
+	mitigations, err := repo.Mitigations(ctx, []string{"1"})
+	assert.NoError(t, err)
+	assert.Len(t, mitigations["1"], 1)
+	history, err := repo.History(ctx, []string{"1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e1", "e2"}, eventIDs(history["1"]))
+
+	other, err := store.ForTenant("other").Comments(ctx, []string{"1"})
+	assert.NoError(t, err)
+	assert.Empty(t, other, "the comments are kept per tenant")
+}
+
+func TestTransactionRollsBack(t *testing.T) {
+	store := risk2.NewStore(log.New())
+	repo := store.ForTenant(tenant.Default)
+	ctx := context.Background()
+	err := repo.Transaction(ctx, func(tx risk2.Tx) error {
+		tx.Create(ctx, &entity.Risk{ID: "1", State: "open", Title: "title", Description: "desc"})
+		tx.AddEvent(ctx, risk2.Event{ID: "e1", Type: risk2.EventCreated})
+		return errors.New("failed")
+	})
+	assert.EqualError(t, err, "failed")
+
+	_, err = repo.Get(ctx, "1")
+	assert.ErrorIs(t, err, errorstype.ErrRecordNotFound)
+	events, _ := store.PendingEvents(ctx, 0, 10)
+	assert.Empty(t, events)
+}
+
+func TestOutbox(t *testing.T) {
+	store := risk2.NewStore(log.New())
+	repo := store.ForTenant(tenant.Default)
+	ctx := context.Background()
+	for _, id := range []string{"e1", "e2", "e3"} {
+		id := id
+		repo.Transaction(ctx, func(tx risk2.Tx) error {
+			return tx.AddEvent(ctx, risk2.Event{ID: id, Type: risk2.EventCreated})
+		})
+	}
+
+	events, _ := store.PendingEvents(ctx, 0, 2)
+	assert.Equal(t, []string{"e1", "e2"}, eventIDs(events))
+
+	assert.NoError(t, store.MarkPublished(ctx, []string{"e1", "e3"}))
+	events, _ = store.PendingEvents(ctx, 0, 10)
+	assert.Equal(t, []string{"e2"}, eventIDs(events))
+}
+
+func TestTenantsAreIsolated(t *testing.T) {
+	store := risk2.NewStore(log.New())
+	ctx := context.Background()
+	acme, globex := store.ForTenant("acme"), store.ForTenant("globex")
+	acme.Create(ctx, &entity.Risk{ID: "1", State: "open", Title: "title", Description: "desc"})
+	assert.NoError(t, globex.Transaction(ctx, func(tx risk2.Tx) error {
+		riskEntity := &entity.Risk{ID: "2", State: "open", Title: "title", Description: "desc"}
+		assert.NoError(t, tx.Create(ctx, riskEntity))
+		return tx.AddEvent(ctx, risk2.Event{ID: "e2", Type: risk2.EventCreated, Risk: riskEntity})
+	}))
+
+	_, err := globex.Get(ctx, "1")
+	assert.ErrorIs(t, err, errorstype.ErrRecordNotFound)
+	_, err = acme.Get(ctx, "2")
+	assert.ErrorIs(t, err, errorstype.ErrRecordNotFound)
+	risks, _ := globex.GetMany(ctx, []string{"1", "2"})
+	assert.Len(t, risks, 1)
+	all, _ := acme.Query(ctx, 0, 10)
+	if assert.Len(t, all, 1) {
+		assert.Equal(t, "1", all[0].ID)
+	}
+	// the repository returned for a tenant id is always the same one
+	_, err = store.ForTenant("acme").Get(ctx, "1")
+	assert.NoError(t, err)
+
+	events, _ := store.PendingEvents(ctx, 0, 10)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "globex", events[0].TenantID, "the events get the tenant of the repository")
+	}
+}
+
+func eventIDs(events []risk2.Event) []string {
+	var ids []string
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+func TestTransactionAddsTheCorrelationIDToTheEvents(t *testing.T) {
+	store := risk2.NewStore(log.New())
+	ctx := log.WithCorrelationID(context.Background(), "checkout-42")
+	err := store.ForTenant(tenant.Default).Transaction(ctx, func(tx risk2.Tx) error {
+		return tx.AddEvent(ctx, risk2.NewEvent(risk2.EventCreated, &entity.Risk{ID: "1"}))
+	})
+	assert.NoError(t, err)
+	events, _ := store.PendingEvents(context.Background(), 0, 10)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "checkout-42", events[0].CorrelationID)
+	}
+}
@@ -9,12 +9,22 @@ import (
 	"github.com/vikasgithub/risky-plumbers/internal/log"
 	"github.com/vikasgithub/risky-plumbers/internal/risk"
 	mocks "github.com/vikasgithub/risky-plumbers/internal/risk/mocks"
+	"github.com/vikasgithub/risky-plumbers/internal/tenant"
+	"strconv"
+	"strings"
 	"testing"
 )
 
+// storeOf returns a store whose default tenant has the repository repo
+func storeOf(repo risk.Repository) risk.Store {
+	store := &mocks.Store{}
+	store.On("ForTenant", tenant.Default).Return(repo)
+	return store
+}
+
 func TestServiceGet(t *testing.T) {
 	repo := &mocks.Repository{}
-	service := risk.NewService(repo, log.New())
+	service := risk.NewService(storeOf(repo), log.New())
 
 	t.Run("Repo must return error", func(t *testing.T) {
 		repo.On("Get", mock.Anything, mock.AnythingOfType("string")).
@@ -37,7 +47,7 @@ func TestServiceGet(t *testing.T) {
 
 func TestServiceGetAll(t *testing.T) {
 	repo := &mocks.Repository{}
-	service := risk.NewService(repo, log.New())
+	service := risk.NewService(storeOf(repo), log.New())
 
 	t.Run("Repo must return error", func(t *testing.T) {
 		repo.On("Query", mock.Anything, mock.AnythingOfType("int"), mock.AnythingOfType("int")).
@@ -64,7 +74,7 @@ func TestServiceGetAll(t *testing.T) {
 
 func TestServiceCreate(t *testing.T) {
 	repo := &mocks.Repository{}
-	service := risk.NewService(repo, log.New())
+	service := risk.NewService(storeOf(repo), log.New())
 
 	t.Run("Must Return ValidationErrors for Required Fields", func(t *testing.T) {
 		createRequest := &risk.CreateRiskRequest{
@@ -79,6 +89,18 @@ func TestServiceCreate(t *testing.T) {
 		assert.ErrorContains(t, err, "title: cannot be blank")
 	})
 
+	t.Run("Must Return ValidationErrors for blank fields", func(t *testing.T) {
+		createRequest := &risk.CreateRiskRequest{
+			State:       "open",
+			Title:       "   ",
+			Description: "d",
+		}
+		createRequest.Bind(nil)
+		_, err := service.Create(context.Background(), createRequest)
+		assert.NotEmpty(t, err)
+		assert.ErrorContains(t, err, "title: cannot be blank")
+	})
+
 	t.Run("Must Return ValidationErrors for invalid parameters", func(t *testing.T) {
 		createRequest := &risk.CreateRiskRequest{
 			State:       "open1",
@@ -99,12 +121,62 @@ func TestServiceCreate(t *testing.T) {
 			Title:       "t",
 			Description: "d",
 		}
-		repo.On("Create", mock.Anything, mock.Anything).
+		tx := &mocks.Tx{}
+		tx.On("Create", mock.Anything, mock.Anything).Return(nil).Once()
+		tx.On("AddEvent", mock.Anything, mock.Anything).Return(nil).Once()
+		repo.On("Transaction", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(func(risk.Tx) error)(tx)
+			}).
 			Return(nil).Once()
 		repo.On("Get", mock.Anything, mock.AnythingOfType("string")).
 			Return(riskEntity, nil).Once()
 		r, err := service.Create(context.Background(), createRequest)
 		assert.NotEmpty(t, r)
 		assert.Empty(t, err)
+		tx.AssertExpectations(t)
 	})
+
+	t.Run("Must Return Transaction Error", func(t *testing.T) {
+		repo.On("Transaction", mock.Anything, mock.Anything).Return(errors.New("failed")).Once()
+		r, err := service.Create(context.Background(), &risk.CreateRiskRequest{State: "open", Title: "t", Description: "d"})
+		assert.Nil(t, r)
+		assert.EqualError(t, err, "failed")
+	})
+}
+
+func TestServiceCreateWritesOutbox(t *testing.T) {
+	store := risk.NewStore(log.New())
+	service := risk.NewService(store, log.New())
+
+	created, err := service.Create(context.Background(), &risk.CreateRiskRequest{State: "open", Title: "t", Description: "d"})
+	assert.NoError(t, err)
+
+	events, err := store.PendingEvents(context.Background(), 0, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, risk.EventCreated, events[0].Type)
+		assert.NotEmpty(t, events[0].ID)
+		assert.Equal(t, created, events[0].Risk)
+	}
+}
+
+func TestServiceCreateTags(t *testing.T) {
+	service := risk.NewService(risk.NewStore(log.New()), log.New())
+	ctx := context.Background()
+
+	created, err := service.Create(ctx, &risk.CreateRiskRequest{
+		State: "open", Title: "t", Description: "d", Tags: []string{" Water", "water", "", "leak"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"water", "leak"}, created.Tags, "the tags are normalized")
+
+	tooMany := make([]string, 21)
+	for i := range tooMany {
+		tooMany[i] = strconv.Itoa(i)
+	}
+	_, err = service.Create(ctx, &risk.CreateRiskRequest{State: "open", Title: "t", Description: "d", Tags: tooMany})
+	assert.ErrorContains(t, err, "tags: the length must be no more than 20")
+	_, err = service.Create(ctx, &risk.CreateRiskRequest{State: "open", Title: "t", Description: "d", Tags: []string{strings.Repeat("a", 33)}})
+	assert.ErrorContains(t, err, "tags: the length of a tag must be no more than 32")
 }
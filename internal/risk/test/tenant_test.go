@@ -0,0 +1,95 @@
+package risktest
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"github.com/vikasgithub/risky-plumbers/internal/tenant"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServiceIsolatesTenants(t *testing.T) {
+	service := risk.NewService(risk.NewStore(log.New()), log.New())
+	acme := tenant.WithTenant(context.Background(), "acme")
+	globex := tenant.WithTenant(context.Background(), "globex")
+
+	created, err := service.Create(acme, &risk.CreateRiskRequest{State: "open", Title: "t", Description: "d"})
+	assert.NoError(t, err)
+
+	_, err = service.Get(globex, created.ID)
+	assert.ErrorIs(t, err, errorstype.ErrRecordNotFound)
+	_, err = service.Get(context.Background(), created.ID)
+	assert.ErrorIs(t, err, errorstype.ErrRecordNotFound, "the default tenant is a tenant like the others")
+	many, err := service.GetMany(globex, []string{created.ID})
+	assert.NoError(t, err)
+	assert.Empty(t, many)
+	all, err := service.GetAll(globex, 0, 100)
+	assert.NoError(t, err)
+	assert.Empty(t, all)
+	exported, err := service.Export(globex)
+	assert.NoError(t, err)
+	assert.Empty(t, exported)
+
+	found, err := service.Get(acme, created.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, created, found)
+	exported, err = service.Export(acme)
+	assert.NoError(t, err)
+	assert.Equal(t, created, exported[0])
+}
+
+func TestTenantLimits(t *testing.T) {
+	service := risk.NewService(risk.NewStore(log.New()), log.New(), risk.WithTenantLimits(map[string]risk.Limits{
+		"acme": {MaxTitleLength: 5, States: []string{"new", "done"}},
+	}))
+	acme := tenant.WithTenant(context.Background(), "acme")
+
+	_, err := service.Create(acme, &risk.CreateRiskRequest{State: "new", Title: "too long", Description: "d"})
+	assert.ErrorContains(t, err, "title: the length must be no more than 5")
+	_, err = service.Create(acme, &risk.CreateRiskRequest{State: "open", Title: "t", Description: "d"})
+	assert.ErrorContains(t, err, "state: must be a valid value")
+	created, err := service.Create(acme, &risk.CreateRiskRequest{State: "new", Title: "t", Description: "d"})
+	assert.NoError(t, err)
+	assert.Equal(t, "new", created.State)
+
+	// the other tenants keep the default limits
+	_, err = service.Create(context.Background(), &risk.CreateRiskRequest{State: "new", Title: "too long", Description: "d"})
+	assert.ErrorContains(t, err, "state: must be a valid value")
+	assert.NotContains(t, err.Error(), "title")
+}
+
+func TestExportAPI(t *testing.T) {
+	service := risk.NewService(risk.NewStore(log.New()), log.New())
+	_, err := service.Create(tenant.WithTenant(context.Background(), "acme"),
+		&risk.CreateRiskRequest{State: "open", Title: "t", Description: "d"})
+	assert.NoError(t, err)
+	router := chi.NewRouter()
+	router.Use(tenant.Middleware())
+	risk.RegisterHandlers(router, service, log.New())
+
+	export := func(tenantID string) risk.ExportResponse {
+		rq, _ := http.NewRequest("GET", "/risks/export", nil)
+		rq.Header.Set(tenant.Header, tenantID)
+		rs := httptest.NewRecorder()
+		router.ServeHTTP(rs, rq)
+		assert.Equal(t, http.StatusOK, rs.Result().StatusCode)
+		assert.Equal(t, `attachment; filename="risks-`+tenantID+`.json"`, rs.Header().Get("Content-Disposition"))
+		var body risk.ExportResponse
+		assert.NoError(t, json.Unmarshal(rs.Body.Bytes(), &body))
+		return body
+	}
+
+	acme := export("acme")
+	assert.Equal(t, "acme", acme.TenantID)
+	assert.Len(t, acme.Risks, 1)
+	globex := export("globex")
+	assert.Equal(t, "globex", globex.TenantID)
+	assert.NotNil(t, globex.Risks)
+	assert.Empty(t, globex.Risks)
+}
@@ -0,0 +1,95 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"github.com/vikasgithub/risky-plumbers/internal/entity"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attributes of the spans
+const (
+	AttributeTenantID = attribute.Key("riskyplumbers.tenant.id")
+	AttributeRiskID   = attribute.Key("riskyplumbers.risk.id")
+)
+
+// tracedRepository traces the calls of the service to the repository with client spans
+type tracedRepository struct {
+	Repository
+	tenantID string
+}
+
+// startCall starts the span of a call to the repository
+func (r *tracedRepository) startCall(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	attributes = append(attributes, AttributeTenantID.String(r.tenantID))
+	return tracing.Tracer().Start(ctx, "risk.Repository/"+name,
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+}
+
+// endCall ends the span of a call, a missing record is not an error of the repository
+func endCall(span trace.Span, err error) {
+	if errors.Is(err, errorstype.ErrRecordNotFound) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
+
+func (r *tracedRepository) Get(ctx context.Context, id string) (*entity.Risk, error) {
+	ctx, span := r.startCall(ctx, "Get", AttributeRiskID.String(id))
+	risk, err := r.Repository.Get(ctx, id)
+	endCall(span, err)
+	return risk, err
+}
+
+func (r *tracedRepository) GetMany(ctx context.Context, ids []string) (map[string]*entity.Risk, error) {
+	ctx, span := r.startCall(ctx, "GetMany", attribute.Int("riskyplumbers.risk.count", len(ids)))
+	risks, err := r.Repository.GetMany(ctx, ids)
+	endCall(span, err)
+	return risks, err
+}
+
+func (r *tracedRepository) Query(ctx context.Context, offset, limit int) ([]*entity.Risk, error) {
+	ctx, span := r.startCall(ctx, "Query",
+		attribute.Int("riskyplumbers.query.offset", offset), attribute.Int("riskyplumbers.query.limit", limit))
+	risks, err := r.Repository.Query(ctx, offset, limit)
+	endCall(span, err)
+	return risks, err
+}
+
+func (r *tracedRepository) Create(ctx context.Context, risk *entity.Risk) error {
+	ctx, span := r.startCall(ctx, "Create", AttributeRiskID.String(risk.ID))
+	err := r.Repository.Create(ctx, risk)
+	endCall(span, err)
+	return err
+}
+
+func (r *tracedRepository) Comments(ctx context.Context, riskIDs []string) (map[string][]*entity.Comment, error) {
+	ctx, span := r.startCall(ctx, "Comments", attribute.Int("riskyplumbers.risk.count", len(riskIDs)))
+	comments, err := r.Repository.Comments(ctx, riskIDs)
+	endCall(span, err)
+	return comments, err
+}
+
+func (r *tracedRepository) Mitigations(ctx context.Context, riskIDs []string) (map[string][]*entity.Mitigation, error) {
+	ctx, span := r.startCall(ctx, "Mitigations", attribute.Int("riskyplumbers.risk.count", len(riskIDs)))
+	mitigations, err := r.Repository.Mitigations(ctx, riskIDs)
+	endCall(span, err)
+	return mitigations, err
+}
+
+func (r *tracedRepository) History(ctx context.Context, riskIDs []string) (map[string][]Event, error) {
+	ctx, span := r.startCall(ctx, "History", attribute.Int("riskyplumbers.risk.count", len(riskIDs)))
+	history, err := r.Repository.History(ctx, riskIDs)
+	endCall(span, err)
+	return history, err
+}
+
+func (r *tracedRepository) Transaction(ctx context.Context, fn func(tx Tx) error) error {
+	ctx, span := r.startCall(ctx, "Transaction")
+	err := r.Repository.Transaction(ctx, fn)
+	endCall(span, err)
+	return err
+}
@@ -0,0 +1,103 @@
+// Package tenant resolves the tenant a request acts on. The risks of the tenants are kept apart by risk.Store.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-chi/render"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"net/http"
+	"regexp"
+)
+
+// Default is the tenant of the requests which do not name one
+const Default = "default"
+
+// Header names the tenant of the requests which are not authenticated
+const Header = "X-Tenant-ID"
+
+var (
+	// ErrInvalid is returned for a tenant id not matching the allowed format
+	ErrInvalid = errors.New("invalid tenant id")
+	// ErrMismatch is returned when the tenant header names another tenant than the one of the principal
+	ErrMismatch = errors.New("the tenant header does not match the tenant of the credentials")
+)
+
+// idPattern allows lowercase ids only, as the ids are also keys of the configuration which are case-insensitive
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// Valid returns true if id is a well formed tenant id.
+func Valid(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// Resolve returns the tenant of a request made by principal, nil when the request is not authenticated, with the
+// given tenant header. An authenticated request acts on the tenant of its principal, or the Default tenant when
+// the principal has none, and the header may only repeat it. The header selects the tenant of the requests which
+// are not authenticated.
+func Resolve(principal *auth.Principal, header string) (string, error) {
+	if header != "" && !Valid(header) {
+		return "", fmt.Errorf("%w: %q", ErrInvalid, header)
+	}
+	if principal != nil {
+		id := principal.Tenant
+		if id == "" {
+			id = Default
+		}
+		if !Valid(id) {
+			return "", fmt.Errorf("%w: %q", ErrInvalid, id)
+		}
+		if header != "" && header != id {
+			return "", ErrMismatch
+		}
+		return id, nil
+	}
+	if header != "" {
+		return header, nil
+	}
+	return Default, nil
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx acting on the tenant.
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext returns the tenant of ctx, the Default tenant if none was resolved.
+func FromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(tenantKey{}).(string); ok {
+		return id
+	}
+	return Default
+}
+
+// Middleware puts the tenant of the request into the request context. It must run after the auth middleware.
+// A malformed tenant header gets a 400 and a header naming another tenant than the principal a 403.
+func Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, _ := auth.PrincipalFrom(r.Context())
+			id, err := Resolve(principal, r.Header.Get(Header))
+			if err != nil {
+				if errors.Is(err, ErrMismatch) {
+					render.Render(w, r, &errorstype.ErrResponse{
+						Err:            err,
+						HTTPStatusCode: http.StatusForbidden,
+						StatusText:     "Forbidden.",
+						ErrorText:      err.Error(),
+					})
+				} else {
+					render.Render(w, r, errorstype.ErrInvalidRequest(err))
+				}
+				return
+			}
+			log.AddAccessFields(r.Context(), "tenantId", id)
+			next.ServeHTTP(w, r.WithContext(WithTenant(r.Context(), id)))
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package tenanttest
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"github.com/vikasgithub/risky-plumbers/internal/tenant"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		header    string
+		tenant    string
+		err       error
+	}{
+		{name: "Default", tenant: tenant.Default},
+		{name: "Header", header: "acme", tenant: "acme"},
+		{name: "Invalid Header", header: "Acme Corp", err: tenant.ErrInvalid},
+		{name: "Principal", principal: &auth.Principal{Tenant: "acme"}, tenant: "acme"},
+		{name: "Principal Without Tenant", principal: &auth.Principal{}, tenant: tenant.Default},
+		{name: "Header Repeating The Principal", principal: &auth.Principal{Tenant: "acme"}, header: "acme", tenant: "acme"},
+		{name: "Header Naming Another Tenant", principal: &auth.Principal{Tenant: "acme"}, header: "globex", err: tenant.ErrMismatch},
+		{name: "Principal Without Tenant And Header", principal: &auth.Principal{}, header: "globex", err: tenant.ErrMismatch},
+		{name: "Invalid Principal Tenant", principal: &auth.Principal{Tenant: "ACME"}, err: tenant.ErrInvalid},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			id, err := tenant.Resolve(test.principal, test.header)
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.tenant, id)
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("as") == "acme" {
+				r = r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{Subject: "acme", Tenant: "acme"}))
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	router.Use(tenant.Middleware())
+	router.Get("/tenant", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(tenant.FromContext(r.Context())))
+	})
+	get := func(url, header string) *httptest.ResponseRecorder {
+		rq, _ := http.NewRequest("GET", url, nil)
+		if header != "" {
+			rq.Header.Set(tenant.Header, header)
+		}
+		rs := httptest.NewRecorder()
+		router.ServeHTTP(rs, rq)
+		return rs
+	}
+
+	assert.Equal(t, tenant.Default, get("/tenant", "").Body.String())
+	assert.Equal(t, "globex", get("/tenant", "globex").Body.String())
+	assert.Equal(t, "acme", get("/tenant?as=acme", "").Body.String())
+	assert.Equal(t, http.StatusForbidden, get("/tenant?as=acme", "globex").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/tenant", "../acme").Code)
+}
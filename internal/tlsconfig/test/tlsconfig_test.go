@@ -0,0 +1,274 @@
+package tlsconfigtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/tlsconfig"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// certificate is a certificate generated by the test with its key
+type certificate struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+var serial int64
+
+// issue generates a certificate signed by parent, or a self-signed CA when parent is nil
+func issue(t *testing.T, subject pkix.Name, parent *certificate, usage x509.ExtKeyUsage) *certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		template.ExtKeyUsage = nil
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	return &certificate{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func (c *certificate) pair(t *testing.T) tls.Certificate {
+	pair, err := tls.X509KeyPair(c.certPEM, c.keyPEM)
+	assert.NoError(t, err)
+	return pair
+}
+
+// pki is the certificates of a test written to a temporary directory
+type pki struct {
+	dir      string
+	ca       *certificate
+	server   *certificate
+	clientCA *certificate
+}
+
+func newPKI(t *testing.T) *pki {
+	p := &pki{dir: t.TempDir()}
+	p.ca = issue(t, pkix.Name{CommonName: "server ca"}, nil, 0)
+	p.server = issue(t, pkix.Name{CommonName: "localhost"}, p.ca, x509.ExtKeyUsageServerAuth)
+	p.clientCA = issue(t, pkix.Name{CommonName: "client ca"}, nil, 0)
+	p.write(t, "server.crt", p.server.certPEM)
+	p.write(t, "server.key", p.server.keyPEM)
+	p.write(t, "clients.crt", p.clientCA.certPEM)
+	return p
+}
+
+func (p *pki) write(t *testing.T, name string, data []byte) {
+	assert.NoError(t, os.WriteFile(p.path(name), data, 0o600))
+}
+
+func (p *pki) path(name string) string {
+	return filepath.Join(p.dir, name)
+}
+
+func (p *pki) config() tlsconfig.Config {
+	return tlsconfig.Config{
+		CertFile:       p.path("server.crt"),
+		KeyFile:        p.path("server.key"),
+		MinVersion:     "1.2",
+		CipherPolicy:   tlsconfig.PolicyIntermediate,
+		ClientAuth:     tlsconfig.ClientAuthOptional,
+		ReloadInterval: time.Second,
+	}
+}
+
+// serve starts a TLS server responding with the subject of the principal authenticated by the client certificate
+func serve(t *testing.T, reloader *tlsconfig.Reloader) *httptest.Server {
+	authenticator, err := auth.NewClientCertificateAuthenticator([]auth.ClientCertificate{
+		{Subject: "ci", Roles: []string{"viewer"}},
+	})
+	assert.NoError(t, err)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := authenticator.Authenticate(r.Context(), auth.CredentialsFromRequest(r))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(principal.Subject))
+	}))
+	server.TLS = reloader.TLSConfig()
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func client(roots *certificate, config *tls.Config) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(roots.cert)
+	config.RootCAs = pool
+	transport := &http.Transport{TLSClientConfig: config, ForceAttemptHTTP2: true}
+	return &http.Client{Transport: transport, Timeout: 5 * time.Second}
+}
+
+func TestServesTheCertificate(t *testing.T) {
+	p := newPKI(t)
+	reloader, err := tlsconfig.New(p.config(), log.New())
+	assert.NoError(t, err)
+	server := serve(t, reloader)
+
+	rs, err := client(p.ca, &tls.Config{}).Get(server.URL)
+	if assert.NoError(t, err) {
+		defer rs.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, rs.StatusCode, "no client certificate was sent")
+		assert.Equal(t, p.server.cert.SerialNumber, rs.TLS.PeerCertificates[0].SerialNumber)
+		assert.Equal(t, "h2", rs.TLS.NegotiatedProtocol)
+	}
+}
+
+func TestReloadsTheChangedCertificate(t *testing.T) {
+	p := newPKI(t)
+	config := p.config()
+	config.ReloadInterval = 0
+	reloader, err := tlsconfig.New(config, log.New())
+	assert.NoError(t, err)
+	server := serve(t, reloader)
+	served := func() *big.Int {
+		conn, err := tls.Dial("tcp", server.Listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber
+	}
+	assert.Equal(t, p.server.cert.SerialNumber, served())
+
+	renewed := issue(t, pkix.Name{CommonName: "localhost"}, p.ca, x509.ExtKeyUsageServerAuth)
+	p.write(t, "server.crt", renewed.certPEM)
+	p.write(t, "server.key", renewed.keyPEM)
+	assert.Equal(t, renewed.cert.SerialNumber, served())
+
+	t.Run("Invalid Files", func(t *testing.T) {
+		p.write(t, "server.key", []byte("not a key"))
+		assert.Equal(t, renewed.cert.SerialNumber, served(), "the current certificate is kept")
+	})
+}
+
+func TestMutualTLS(t *testing.T) {
+	p := newPKI(t)
+	ci := issue(t, pkix.Name{CommonName: "ci", Organization: []string{"Acme"}}, p.clientCA, x509.ExtKeyUsageClientAuth)
+	unknown := issue(t, pkix.Name{CommonName: "unknown"}, p.clientCA, x509.ExtKeyUsageClientAuth)
+	untrusted := issue(t, pkix.Name{CommonName: "ci"}, issue(t, pkix.Name{CommonName: "other ca"}, nil, 0),
+		x509.ExtKeyUsageClientAuth)
+
+	config := p.config()
+	config.ClientCAFile = p.path("clients.crt")
+	reloader, err := tlsconfig.New(config, log.New())
+	assert.NoError(t, err)
+	server := serve(t, reloader)
+	get := func(certificate *certificate) (*http.Response, error) {
+		tlsConfig := &tls.Config{}
+		if certificate != nil {
+			// the certificate is sent even when the server does not accept its ca
+			pair := certificate.pair(t)
+			tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				return &pair, nil
+			}
+		}
+		return client(p.ca, tlsConfig).Get(server.URL)
+	}
+
+	rs, err := get(ci)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, rs.StatusCode)
+		rs.Body.Close()
+	}
+	rs, err = get(unknown)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnauthorized, rs.StatusCode)
+		rs.Body.Close()
+	}
+	_, err = get(untrusted)
+	assert.Error(t, err, "the handshake fails with a certificate of another ca")
+	rs, err = get(nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusUnauthorized, rs.StatusCode, "the client certificate is optional")
+		rs.Body.Close()
+	}
+
+	t.Run("Required", func(t *testing.T) {
+		config.ClientAuth = tlsconfig.ClientAuthRequired
+		reloader, err := tlsconfig.New(config, log.New())
+		assert.NoError(t, err)
+		server := serve(t, reloader)
+		_, err = client(p.ca, &tls.Config{}).Get(server.URL)
+		assert.Error(t, err)
+	})
+}
+
+func TestMinVersion(t *testing.T) {
+	p := newPKI(t)
+	config := p.config()
+	config.CipherPolicy = tlsconfig.PolicyModern
+	reloader, err := tlsconfig.New(config, log.New())
+	assert.NoError(t, err)
+	server := serve(t, reloader)
+
+	_, err = client(p.ca, &tls.Config{MaxVersion: tls.VersionTLS12}).Get(server.URL)
+	assert.Error(t, err, "the modern policy accepts tls 1.3 only")
+	rs, err := client(p.ca, &tls.Config{}).Get(server.URL)
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint16(tls.VersionTLS13), rs.TLS.Version)
+		rs.Body.Close()
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	p := newPKI(t)
+	for name, change := range map[string]func(*tlsconfig.Config){
+		"No Certificate":         func(c *tlsconfig.Config) { c.CertFile = "" },
+		"Missing Certificate":    func(c *tlsconfig.Config) { c.CertFile = p.path("missing.crt") },
+		"Old Version":            func(c *tlsconfig.Config) { c.MinVersion = "1.0" },
+		"Unknown Cipher Policy":  func(c *tlsconfig.Config) { c.CipherPolicy = "legacy" },
+		"Unknown Client Auth":    func(c *tlsconfig.Config) { c.ClientCAFile, c.ClientAuth = p.path("clients.crt"), "maybe" },
+		"Empty Client CA Bundle": func(c *tlsconfig.Config) { c.ClientCAFile = p.path("server.key") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := p.config()
+			change(&config)
+			_, err := tlsconfig.New(config, log.New())
+			assert.Error(t, err)
+		})
+	}
+}
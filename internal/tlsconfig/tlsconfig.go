@@ -0,0 +1,208 @@
+// Package tlsconfig serves TLS and mutual TLS with certificates which are reloaded when their files change.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Cipher policies, after the server side recommendations of Mozilla
+const (
+	// PolicyIntermediate accepts TLS 1.2 with the ECDHE AEAD cipher suites, and TLS 1.3
+	PolicyIntermediate = "intermediate"
+	// PolicyModern accepts TLS 1.3 only
+	PolicyModern = "modern"
+)
+
+// Client authentication modes, when a client CA bundle is configured
+const (
+	// ClientAuthOptional verifies the client certificates which are sent, the other callers authenticate otherwise
+	ClientAuthOptional = "optional"
+	// ClientAuthRequired rejects the connections without a verified client certificate
+	ClientAuthRequired = "required"
+)
+
+// intermediateCipherSuites are the TLS 1.2 cipher suites of PolicyIntermediate, TLS 1.3 suites are not configurable
+var intermediateCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+var versions = map[string]uint16{"1.2": tls.VersionTLS12, "1.3": tls.VersionTLS13}
+
+// Config configures the TLS of a server.
+type Config struct {
+	// CertFile and KeyFile are the PEM encoded certificate chain and private key of the server
+	CertFile string
+	KeyFile  string
+	// MinVersion is 1.2 or 1.3
+	MinVersion string
+	// CipherPolicy is PolicyIntermediate or PolicyModern
+	CipherPolicy string
+	// ClientCAFile is the PEM bundle of the CAs issuing the client certificates, mutual TLS is disabled without it
+	ClientCAFile string
+	// ClientAuth is ClientAuthOptional or ClientAuthRequired
+	ClientAuth string
+	// ReloadInterval is how often the files are checked for changes
+	ReloadInterval time.Duration
+}
+
+// Reloader provides the TLS configuration of a server. The files are checked for changes at most once per
+// reload interval during the handshakes, and the new certificates are used for the next connections. The
+// current certificates are kept while the changed files can not be loaded.
+type Reloader struct {
+	config     Config
+	minVersion uint16
+	clientAuth tls.ClientAuthType
+	logger     log.Logger
+
+	mu          sync.Mutex
+	certificate *tls.Certificate
+	clientCAs   *x509.CertPool
+	stamps      map[string]stamp
+	checkedAt   time.Time
+}
+
+// stamp identifies a version of a file
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// New validates the configuration and loads the certificates.
+func New(config Config, logger log.Logger) (*Reloader, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, errors.New("the certificate and the key files are required")
+	}
+	minVersion, ok := versions[config.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported minimum tls version %q, the versions are 1.2 and 1.3", config.MinVersion)
+	}
+	switch config.CipherPolicy {
+	case PolicyIntermediate:
+	case PolicyModern:
+		minVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unknown cipher policy %q, the policies are %s and %s", config.CipherPolicy,
+			PolicyIntermediate, PolicyModern)
+	}
+	r := &Reloader{config: config, minVersion: minVersion, clientAuth: tls.NoClientCert, logger: logger}
+	if config.ClientCAFile != "" {
+		switch config.ClientAuth {
+		case ClientAuthOptional:
+			r.clientAuth = tls.VerifyClientCertIfGiven
+		case ClientAuthRequired:
+			r.clientAuth = tls.RequireAndVerifyClientCert
+		default:
+			return nil, fmt.Errorf("unknown client authentication %q, the modes are %s and %s", config.ClientAuth,
+				ClientAuthOptional, ClientAuthRequired)
+		}
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TLSConfig returns the configuration to set as the TLSConfig of an http.Server or to pass to the TLS credentials
+// of a gRPC server. It offers HTTP/2 and HTTP/1.1.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: r.minVersion,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			certificate, clientCAs := r.current()
+			return &tls.Config{
+				MinVersion:   r.minVersion,
+				CipherSuites: intermediateCipherSuites,
+				NextProtos:   []string{"h2", "http/1.1"},
+				Certificates: []tls.Certificate{*certificate},
+				ClientAuth:   r.clientAuth,
+				ClientCAs:    clientCAs,
+			}, nil
+		},
+	}
+}
+
+// current returns the certificates, after reloading the files which changed
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.checkedAt) >= r.config.ReloadInterval {
+		r.checkedAt = time.Now()
+		if r.changed() {
+			if err := r.loadLocked(); err != nil {
+				r.logger.Errorf("failed to reload the tls certificates, the current ones are kept: %v", err)
+			} else {
+				r.logger.Infof("reloaded the tls certificates of %s", r.config.CertFile)
+			}
+		}
+	}
+	return r.certificate, r.clientCAs
+}
+
+// changed reports whether one of the files changed since they were loaded
+func (r *Reloader) changed() bool {
+	for file, loaded := range r.stamps {
+		info, err := os.Stat(file)
+		if err != nil {
+			// the file is being replaced, it is checked again after the interval
+			continue
+		}
+		if (stamp{info.ModTime(), info.Size()}) != loaded {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reloader) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkedAt = time.Now()
+	return r.loadLocked()
+}
+
+// loadLocked loads all the files, nothing is replaced unless they can all be loaded
+func (r *Reloader) loadLocked() error {
+	files := []string{r.config.CertFile, r.config.KeyFile}
+	if r.config.ClientCAFile != "" {
+		files = append(files, r.config.ClientCAFile)
+	}
+	// the stamps are taken first, so that a file changing while it is loaded is loaded again
+	stamps := make(map[string]stamp, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		stamps[file] = stamp{info.ModTime(), info.Size()}
+	}
+	certificate, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load the certificate %s: %w", r.config.CertFile, err)
+	}
+	var clientCAs *x509.CertPool
+	if r.config.ClientCAFile != "" {
+		data, err := os.ReadFile(r.config.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certificate found in the client ca bundle %s", r.config.ClientCAFile)
+		}
+	}
+	r.certificate, r.clientCAs, r.stamps = &certificate, clientCAs, stamps
+	return nil
+}
@@ -0,0 +1,105 @@
+package tracingtest
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/vikasgithub/risky-plumbers/internal/entity"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"github.com/vikasgithub/risky-plumbers/internal/tracing"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// install records the spans ended by the test
+func install(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracing.Install(provider)
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	return recorder
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+	return names
+}
+
+func TestMiddlewareContinuesTheTraceOfTheCaller(t *testing.T) {
+	recorder := install(t)
+	router := chi.NewRouter()
+	router.Use(tracing.Middleware())
+	router.Get("/risks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	rq := httptest.NewRequest(http.MethodGet, "/risks/1", nil)
+	rq.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), rq)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "GET /risks/{id}", spans[0].Name())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+	}
+}
+
+func TestSpansOfARequest(t *testing.T) {
+	recorder := install(t)
+	store := risk.NewStore(log.New())
+	store.ForTenant("default").Create(context.Background(), &entity.Risk{ID: "1", State: "open"})
+	router := chi.NewRouter()
+	router.Use(tracing.Middleware())
+	risk.RegisterHandlers(router, risk.NewService(store, log.New()), log.New())
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/risks/1", nil))
+
+	spans := recorder.Ended()
+	assert.Equal(t, []string{"risk.Repository/Get", "risk.Service/Get", "risk.resource/get", "GET /risks/{id}"}, spanNames(spans))
+	for i := 0; i < len(spans)-1; i++ {
+		assert.Equal(t, spans[i+1].SpanContext().SpanID(), spans[i].Parent().SpanID(), "%s is a child of %s", spans[i].Name(), spans[i+1].Name())
+	}
+}
+
+func TestSpansOfACreation(t *testing.T) {
+	recorder := install(t)
+	service := risk.NewService(risk.NewStore(log.New()), log.New())
+
+	_, err := service.Create(context.Background(), &risk.CreateRiskRequest{State: "open", Title: "t", Description: "d"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"risk.Service/Create.validate", "risk.Repository/Transaction", "risk.Repository/Get", "risk.Service/Create"},
+		spanNames(recorder.Ended()))
+
+	t.Run("Invalid Request", func(t *testing.T) {
+		created := len(recorder.Ended())
+		_, err := service.Create(context.Background(), &risk.CreateRiskRequest{State: "unknown"})
+		assert.Error(t, err)
+		spans := recorder.Ended()[created:]
+		assert.Equal(t, []string{"risk.Service/Create.validate", "risk.Service/Create"}, spanNames(spans))
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+	})
+}
+
+func TestEnd(t *testing.T) {
+	recorder := install(t)
+	_, span := tracing.Tracer().Start(context.Background(), "failing")
+	tracing.End(span, errors.New("boom"))
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Equal(t, "boom", spans[0].Status().Description)
+		assert.Len(t, spans[0].Events(), 1, "the error is recorded as an event")
+	}
+}
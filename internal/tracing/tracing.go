@@ -0,0 +1,111 @@
+// Package tracing traces the requests with OpenTelemetry, from the handlers through the service to the repository.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// Protocols of the OTLP exporter
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// Config configures the tracer provider created by NewProvider.
+type Config struct {
+	ServiceName string
+	// Exporter is otlp or stdout
+	Exporter string
+	// Protocol is the protocol of the otlp exporter, grpc or http
+	Protocol string
+	// Endpoint is the host:port of the otlp collector, the default one of the protocol when empty
+	Endpoint string
+	// Insecure sends the spans to the collector without TLS
+	Insecure bool
+	// SampleRatio is the ratio of the new traces which are sampled, the traces started by the callers follow
+	// their sampling decision
+	SampleRatio float64
+}
+
+// NewProvider creates a tracer provider exporting the spans in batches. It must be shut down to flush the last
+// spans.
+func NewProvider(ctx context.Context, cfg Config) (*sdktrace.TracerProvider, error) {
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewProviderWithExporter(cfg, exporter), nil
+}
+
+// NewProviderWithExporter creates a tracer provider exporting the spans in batches with exporter.
+func NewProviderWithExporter(cfg Config, exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName))),
+	)
+}
+
+func newExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case ExporterStdout:
+		return stdouttrace.New()
+	case ExporterOTLP:
+		switch cfg.Protocol {
+		case ProtocolGRPC:
+			var opts []otlptracegrpc.Option
+			if cfg.Endpoint != "" {
+				opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+			}
+			if cfg.Insecure {
+				opts = append(opts, otlptracegrpc.WithInsecure())
+			}
+			return otlptracegrpc.New(ctx, opts...)
+		case ProtocolHTTP:
+			var opts []otlptracehttp.Option
+			if cfg.Endpoint != "" {
+				opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+			}
+			if cfg.Insecure {
+				opts = append(opts, otlptracehttp.WithInsecure())
+			}
+			return otlptracehttp.New(ctx, opts...)
+		default:
+			return nil, fmt.Errorf("unknown otlp protocol %q, it is grpc or http", cfg.Protocol)
+		}
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, it is otlp or stdout", cfg.Exporter)
+	}
+}
+
+// Install makes provider the global tracer provider and propagates the W3C trace context and baggage.
+func Install(provider trace.TracerProvider) {
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// End records err on the span, if any, and ends the span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
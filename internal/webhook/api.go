@@ -0,0 +1,164 @@
+package webhook
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"github.com/vikasgithub/risky-plumbers/internal/entity"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/i18n"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/request"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"github.com/vikasgithub/risky-plumbers/internal/tenant"
+	"net/http"
+	"time"
+)
+
+type resource struct {
+	store  Store
+	logger log.Logger
+}
+
+type SubscriptionResponse struct {
+	*Subscription
+}
+
+func (sr *SubscriptionResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+type DeliveryResponse struct {
+	*Delivery
+}
+
+func (dr *DeliveryResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func newSubscriptionListResponse(subscriptions []*Subscription) []render.Renderer {
+	list := []render.Renderer{}
+	for _, subscription := range subscriptions {
+		// never return the secret after the subscription was created
+		withoutSecret := *subscription
+		withoutSecret.Secret = ""
+		list = append(list, &SubscriptionResponse{&withoutSecret})
+	}
+	return list
+}
+
+func newDeliveryListResponse(deliveries []*Delivery) []render.Renderer {
+	list := []render.Renderer{}
+	for _, delivery := range deliveries {
+		list = append(list, &DeliveryResponse{delivery})
+	}
+	return list
+}
+
+// RegisterHandlers registers the endpoints managing the webhook subscriptions under /webhooks. They require
+// auth.ActionManageWebhooks, since a subscription receives every event of the tenant.
+// The subscriptions belong to the tenant of the request creating them.
+func RegisterHandlers(r chi.Router, store Store, service risk.Service, logger log.Logger) {
+	res := resource{store, logger}
+
+	r.With(risk.Authorized(service, auth.ActionManageWebhooks)).Group(func(r chi.Router) {
+		r.Post("/webhooks", res.create)
+		r.Get("/webhooks", res.list)
+		r.Get("/webhooks/dead-letters", res.deadLetters)
+		r.Get("/webhooks/{id}", res.get)
+		r.Delete("/webhooks/{id}", res.delete)
+		r.Get("/webhooks/{id}/deliveries", res.deliveries)
+	})
+}
+
+func (res resource) create(w http.ResponseWriter, r *http.Request) {
+	createRequest := &CreateSubscriptionRequest{}
+	if err := request.BindJSON(r, createRequest); err != nil {
+		render.Render(w, r, errorstype.ErrBind(err))
+		return
+	}
+	if err := createRequest.Validate(); err != nil {
+		render.Render(w, r, errorstype.ErrValidation(err, i18n.RequestLanguage(r)))
+		return
+	}
+	subscription := &Subscription{
+		ID:         entity.GenerateID(),
+		TenantID:   tenant.FromContext(r.Context()),
+		URL:        createRequest.URL,
+		Secret:     createRequest.Secret,
+		EventTypes: createRequest.EventTypes,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if subscription.Secret == "" {
+		subscription.Secret = generateSecret()
+	}
+	if subscription.EventTypes == nil {
+		subscription.EventTypes = []string{}
+	}
+	if err := res.store.CreateSubscription(r.Context(), subscription); err != nil {
+		render.Render(w, r, errorstype.ErrInternal(err))
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.Render(w, r, &SubscriptionResponse{subscription})
+}
+
+func (res resource) list(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := res.store.ListSubscriptions(r.Context(), tenant.FromContext(r.Context()))
+	if err != nil {
+		render.Render(w, r, errorstype.ErrInternal(err))
+		return
+	}
+	render.RenderList(w, r, newSubscriptionListResponse(subscriptions))
+}
+
+func (res resource) get(w http.ResponseWriter, r *http.Request) {
+	subscription, err := res.store.GetSubscription(r.Context(), tenant.FromContext(r.Context()), chi.URLParam(r, "id"))
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+	withoutSecret := *subscription
+	withoutSecret.Secret = ""
+	render.Render(w, r, &SubscriptionResponse{&withoutSecret})
+}
+
+func (res resource) delete(w http.ResponseWriter, r *http.Request) {
+	if err := res.store.DeleteSubscription(r.Context(), tenant.FromContext(r.Context()), chi.URLParam(r, "id")); err != nil {
+		renderError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (res resource) deliveries(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := res.store.GetSubscription(r.Context(), tenant.FromContext(r.Context()), id); err != nil {
+		renderError(w, r, err)
+		return
+	}
+	deliveries, err := res.store.ListDeliveries(r.Context(), id)
+	if err != nil {
+		render.Render(w, r, errorstype.ErrInternal(err))
+		return
+	}
+	render.RenderList(w, r, newDeliveryListResponse(deliveries))
+}
+
+func (res resource) deadLetters(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := res.store.DeadLetters(r.Context(), tenant.FromContext(r.Context()))
+	if err != nil {
+		render.Render(w, r, errorstype.ErrInternal(err))
+		return
+	}
+	render.RenderList(w, r, newDeliveryListResponse(deliveries))
+}
+
+func renderError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errorstype.ErrRecordNotFound) {
+		render.Render(w, r, errorstype.ErrResponseNotFound())
+		return
+	}
+	render.Render(w, r, errorstype.ErrInternal(err))
+}
@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// reservedNetworks are the special purpose ranges which are not covered by the methods of net.IP
+var reservedNetworks = mustParseNetworks(
+	"0.0.0.0/8",       // this network
+	"100.64.0.0/10",   // carrier grade NAT
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // documentation
+	"198.18.0.0/15",   // benchmarking
+	"198.51.100.0/24", // documentation
+	"203.0.113.0/24",  // documentation
+	"240.0.0.0/4",     // reserved, including the broadcast address
+	"64:ff9b::/96",    // NAT64, it reaches the IPv4 addresses behind the translator
+	"2001:db8::/32",   // documentation
+)
+
+func mustParseNetworks(cidrs ...string) []*net.IPNet {
+	networks, err := parseNetworks(cidrs)
+	if err != nil {
+		panic(err)
+	}
+	return networks
+}
+
+func parseNetworks(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %w", cidr, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// public reports whether ip is a public unicast address
+func public(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// newClient returns the client sending the deliveries. The subscription urls are chosen by the tenants, so the
+// client only connects to public addresses and the allowed networks, checked once the host name is resolved,
+// and it does not follow redirects. The proxy of the environment is not used, it would bypass the check.
+func newClient(config Config) (*http.Client, error) {
+	allowed, err := parseNetworks(config.AllowedNetworks)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{
+		Timeout: config.Timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return fmt.Errorf("refusing to connect to %s, it is not an ip address", host)
+			}
+			if public(ip) {
+				return nil
+			}
+			for _, network := range allowed {
+				if network.Contains(ip) {
+					return nil
+				}
+			}
+			return fmt.Errorf("refusing to connect to the non-public address %s", ip)
+		},
+	}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &http.Client{
+		Timeout:   config.Timeout,
+		Transport: transport,
+		// a redirect is a failed attempt, a public receiver could redirect to an internal address
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}, nil
+}
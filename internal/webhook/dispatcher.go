@@ -0,0 +1,309 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/vikasgithub/risky-plumbers/internal/entity"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderEventType = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+)
+
+// Config configures the delivery of the webhooks.
+type Config struct {
+	// Workers is the number of deliveries sent concurrently
+	Workers int
+	// QueueSize is the number of deliveries waiting for a worker, the deliveries published while the queue is full
+	// are queued again after BaseDelay
+	QueueSize int
+	// MaxAttempts is the number of attempts before a delivery goes to the dead-letter queue
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, it doubles with every retry up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Timeout limits the duration of a single attempt
+	Timeout time.Duration
+	// AllowedNetworks are the CIDRs of the internal receivers, the deliveries only reach public addresses otherwise
+	AllowedNetworks []string
+}
+
+// Sign returns the signature sent in the X-Webhook-Signature header. Receivers verify a delivery by computing
+// the HMAC-SHA256 of the timestamp header, a dot and the raw body using the subscription secret.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher is a risk.Publisher delivering the events to the matching subscriptions in the background.
+type Dispatcher struct {
+	store  Store
+	config Config
+	client *http.Client
+	logger log.Logger
+
+	queue chan *Delivery
+	// done stops the workers once they drained the queue, aborted stops them at once
+	done      chan struct{}
+	aborted   chan struct{}
+	wg        sync.WaitGroup
+	once      sync.Once
+	abortOnce sync.Once
+
+	// mu guards retries, the deliveries waiting for their next attempt or for room in the queue. It is nil once the
+	// dispatcher is closed.
+	mu      sync.Mutex
+	retries map[*Delivery]*time.Timer
+}
+
+// NewDispatcher creates a dispatcher and starts its workers. Close stops them.
+func NewDispatcher(store Store, config Config, logger log.Logger) (*Dispatcher, error) {
+	client, err := newClient(config)
+	if err != nil {
+		return nil, err
+	}
+	d := &Dispatcher{
+		store:   store,
+		config:  config,
+		client:  client,
+		logger:  logger,
+		queue:   make(chan *Delivery, config.QueueSize),
+		done:    make(chan struct{}),
+		aborted: make(chan struct{}),
+		retries: map[*Delivery]*time.Timer{},
+	}
+	for i := 0; i < config.Workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+	return d, nil
+}
+
+// Publish creates a delivery for every subscription of the tenant of the event accepting the event type and
+// queues them.
+func (d *Dispatcher) Publish(ctx context.Context, event risk.Event) error {
+	subscriptions, err := d.store.ListSubscriptions(ctx, event.TenantID)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	for _, subscription := range subscriptions {
+		if !subscription.accepts(event.Type) {
+			continue
+		}
+		delivery := &Delivery{
+			ID:             entity.GenerateID(),
+			SubscriptionID: subscription.ID,
+			TenantID:       event.TenantID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			CorrelationID:  event.CorrelationID,
+			Status:         StatusPending,
+			Attempts:       []Attempt{},
+			CreatedAt:      time.Now().UTC(),
+			payload:        payload,
+		}
+		if err := d.store.SaveDelivery(ctx, delivery); err != nil {
+			return err
+		}
+		d.enqueue(delivery)
+	}
+	return nil
+}
+
+// enqueue never blocks, so that a slow receiver does not hold back the relay. When the queue is full, the delivery
+// stays pending in the store and is queued again after BaseDelay.
+func (d *Dispatcher) enqueue(delivery *Delivery) {
+	select {
+	case <-d.done:
+		return
+	default:
+	}
+	select {
+	case d.queue <- delivery:
+	default:
+		d.logger.Debugf("webhook queue is full, delivery %s is queued again in %v", delivery.ID, d.config.BaseDelay)
+		d.retryAfter(delivery, d.config.BaseDelay)
+	}
+}
+
+// Close stops scheduling retries and waits for the workers to send the queued deliveries, or for ctx to be done.
+// The deliveries waiting for a retry get their last attempt at once. The deliveries which are not sent once ctx
+// is done remain in the pending or retrying status.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.once.Do(func() {
+		d.mu.Lock()
+		var waiting []*Delivery
+		for delivery, timer := range d.retries {
+			if timer.Stop() {
+				waiting = append(waiting, delivery)
+			}
+		}
+		d.retries = nil
+		d.mu.Unlock()
+		for _, delivery := range waiting {
+			select {
+			case d.queue <- delivery:
+			default:
+			}
+		}
+		close(d.done)
+	})
+	stopped := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		d.abortOnce.Do(func() { close(d.aborted) })
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.done:
+			d.drain()
+			return
+		case delivery := <-d.queue:
+			d.deliver(delivery)
+		}
+	}
+}
+
+// drain sends the queued deliveries until the queue is empty or the shutdown is aborted
+func (d *Dispatcher) drain() {
+	for {
+		select {
+		case <-d.aborted:
+			return
+		default:
+		}
+		select {
+		case delivery := <-d.queue:
+			d.deliver(delivery)
+		default:
+			return
+		}
+	}
+}
+
+// retryAfter enqueues the delivery again after the delay, unless the dispatcher is closed or the subscription deleted
+func (d *Dispatcher) retryAfter(delivery *Delivery, delay time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.retries == nil {
+		return
+	}
+	d.retries[delivery] = time.AfterFunc(delay, func() {
+		d.mu.Lock()
+		delete(d.retries, delivery)
+		d.mu.Unlock()
+		// the subscription was deleted while the delivery waited
+		if _, err := d.store.GetSubscription(context.Background(), delivery.TenantID, delivery.SubscriptionID); err != nil {
+			return
+		}
+		d.enqueue(delivery)
+	})
+}
+
+func (d *Dispatcher) deliver(delivery *Delivery) {
+	ctx := log.WithCorrelationID(context.Background(), delivery.CorrelationID)
+	subscription, err := d.store.GetSubscription(ctx, delivery.TenantID, delivery.SubscriptionID)
+	if err != nil {
+		// the subscription was deleted in the meantime
+		return
+	}
+
+	attempt := d.send(subscription, delivery)
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	delivery.NextAttemptAt = nil
+	switch {
+	case attempt.Error == "":
+		delivery.Status = StatusSucceeded
+	case len(delivery.Attempts) >= d.config.MaxAttempts:
+		delivery.Status = StatusDead
+		d.logger.WithContext(ctx).Warnf("webhook delivery %s to %s moved to the dead-letter queue: %s",
+			delivery.ID, subscription.URL, attempt.Error)
+	default:
+		delivery.Status = StatusRetrying
+		next := time.Now().Add(d.backoff(len(delivery.Attempts))).UTC()
+		delivery.NextAttemptAt = &next
+	}
+	if err := d.store.SaveDelivery(ctx, delivery); err != nil {
+		d.logger.WithContext(ctx).Errorf("failed to save webhook delivery %s: %v", delivery.ID, err)
+	}
+	// schedule the retry only once the delivery was saved, another worker may pick it up
+	if delivery.NextAttemptAt != nil {
+		d.retryAfter(delivery, time.Until(*delivery.NextAttemptAt))
+	}
+}
+
+func (d *Dispatcher) send(subscription *Subscription, delivery *Delivery) Attempt {
+	start := time.Now()
+	attempt := Attempt{Time: start.UTC()}
+	timestamp := strconv.FormatInt(start.Unix(), 10)
+
+	rq, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(delivery.payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	rq.Header.Set("Content-Type", "application/json")
+	rq.Header.Set(HeaderTimestamp, timestamp)
+	rq.Header.Set(HeaderSignature, Sign(subscription.Secret, timestamp, delivery.payload))
+	rq.Header.Set(HeaderEventType, delivery.EventType)
+	rq.Header.Set(HeaderDelivery, delivery.ID)
+	if delivery.CorrelationID != "" {
+		rq.Header.Set(log.HeaderCorrelationID, delivery.CorrelationID)
+	}
+
+	rs, err := d.client.Do(rq)
+	attempt.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer rs.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(rs.Body, 1<<16))
+	attempt.StatusCode = rs.StatusCode
+	if rs.StatusCode < 200 || rs.StatusCode >= 300 {
+		attempt.Error = fmt.Sprintf("unexpected status %d", rs.StatusCode)
+	}
+	return attempt
+}
+
+// backoff returns the delay after the given number of failed attempts
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.BaseDelay << (attempts - 1)
+	if delay > d.config.MaxDelay || delay <= 0 {
+		delay = d.config.MaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/openapi"
+	"net/http"
+)
+
+// Describe adds the operations registered by RegisterHandlers to the OpenAPI document.
+func Describe(doc *openapi.Document) {
+	subscriptionRef := doc.AddSchema("WebhookSubscription", openapi.SchemaOf(Subscription{}))
+	deliveryRef := doc.AddSchema("WebhookDelivery", openapi.SchemaOf(Delivery{}))
+	errRef := doc.AddSchema("ErrResponse", openapi.SchemaOf(errorstype.ErrResponse{}))
+
+	createSchema := openapi.SchemaOf(CreateSubscriptionRequest{})
+	createSchema.Required = []string{"url"}
+	createSchema.Properties["url"].Format = "uri"
+	createSchema.Properties["secret"].MinLength = intPtr(16)
+	createSchema.Properties["secret"].MaxLength = intPtr(256)
+	createSchema.Properties["eventTypes"].Items.Enum = eventTypes
+	createRef := doc.AddSchema("CreateWebhookSubscriptionRequest", createSchema)
+
+	idParam := openapi.Parameter{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "string", Format: "uuid"}}
+	notFound := openapi.JSON("Subscription not found", errRef)
+
+	doc.AddOperation(http.MethodPost, "/webhooks", &openapi.Operation{
+		OperationID: "createWebhookSubscription",
+		Summary:     "Subscribe to risk events. The secret is only returned in this response",
+		Tags:        []string{"webhooks"},
+		RequestBody: &openapi.RequestBody{
+			Required: true,
+			Content:  map[string]openapi.MediaType{"application/json": {Schema: createRef}},
+		},
+		Responses: map[string]openapi.Response{
+			"201": openapi.JSON("The created subscription", subscriptionRef),
+			"400": openapi.JSON("Invalid request", errRef),
+		},
+	})
+	doc.AddOperation(http.MethodGet, "/webhooks", &openapi.Operation{
+		OperationID: "listWebhookSubscriptions",
+		Summary:     "List the webhook subscriptions",
+		Tags:        []string{"webhooks"},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSON("The subscriptions", &openapi.Schema{Type: "array", Items: subscriptionRef}),
+		},
+	})
+	doc.AddOperation(http.MethodGet, "/webhooks/dead-letters", &openapi.Operation{
+		OperationID: "listWebhookDeadLetters",
+		Summary:     "List the deliveries which failed after all their attempts",
+		Tags:        []string{"webhooks"},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSON("The dead-lettered deliveries", &openapi.Schema{Type: "array", Items: deliveryRef}),
+		},
+	})
+	doc.AddOperation(http.MethodGet, "/webhooks/{id}", &openapi.Operation{
+		OperationID: "getWebhookSubscription",
+		Summary:     "Get a webhook subscription",
+		Tags:        []string{"webhooks"},
+		Parameters:  []openapi.Parameter{idParam},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSON("The subscription", subscriptionRef),
+			"404": notFound,
+		},
+	})
+	doc.AddOperation(http.MethodDelete, "/webhooks/{id}", &openapi.Operation{
+		OperationID: "deleteWebhookSubscription",
+		Summary:     "Delete a webhook subscription",
+		Tags:        []string{"webhooks"},
+		Parameters:  []openapi.Parameter{idParam},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Subscription deleted"},
+			"404": notFound,
+		},
+	})
+	doc.AddOperation(http.MethodGet, "/webhooks/{id}/deliveries", &openapi.Operation{
+		OperationID: "listWebhookDeliveries",
+		Summary:     "List the deliveries of a webhook subscription, the most recent first",
+		Tags:        []string{"webhooks"},
+		Parameters:  []openapi.Parameter{idParam},
+		Responses: map[string]openapi.Response{
+			"200": openapi.JSON("The deliveries", &openapi.Schema{Type: "array", Items: deliveryRef}),
+			"404": notFound,
+		},
+	})
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,257 @@
+// Package webhook notifies external systems about risk changes using signed HTTP callbacks.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+	errorstype "github.com/vikasgithub/risky-plumbers/internal/errors"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	// StatusRetrying is set after a failed attempt while attempts are left
+	StatusRetrying = "retrying"
+	// StatusDead is set once all the attempts failed, the delivery is then kept in the dead-letter queue
+	StatusDead = "dead"
+)
+
+// MaxDeliveries is the number of deliveries the memory store keeps per subscription. The oldest succeeded and dead
+// deliveries are dropped beyond it.
+const MaxDeliveries = 100
+
+// eventTypes lists the event types a subscription can filter on
+var eventTypes = []interface{}{risk.EventCreated, risk.EventUpdated}
+
+// Subscription registers a URL to be called when risks change.
+type Subscription struct {
+	ID string `json:"id"`
+	// TenantID is the tenant the subscription was created by, it is only notified about the risks of its tenant
+	TenantID string `json:"tenantId"`
+	URL      string `json:"url"`
+	// Secret is used to sign the payloads, it is only returned when the subscription is created
+	Secret string `json:"secret,omitempty"`
+	// EventTypes limits the notifications to the given event types, all events are sent when empty
+	EventTypes []string  `json:"eventTypes"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func (s *Subscription) accepts(eventType string) bool {
+	if len(s.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Attempt records a single try to deliver an event.
+type Attempt struct {
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"durationMs"`
+}
+
+// Delivery tracks the notification of one subscription about one event.
+type Delivery struct {
+	ID             string     `json:"id"`
+	SubscriptionID string     `json:"subscriptionId"`
+	TenantID       string     `json:"tenantId"`
+	EventID        string     `json:"eventId"`
+	EventType      string     `json:"eventType"`
+	CorrelationID  string     `json:"correlationId,omitempty"`
+	Status         string     `json:"status"`
+	Attempts       []Attempt  `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"nextAttemptAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	payload        []byte
+}
+
+type CreateSubscriptionRequest struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"eventTypes"`
+}
+
+func (cr *CreateSubscriptionRequest) Bind(r *http.Request) error {
+	cr.URL = strings.TrimSpace(cr.URL)
+	return nil
+}
+
+func (cr *CreateSubscriptionRequest) Validate() error {
+	return validation.ValidateStruct(cr,
+		validation.Field(&cr.URL,
+			errorstype.Coded(validation.Required, errorstype.CodeRequired, nil),
+			errorstype.Coded(is.RequestURL, errorstype.CodeInvalidURL, nil),
+			errorstype.Coded(validation.By(httpScheme), errorstype.CodeInvalidURL, nil)),
+		validation.Field(&cr.Secret,
+			errorstype.Coded(validation.Length(16, 256), errorstype.CodeLengthRange,
+				map[string]interface{}{"min": 16, "max": 256})),
+		validation.Field(&cr.EventTypes,
+			errorstype.Coded(validation.Each(validation.In(eventTypes...)), errorstype.CodeInvalidValue,
+				map[string]interface{}{"allowed": eventTypes})),
+	)
+}
+
+func httpScheme(value interface{}) error {
+	u, err := url.Parse(value.(string))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.New("must be a http or https URL")
+	}
+	return nil
+}
+
+// generateSecret returns a random secret used when the subscriber does not provide one
+func generateSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Store keeps the subscriptions and their deliveries. The subscriptions of a tenant are only reachable with
+// the id of the tenant.
+type Store interface {
+	CreateSubscription(ctx context.Context, subscription *Subscription) error
+	GetSubscription(ctx context.Context, tenantID, id string) (*Subscription, error)
+	ListSubscriptions(ctx context.Context, tenantID string) ([]*Subscription, error)
+	// DeleteSubscription deletes the subscription together with its deliveries
+	DeleteSubscription(ctx context.Context, tenantID, id string) error
+	// SaveDelivery creates or replaces a delivery. The deliveries of a deleted subscription are not kept.
+	SaveDelivery(ctx context.Context, delivery *Delivery) error
+	// ListDeliveries returns the deliveries of a subscription, the most recent first. The store may drop the oldest
+	// finished deliveries.
+	ListDeliveries(ctx context.Context, subscriptionID string) ([]*Delivery, error)
+	// DeadLetters returns the deliveries of the tenant which failed permanently, the most recent first
+	DeadLetters(ctx context.Context, tenantID string) ([]*Delivery, error)
+}
+
+type memoryStore struct {
+	subscriptions sync.Map
+	mu            sync.Mutex
+	deliveries    map[string]*Delivery
+	// history has the ids of the deliveries of each subscription, the oldest first
+	history map[string][]string
+}
+
+// NewMemoryStore returns a Store keeping the subscriptions and deliveries in memory.
+func NewMemoryStore() Store {
+	return &memoryStore{deliveries: map[string]*Delivery{}, history: map[string][]string{}}
+}
+
+func (s *memoryStore) CreateSubscription(ctx context.Context, subscription *Subscription) error {
+	s.subscriptions.Store(subscription.ID, subscription)
+	return nil
+}
+
+func (s *memoryStore) GetSubscription(ctx context.Context, tenantID, id string) (*Subscription, error) {
+	value, ok := s.subscriptions.Load(id)
+	if !ok || value.(*Subscription).TenantID != tenantID {
+		return nil, errorstype.ErrRecordNotFound
+	}
+	return value.(*Subscription), nil
+}
+
+func (s *memoryStore) ListSubscriptions(ctx context.Context, tenantID string) ([]*Subscription, error) {
+	var subscriptions []*Subscription
+	s.subscriptions.Range(func(key, value interface{}) bool {
+		if subscription := value.(*Subscription); subscription.TenantID == tenantID {
+			subscriptions = append(subscriptions, subscription)
+		}
+		return true
+	})
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+	})
+	return subscriptions, nil
+}
+
+func (s *memoryStore) DeleteSubscription(ctx context.Context, tenantID, id string) error {
+	if _, err := s.GetSubscription(ctx, tenantID, id); err != nil {
+		return err
+	}
+	s.subscriptions.Delete(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, deliveryID := range s.history[id] {
+		delete(s.deliveries, deliveryID)
+	}
+	delete(s.history, id)
+	return nil
+}
+
+func (s *memoryStore) SaveDelivery(ctx context.Context, delivery *Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// the subscription was deleted while the delivery was sent
+	if _, ok := s.subscriptions.Load(delivery.SubscriptionID); !ok {
+		return nil
+	}
+	// keep a copy so that readers never see a delivery being updated
+	stored := *delivery
+	stored.Attempts = append([]Attempt(nil), delivery.Attempts...)
+	if _, ok := s.deliveries[delivery.ID]; !ok {
+		s.history[delivery.SubscriptionID] = append(s.history[delivery.SubscriptionID], delivery.ID)
+	}
+	s.deliveries[delivery.ID] = &stored
+	s.prune(delivery.SubscriptionID)
+	return nil
+}
+
+// prune drops the oldest finished deliveries of the subscription beyond MaxDeliveries. The pending and retrying
+// deliveries are kept until they finish.
+func (s *memoryStore) prune(subscriptionID string) {
+	ids := s.history[subscriptionID]
+	excess := len(ids) - MaxDeliveries
+	if excess <= 0 {
+		return
+	}
+	kept := ids[:0]
+	for _, id := range ids {
+		if status := s.deliveries[id].Status; excess > 0 && (status == StatusSucceeded || status == StatusDead) {
+			delete(s.deliveries, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.history[subscriptionID] = kept
+}
+
+func (s *memoryStore) ListDeliveries(ctx context.Context, subscriptionID string) ([]*Delivery, error) {
+	return s.filter(func(d *Delivery) bool { return d.SubscriptionID == subscriptionID }), nil
+}
+
+func (s *memoryStore) DeadLetters(ctx context.Context, tenantID string) ([]*Delivery, error) {
+	return s.filter(func(d *Delivery) bool { return d.TenantID == tenantID && d.Status == StatusDead }), nil
+}
+
+func (s *memoryStore) filter(keep func(*Delivery) bool) []*Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deliveries := []*Delivery{}
+	for _, d := range s.deliveries {
+		if keep(d) {
+			deliveries = append(deliveries, d)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+	return deliveries
+}
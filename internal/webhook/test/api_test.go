@@ -0,0 +1,164 @@
+package webhooktest
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/openapi"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"github.com/vikasgithub/risky-plumbers/internal/tenant"
+	"github.com/vikasgithub/risky-plumbers/internal/webhook"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serve(router http.Handler, method, url, body string) *httptest.ResponseRecorder {
+	rq, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+	rq.Header.Set("Content-Type", "application/json")
+	rs := httptest.NewRecorder()
+	router.ServeHTTP(rs, rq)
+	return rs
+}
+
+func TestSubscriptionAPI(t *testing.T) {
+	router := chi.NewRouter()
+	webhook.RegisterHandlers(router, webhook.NewMemoryStore(), newService(), log.New())
+
+	rs := serve(router, "POST", "/webhooks", `{"url":"https://example.com/hook","eventTypes":["risk.created"]}`)
+	assert.Equal(t, http.StatusCreated, rs.Result().StatusCode)
+	var created webhook.Subscription
+	assert.NoError(t, json.Unmarshal(rs.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.ID)
+	assert.Len(t, created.Secret, 64)
+	assert.Equal(t, []string{"risk.created"}, created.EventTypes)
+
+	rs = serve(router, "GET", "/webhooks/"+created.ID, "")
+	assert.Equal(t, http.StatusOK, rs.Result().StatusCode)
+	assert.NotContains(t, rs.Body.String(), "secret")
+
+	rs = serve(router, "GET", "/webhooks", "")
+	assert.Equal(t, http.StatusOK, rs.Result().StatusCode)
+	assert.Contains(t, rs.Body.String(), created.ID)
+	assert.NotContains(t, rs.Body.String(), "secret")
+
+	rs = serve(router, "GET", "/webhooks/"+created.ID+"/deliveries", "")
+	assert.Equal(t, http.StatusOK, rs.Result().StatusCode)
+	assert.JSONEq(t, `[]`, rs.Body.String())
+
+	rs = serve(router, "GET", "/webhooks/dead-letters", "")
+	assert.Equal(t, http.StatusOK, rs.Result().StatusCode)
+	assert.JSONEq(t, `[]`, rs.Body.String())
+
+	rs = serve(router, "DELETE", "/webhooks/"+created.ID, "")
+	assert.Equal(t, http.StatusNoContent, rs.Result().StatusCode)
+
+	rs = serve(router, "GET", "/webhooks/"+created.ID, "")
+	assert.Equal(t, http.StatusNotFound, rs.Result().StatusCode)
+	rs = serve(router, "GET", "/webhooks/"+created.ID+"/deliveries", "")
+	assert.Equal(t, http.StatusNotFound, rs.Result().StatusCode)
+	rs = serve(router, "DELETE", "/webhooks/"+created.ID, "")
+	assert.Equal(t, http.StatusNotFound, rs.Result().StatusCode)
+}
+
+func TestSubscriptionsAreScopedToTheTenant(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(tenant.Middleware())
+	webhook.RegisterHandlers(router, webhook.NewMemoryStore(), newService(), log.New())
+	serveAs := func(tenantID, method, url, body string) *httptest.ResponseRecorder {
+		rq, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		rq.Header.Set("Content-Type", "application/json")
+		rq.Header.Set(tenant.Header, tenantID)
+		rs := httptest.NewRecorder()
+		router.ServeHTTP(rs, rq)
+		return rs
+	}
+
+	rs := serveAs("acme", "POST", "/webhooks", `{"url":"https://example.com/hook"}`)
+	assert.Equal(t, http.StatusCreated, rs.Result().StatusCode)
+	var created webhook.Subscription
+	assert.NoError(t, json.Unmarshal(rs.Body.Bytes(), &created))
+	assert.Equal(t, "acme", created.TenantID)
+
+	assert.Equal(t, http.StatusOK, serveAs("acme", "GET", "/webhooks/"+created.ID, "").Result().StatusCode)
+	assert.Equal(t, http.StatusNotFound, serveAs("globex", "GET", "/webhooks/"+created.ID, "").Result().StatusCode)
+	assert.Equal(t, http.StatusNotFound, serveAs("globex", "GET", "/webhooks/"+created.ID+"/deliveries", "").Result().StatusCode)
+	assert.Equal(t, http.StatusNotFound, serveAs("globex", "DELETE", "/webhooks/"+created.ID, "").Result().StatusCode)
+	assert.JSONEq(t, `[]`, serveAs("globex", "GET", "/webhooks", "").Body.String())
+	assert.Contains(t, serveAs("acme", "GET", "/webhooks", "").Body.String(), created.ID)
+}
+
+func TestCreateSubscriptionValidation(t *testing.T) {
+	router := chi.NewRouter()
+	webhook.RegisterHandlers(router, webhook.NewMemoryStore(), newService(), log.New())
+
+	rs := serve(router, "POST", "/webhooks", `{"url":"ftp://example.com","secret":"short","eventTypes":["risk.unknown"]}`)
+	assert.Equal(t, http.StatusBadRequest, rs.Result().StatusCode)
+	var body struct {
+		Errors []struct {
+			Field string `json:"field"`
+			Code  string `json:"code"`
+		} `json:"errors"`
+	}
+	assert.NoError(t, json.Unmarshal(rs.Body.Bytes(), &body))
+	codes := map[string]string{}
+	for _, fieldErr := range body.Errors {
+		codes[fieldErr.Field] = fieldErr.Code
+	}
+	assert.Equal(t, map[string]string{
+		"url":        "invalid_url",
+		"secret":     "length_range",
+		"eventTypes": "invalid_value",
+	}, codes)
+
+	rs = serve(router, "POST", "/webhooks", `{"url":""}`)
+	assert.Equal(t, http.StatusBadRequest, rs.Result().StatusCode)
+	assert.Contains(t, rs.Body.String(), `"code":"required"`)
+}
+
+func TestOpenAPICoversAllRoutes(t *testing.T) {
+	router := chi.NewRouter()
+	webhook.RegisterHandlers(router, webhook.NewMemoryStore(), newService(), log.New())
+	doc := openapi.New("test", "test", "/api/v1")
+	webhook.Describe(doc)
+
+	routes := 0
+	chi.Walk(router, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		routes++
+		assert.True(t, doc.HasOperation(method, route), "%s %s is not described in the OpenAPI document", method, route)
+		return nil
+	})
+	assert.NotZero(t, routes)
+}
+
+// newService returns a service without a policy, which allows every operation
+func newService() risk.Service {
+	return risk.NewService(risk.NewStore(log.New()), log.New())
+}
+
+func TestManagingSubscriptionsRequiresThePermission(t *testing.T) {
+	service := risk.NewService(risk.NewStore(log.New()), log.New(), risk.WithPolicy(auth.DefaultPolicy()))
+	router := chi.NewRouter()
+	router.Use(tenant.Middleware())
+	webhook.RegisterHandlers(router, webhook.NewMemoryStore(), service, log.New())
+	serveAs := func(role, method, url, body string) *httptest.ResponseRecorder {
+		rq, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		rq.Header.Set("Content-Type", "application/json")
+		rq = rq.WithContext(auth.WithPrincipal(rq.Context(), &auth.Principal{Subject: role, Roles: []string{role}}))
+		rs := httptest.NewRecorder()
+		router.ServeHTTP(rs, rq)
+		return rs
+	}
+	body := `{"url":"https://example.com/hook"}`
+
+	rs := serveAs(auth.RoleViewer, "POST", "/webhooks", body)
+	assert.Equal(t, http.StatusForbidden, rs.Code)
+	assert.Contains(t, rs.Body.String(), `"permission":"manage-webhooks"`)
+	assert.Equal(t, http.StatusForbidden, serveAs(auth.RoleViewer, "GET", "/webhooks", "").Code)
+	assert.Equal(t, http.StatusForbidden, serveAs(auth.RoleRiskOwner, "GET", "/webhooks/dead-letters", "").Code)
+
+	assert.Equal(t, http.StatusCreated, serveAs(auth.RoleAdmin, "POST", "/webhooks", body).Code)
+}
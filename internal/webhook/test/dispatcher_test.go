@@ -0,0 +1,393 @@
+package webhooktest
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/vikasgithub/risky-plumbers/internal/entity"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"github.com/vikasgithub/risky-plumbers/internal/tenant"
+	"github.com/vikasgithub/risky-plumbers/internal/webhook"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const secret = "0123456789abcdef"
+
+var testConfig = webhook.Config{
+	Workers:     2,
+	QueueSize:   1024,
+	MaxAttempts: 3,
+	BaseDelay:   10 * time.Millisecond,
+	MaxDelay:    50 * time.Millisecond,
+	Timeout:     time.Second,
+	// the receivers of the tests listen on the loopback interface
+	AllowedNetworks: []string{"127.0.0.0/8"},
+}
+
+func newDispatcher(t *testing.T, store webhook.Store) *webhook.Dispatcher {
+	return newDispatcherWith(t, store, testConfig)
+}
+
+func newDispatcherWith(t *testing.T, store webhook.Store, config webhook.Config) *webhook.Dispatcher {
+	dispatcher, err := webhook.NewDispatcher(store, config, log.New())
+	assert.NoError(t, err)
+	t.Cleanup(func() { dispatcher.Close(context.Background()) })
+	return dispatcher
+}
+
+func subscribe(t *testing.T, store webhook.Store, url string, eventTypes ...string) *webhook.Subscription {
+	subscription := &webhook.Subscription{
+		ID:         entity.GenerateID(),
+		TenantID:   tenant.Default,
+		URL:        url,
+		Secret:     secret,
+		EventTypes: eventTypes,
+		CreatedAt:  time.Now(),
+	}
+	assert.NoError(t, store.CreateSubscription(context.Background(), subscription))
+	return subscription
+}
+
+func newEvent(eventType string) risk.Event {
+	return risk.Event{
+		ID:       entity.GenerateID(),
+		Type:     eventType,
+		Time:     time.Now(),
+		TenantID: tenant.Default,
+		Risk:     &entity.Risk{ID: "1", State: "open", Title: "t", Description: "d"},
+	}
+}
+
+// waitForStatus waits until the only delivery of the subscription has the given status
+func waitForStatus(t *testing.T, store webhook.Store, subscriptionID, status string) *webhook.Delivery {
+	var delivery *webhook.Delivery
+	assert.Eventually(t, func() bool {
+		deliveries, _ := store.ListDeliveries(context.Background(), subscriptionID)
+		if len(deliveries) != 1 {
+			return false
+		}
+		delivery = deliveries[0]
+		return delivery.Status == status
+	}, 5*time.Second, 10*time.Millisecond)
+	return delivery
+}
+
+func TestDeliverySignature(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer receiver.Close()
+
+	store := webhook.NewMemoryStore()
+	dispatcher := newDispatcher(t, store)
+	subscription := subscribe(t, store, receiver.URL)
+	event := newEvent(risk.EventCreated)
+	assert.NoError(t, dispatcher.Publish(context.Background(), event))
+
+	r := <-received
+	body := <-bodies
+	timestamp := r.Header.Get(webhook.HeaderTimestamp)
+	assert.NotEmpty(t, timestamp)
+	assert.Equal(t, webhook.Sign(secret, timestamp, body), r.Header.Get(webhook.HeaderSignature))
+	assert.NotEqual(t, webhook.Sign("another secret!!", timestamp, body), r.Header.Get(webhook.HeaderSignature))
+	assert.Equal(t, risk.EventCreated, r.Header.Get(webhook.HeaderEventType))
+	assert.NotEmpty(t, r.Header.Get(webhook.HeaderDelivery))
+
+	var payload risk.Event
+	assert.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, event.ID, payload.ID)
+	assert.Equal(t, "1", payload.Risk.ID)
+
+	delivery := waitForStatus(t, store, subscription.ID, webhook.StatusSucceeded)
+	assert.Len(t, delivery.Attempts, 1)
+	assert.Equal(t, http.StatusOK, delivery.Attempts[0].StatusCode)
+}
+
+func TestDeliveryRetries(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	store := webhook.NewMemoryStore()
+	dispatcher := newDispatcher(t, store)
+	subscription := subscribe(t, store, receiver.URL)
+	assert.NoError(t, dispatcher.Publish(context.Background(), newEvent(risk.EventCreated)))
+
+	delivery := waitForStatus(t, store, subscription.ID, webhook.StatusSucceeded)
+	if assert.Len(t, delivery.Attempts, 3) {
+		assert.Equal(t, http.StatusServiceUnavailable, delivery.Attempts[0].StatusCode)
+		assert.NotEmpty(t, delivery.Attempts[0].Error)
+		assert.Empty(t, delivery.Attempts[2].Error)
+	}
+	deadLetters, _ := store.DeadLetters(context.Background(), tenant.Default)
+	assert.Empty(t, deadLetters)
+}
+
+func TestDeadLetters(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	store := webhook.NewMemoryStore()
+	dispatcher := newDispatcher(t, store)
+	subscription := subscribe(t, store, receiver.URL)
+	assert.NoError(t, dispatcher.Publish(context.Background(), newEvent(risk.EventCreated)))
+
+	delivery := waitForStatus(t, store, subscription.ID, webhook.StatusDead)
+	assert.Len(t, delivery.Attempts, testConfig.MaxAttempts)
+	assert.Nil(t, delivery.NextAttemptAt)
+	assert.Equal(t, int32(testConfig.MaxAttempts), atomic.LoadInt32(&calls))
+
+	deadLetters, _ := store.DeadLetters(context.Background(), tenant.Default)
+	if assert.Len(t, deadLetters, 1) {
+		assert.Equal(t, delivery.ID, deadLetters[0].ID)
+	}
+}
+
+func TestEventTypeFilter(t *testing.T) {
+	received := make(chan string, 2)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(webhook.HeaderEventType)
+	}))
+	defer receiver.Close()
+
+	store := webhook.NewMemoryStore()
+	dispatcher := newDispatcher(t, store)
+	subscription := subscribe(t, store, receiver.URL, risk.EventUpdated)
+	assert.NoError(t, dispatcher.Publish(context.Background(), newEvent(risk.EventCreated)))
+	assert.NoError(t, dispatcher.Publish(context.Background(), newEvent(risk.EventUpdated)))
+
+	assert.Equal(t, risk.EventUpdated, <-received)
+	waitForStatus(t, store, subscription.ID, webhook.StatusSucceeded)
+}
+
+func TestSubscriptionsOfOtherTenantsAreNotNotified(t *testing.T) {
+	received := make(chan string, 2)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(webhook.HeaderDelivery)
+	}))
+	defer receiver.Close()
+
+	store := webhook.NewMemoryStore()
+	dispatcher := newDispatcher(t, store)
+	subscription := subscribe(t, store, receiver.URL)
+	other := newEvent(risk.EventCreated)
+	other.TenantID = "acme"
+	assert.NoError(t, dispatcher.Publish(context.Background(), other))
+	assert.NoError(t, dispatcher.Publish(context.Background(), newEvent(risk.EventCreated)))
+
+	delivery := waitForStatus(t, store, subscription.ID, webhook.StatusSucceeded)
+	assert.Equal(t, delivery.ID, <-received)
+	assert.Equal(t, tenant.Default, delivery.TenantID)
+	assert.Len(t, received, 0)
+}
+
+func TestDeliveriesOnlyReachPublicAddresses(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer receiver.Close()
+
+	config := testConfig
+	config.MaxAttempts = 1
+	config.AllowedNetworks = nil
+	store := webhook.NewMemoryStore()
+	dispatcher := newDispatcherWith(t, store, config)
+	for _, url := range []string{receiver.URL, "http://169.254.169.254/latest/meta-data", "http://[::1]:8081/metrics"} {
+		subscribe(t, store, url)
+	}
+	assert.NoError(t, dispatcher.Publish(context.Background(), newEvent(risk.EventCreated)))
+
+	subscriptions, _ := store.ListSubscriptions(context.Background(), tenant.Default)
+	for _, subscription := range subscriptions {
+		delivery := waitForStatus(t, store, subscription.ID, webhook.StatusDead)
+		assert.Contains(t, delivery.Attempts[0].Error, "non-public address", subscription.URL)
+	}
+	assert.Zero(t, atomic.LoadInt32(&calls))
+}
+
+func TestRedirectsAreNotFollowed(t *testing.T) {
+	var redirected int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&redirected, 1)
+	}))
+	defer target.Close()
+	receiver := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer receiver.Close()
+
+	config := testConfig
+	config.MaxAttempts = 1
+	store := webhook.NewMemoryStore()
+	dispatcher := newDispatcherWith(t, store, config)
+	subscription := subscribe(t, store, receiver.URL)
+	assert.NoError(t, dispatcher.Publish(context.Background(), newEvent(risk.EventCreated)))
+
+	delivery := waitForStatus(t, store, subscription.ID, webhook.StatusDead)
+	assert.Equal(t, http.StatusFound, delivery.Attempts[0].StatusCode)
+	assert.Zero(t, atomic.LoadInt32(&redirected))
+}
+
+func TestInvalidAllowedNetworks(t *testing.T) {
+	config := testConfig
+	config.AllowedNetworks = []string{"10.0.0.0"}
+	_, err := webhook.NewDispatcher(webhook.NewMemoryStore(), config, log.New())
+	assert.Error(t, err)
+}
+
+func TestCloseSendsTheQueuedDeliveries(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer receiver.Close()
+
+	store := webhook.NewMemoryStore()
+	dispatcher := newDispatcher(t, store)
+	subscribe(t, store, receiver.URL)
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, dispatcher.Publish(context.Background(), newEvent(risk.EventCreated)))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, dispatcher.Close(ctx))
+	assert.Equal(t, int32(10), atomic.LoadInt32(&calls))
+}
+
+func TestCloseSendsTheWaitingRetries(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	config := testConfig
+	config.BaseDelay, config.MaxDelay = time.Hour, time.Hour
+	store := webhook.NewMemoryStore()
+	dispatcher := newDispatcherWith(t, store, config)
+	subscription := subscribe(t, store, receiver.URL)
+	assert.NoError(t, dispatcher.Publish(context.Background(), newEvent(risk.EventCreated)))
+	waitForStatus(t, store, subscription.ID, webhook.StatusRetrying)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, dispatcher.Close(ctx))
+	delivery := waitForStatus(t, store, subscription.ID, webhook.StatusSucceeded)
+	assert.Len(t, delivery.Attempts, 2)
+}
+
+func TestPublishDoesNotWaitForRoomInTheQueue(t *testing.T) {
+	release := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer receiver.Close()
+
+	config := testConfig
+	config.Workers, config.QueueSize = 1, 1
+	store := webhook.NewMemoryStore()
+	dispatcher := newDispatcherWith(t, store, config)
+	subscription := subscribe(t, store, receiver.URL)
+
+	published := make(chan struct{})
+	go func() {
+		defer close(published)
+		for i := 0; i < 5; i++ {
+			assert.NoError(t, dispatcher.Publish(context.Background(), newEvent(risk.EventCreated)))
+		}
+	}()
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("publish waited for the receiver")
+	}
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		deliveries, _ := store.ListDeliveries(context.Background(), subscription.ID)
+		for _, delivery := range deliveries {
+			if delivery.Status != webhook.StatusSucceeded {
+				return false
+			}
+		}
+		return len(deliveries) == 5
+	}, 5*time.Second, 10*time.Millisecond, "the deliveries which did not fit in the queue are sent later")
+}
+
+func TestDeliveryHistoryIsBounded(t *testing.T) {
+	store := webhook.NewMemoryStore()
+	ctx := context.Background()
+	subscription := subscribe(t, store, "https://example.com")
+	save := func(status string, createdAt time.Time) *webhook.Delivery {
+		delivery := &webhook.Delivery{ID: entity.GenerateID(), SubscriptionID: subscription.ID, TenantID: tenant.Default,
+			Status: status, CreatedAt: createdAt}
+		assert.NoError(t, store.SaveDelivery(ctx, delivery))
+		return delivery
+	}
+	start := time.Now()
+	pending := save(webhook.StatusPending, start)
+	oldest := save(webhook.StatusSucceeded, start.Add(time.Second))
+	for i := 0; i < webhook.MaxDeliveries; i++ {
+		save(webhook.StatusSucceeded, start.Add(time.Duration(i+2)*time.Second))
+	}
+
+	deliveries, err := store.ListDeliveries(ctx, subscription.ID)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, webhook.MaxDeliveries)
+	ids := map[string]bool{}
+	for _, delivery := range deliveries {
+		ids[delivery.ID] = true
+	}
+	assert.True(t, ids[pending.ID], "the pending delivery is kept")
+	assert.False(t, ids[oldest.ID], "the oldest finished delivery is dropped")
+}
+
+func TestDeletedSubscriptionsAreNotRetried(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	config := testConfig
+	config.MaxAttempts = 10
+	config.BaseDelay, config.MaxDelay = 50*time.Millisecond, 50*time.Millisecond
+	store := webhook.NewMemoryStore()
+	dispatcher := newDispatcherWith(t, store, config)
+	subscription := subscribe(t, store, receiver.URL)
+	assert.NoError(t, dispatcher.Publish(context.Background(), newEvent(risk.EventCreated)))
+	waitForStatus(t, store, subscription.ID, webhook.StatusRetrying)
+
+	ctx := context.Background()
+	assert.NoError(t, store.DeleteSubscription(ctx, tenant.Default, subscription.ID))
+	attempts := atomic.LoadInt32(&calls)
+	deliveries, err := store.ListDeliveries(ctx, subscription.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, deliveries, "the deliveries are deleted with the subscription")
+	assert.Never(t, func() bool { return atomic.LoadInt32(&calls) > attempts }, 300*time.Millisecond, 10*time.Millisecond,
+		"the waiting retry is dropped")
+	deliveries, _ = store.ListDeliveries(ctx, subscription.ID)
+	assert.Empty(t, deliveries)
+}
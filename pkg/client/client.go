@@ -0,0 +1,325 @@
+// Package client provides a typed client for the risky plumbers REST API.
+//
+// The client covers the operations exposed by the API: get, list, create, update, transition, export and the
+// stream of the changes.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageSize    = 100
+	defaultMaxAttempts = 3
+	defaultBaseDelay   = 100 * time.Millisecond
+	defaultMaxDelay    = 2 * time.Second
+
+	risksPath = "/api/v1/risks"
+)
+
+// Risk is a risk as returned by the API. The Description of a Confidential risk is "[redacted]" when the caller
+// may not read confidential risks.
+type Risk struct {
+	ID           string   `json:"id"`
+	State        string   `json:"state"`
+	Title        string   `json:"title"`
+	Description  string   `json:"description"`
+	Confidential bool     `json:"confidential,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+}
+
+// CreateRiskRequest holds the fields of a new risk.
+type CreateRiskRequest struct {
+	State        string   `json:"state"`
+	Title        string   `json:"title"`
+	Description  string   `json:"description"`
+	Confidential bool     `json:"confidential,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+}
+
+// UpdateRiskRequest holds the fields of a risk to change, the fields left nil keep their value.
+type UpdateRiskRequest struct {
+	State        *string   `json:"state,omitempty"`
+	Title        *string   `json:"title,omitempty"`
+	Description  *string   `json:"description,omitempty"`
+	Confidential *bool     `json:"confidential,omitempty"`
+	Tags         *[]string `json:"tags,omitempty"`
+}
+
+// Client calls the risk API. It is safe for concurrent use.
+type Client struct {
+	baseURL     string
+	httpClient  *http.Client
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	pageSize    int
+	apiKey      string
+	bearerToken string
+	tenant      string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the http client used to send the requests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetry sets how many times a request is attempted and the bounds of the exponential backoff between attempts.
+func WithRetry(maxAttempts int, baseDelay, maxDelay time.Duration) Option {
+	return func(c *Client) {
+		c.maxAttempts = maxAttempts
+		c.baseDelay = baseDelay
+		c.maxDelay = maxDelay
+	}
+}
+
+// WithPageSize sets the number of risks fetched per request by List.
+func WithPageSize(pageSize int) Option {
+	return func(c *Client) {
+		c.pageSize = pageSize
+	}
+}
+
+// WithAPIKey authenticates the requests with the given API key.
+func WithAPIKey(apiKey string) Option {
+	return func(c *Client) {
+		c.apiKey = apiKey
+	}
+}
+
+// WithBearerToken authenticates the requests with the given JWT bearer token.
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.bearerToken = token
+	}
+}
+
+// WithTenant sends the requests to the given tenant. Authenticated callers act on the tenant of their
+// credentials and may omit it.
+func WithTenant(tenant string) Option {
+	return func(c *Client) {
+		c.tenant = tenant
+	}
+}
+
+// New creates a client for the API served at baseURL, e.g. http://localhost:8080
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		httpClient:  http.DefaultClient,
+		maxAttempts: defaultMaxAttempts,
+		baseDelay:   defaultBaseDelay,
+		maxDelay:    defaultMaxDelay,
+		pageSize:    defaultPageSize,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Get returns the risk with the given id. The error matches ErrNotFound if the risk does not exist.
+func (c *Client) Get(ctx context.Context, id string) (*Risk, error) {
+	var risk Risk
+	if err := c.do(ctx, http.MethodGet, risksPath+"/"+url.PathEscape(id), nil, nil, &risk); err != nil {
+		return nil, err
+	}
+	return &risk, nil
+}
+
+// ListPage returns at most limit risks starting at offset.
+func (c *Client) ListPage(ctx context.Context, offset, limit int) ([]*Risk, error) {
+	query := url.Values{}
+	query.Set("offset", strconv.Itoa(offset))
+	query.Set("limit", strconv.Itoa(limit))
+	var risks []*Risk
+	if err := c.do(ctx, http.MethodGet, risksPath+"?"+query.Encode(), nil, nil, &risks); err != nil {
+		return nil, err
+	}
+	return risks, nil
+}
+
+// List returns an iterator over all the risks. The pages are fetched lazily while iterating.
+func (c *Client) List(ctx context.Context) *RiskIterator {
+	return &RiskIterator{ctx: ctx, client: c}
+}
+
+// Export returns all the risks of the tenant in a single request.
+func (c *Client) Export(ctx context.Context) ([]*Risk, error) {
+	var export struct {
+		Risks []*Risk `json:"risks"`
+	}
+	if err := c.do(ctx, http.MethodGet, risksPath+"/export", nil, nil, &export); err != nil {
+		return nil, err
+	}
+	return export.Risks, nil
+}
+
+// Create creates a new risk. Every call is sent with a new Idempotency-Key, so that retries never create duplicates.
+func (c *Client) Create(ctx context.Context, input *CreateRiskRequest) (*Risk, error) {
+	return c.CreateWithKey(ctx, uuid.New().String(), input)
+}
+
+// CreateWithKey creates a new risk using the given idempotency key. Callers which retry on their own should pass
+// the same key on every attempt.
+func (c *Client) CreateWithKey(ctx context.Context, idempotencyKey string, input *CreateRiskRequest) (*Risk, error) {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	header.Set("Idempotency-Key", idempotencyKey)
+	var risk Risk
+	if err := c.do(ctx, http.MethodPost, risksPath, header, body, &risk); err != nil {
+		return nil, err
+	}
+	return &risk, nil
+}
+
+// Update changes the fields of the risk set in input. The error matches ErrNotFound if the risk does not exist and
+// ErrInvalidRequest if the updated risk is not valid.
+func (c *Client) Update(ctx context.Context, id string, input *UpdateRiskRequest) (*Risk, error) {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	var risk Risk
+	if err := c.do(ctx, http.MethodPut, risksPath+"/"+url.PathEscape(id), nil, body, &risk); err != nil {
+		return nil, err
+	}
+	return &risk, nil
+}
+
+// Transition moves the risk to the given state. Accepting a risk requires the transition-to-accepted permission,
+// the error matches ErrForbidden otherwise.
+func (c *Client) Transition(ctx context.Context, id, state string) (*Risk, error) {
+	body, err := json.Marshal(map[string]string{"state": state})
+	if err != nil {
+		return nil, err
+	}
+	var risk Risk
+	if err := c.do(ctx, http.MethodPost, risksPath+"/"+url.PathEscape(id)+"/transition", nil, body, &risk); err != nil {
+		return nil, err
+	}
+	return &risk, nil
+}
+
+// do sends the request, retrying transport errors and retryable statuses, and decodes the response into out.
+// Only idempotent requests, or requests carrying an Idempotency-Key, are retried.
+func (c *Client) do(ctx context.Context, method, path string, header http.Header, body []byte, out interface{}) error {
+	retryable := method == http.MethodGet || header.Get("Idempotency-Key") != ""
+	var lastErr error
+	for attempt := 0; attempt < c.maxAttempts; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, c.backoff(attempt, lastErr)); err != nil {
+				return err
+			}
+		}
+
+		rq, err := c.newRequest(ctx, method, path, header, body)
+		if err != nil {
+			return err
+		}
+		rs, err := c.httpClient.Do(rq)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			lastErr = err
+			if !retryable {
+				return err
+			}
+			continue
+		}
+		lastErr = c.handleResponse(rs, out)
+		if lastErr == nil || !retryable || !isRetryable(lastErr) {
+			return lastErr
+		}
+	}
+	return lastErr
+}
+
+// newRequest returns a request to the API with the credentials and the tenant of the client
+func (c *Client) newRequest(ctx context.Context, method, path string, header http.Header, body []byte) (*http.Request, error) {
+	rq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		rq.Header[name] = values
+	}
+	if rq.Header.Get("Accept") == "" {
+		rq.Header.Set("Accept", "application/json")
+	}
+	if c.apiKey != "" {
+		rq.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.bearerToken != "" {
+		rq.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	}
+	if c.tenant != "" {
+		rq.Header.Set("X-Tenant-ID", c.tenant)
+	}
+	if body != nil {
+		rq.Header.Set("Content-Type", "application/json")
+	}
+	return rq, nil
+}
+
+func (c *Client) handleResponse(rs *http.Response, out interface{}) error {
+	defer rs.Body.Close()
+	body, err := io.ReadAll(rs.Body)
+	if err != nil {
+		return err
+	}
+	if rs.StatusCode >= http.StatusBadRequest {
+		return newAPIError(rs, body)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// backoff returns the delay before the given attempt. Retry-After sent by the server takes precedence.
+func (c *Client) backoff(attempt int, lastErr error) time.Duration {
+	if apiErr, ok := lastErr.(*APIError); ok && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+	delay := c.baseDelay << (attempt - 1)
+	if delay > c.maxDelay || delay <= 0 {
+		delay = c.maxDelay
+	}
+	// full jitter
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrNotFound mirrors the record not found error of the service
+	ErrNotFound = errors.New("record does not exist")
+	// ErrInvalidRequest is matched by errors caused by an invalid request, e.g. a validation failure
+	ErrInvalidRequest = errors.New("invalid request")
+	// ErrConflict is matched when the request conflicts with a request in progress or a reused idempotency key
+	ErrConflict = errors.New("conflict")
+	// ErrUnauthorized is matched when the credentials are missing or invalid
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden is matched when the caller lacks the permission of the operation, see APIError.Permission
+	ErrForbidden = errors.New("forbidden")
+)
+
+// FieldError describes a validation failure of a single request field.
+type FieldError struct {
+	Field   string                 `json:"field"`
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
+
+// APIError is returned when the API responds with an error status.
+type APIError struct {
+	StatusCode int
+	Status     string       `json:"status"`
+	Message    string       `json:"error"`
+	Errors     []FieldError `json:"errors"`
+	// Permission is the missing permission of a forbidden request
+	Permission string `json:"permission"`
+	// RetryAfter is the delay requested by the server before retrying
+	RetryAfter time.Duration
+	// RequestID identifies the failed request in the logs of the server
+	RequestID string `json:"requestId"`
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%d %s %s", e.StatusCode, e.Status, e.Message)
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, e.Status)
+}
+
+// Is allows matching the error against ErrNotFound, ErrInvalidRequest, ErrConflict, ErrUnauthorized and ErrForbidden
+// using errors.Is
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrInvalidRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	}
+	return false
+}
+
+func newAPIError(rs *http.Response, body []byte) *APIError {
+	apiErr := &APIError{}
+	if err := json.Unmarshal(body, apiErr); err != nil || apiErr.Status == "" {
+		apiErr.Status = http.StatusText(rs.StatusCode)
+	}
+	apiErr.StatusCode = rs.StatusCode
+	if apiErr.RequestID == "" {
+		apiErr.RequestID = rs.Header.Get("X-Request-ID")
+	}
+	if seconds, err := strconv.Atoi(rs.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
+
+// isRetryable reports whether a request failing with err can be sent again
+func isRetryable(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	// 409 is returned while a request with the same idempotency key is still in progress
+	return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode == http.StatusConflict ||
+		apiErr.StatusCode >= http.StatusInternalServerError
+}
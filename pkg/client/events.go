@@ -0,0 +1,120 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// The types of the events.
+const (
+	EventCreated = "risk.created"
+	EventUpdated = "risk.updated"
+)
+
+// maxEventSize is the size of the largest event the stream reads
+const maxEventSize = 1 << 20
+
+// Event is a change of a risk. The Description of a Confidential risk is always "[redacted]", the callers allowed
+// to read it Get the risk.
+type Event struct {
+	ID       string    `json:"id"`
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	TenantID string    `json:"tenantId"`
+	Risk     *Risk     `json:"risk"`
+}
+
+// EventStream reads the changes of the risks of the tenant as they happen.
+//
+//	stream, err := c.Events(ctx, "")
+//	for stream.Next() {
+//		event := stream.Event()
+//	}
+//	stream.Close()
+//	// the stream ended, reconnect with c.Events(ctx, stream.LastEventID()) to get the events missed meanwhile
+type EventStream struct {
+	body        io.ReadCloser
+	scanner     *bufio.Scanner
+	event       *Event
+	lastEventID string
+	err         error
+}
+
+// Events opens the stream of the changes of the risks of the tenant, served as Server-Sent Events. When
+// lastEventID is not empty, the events which followed it and are still kept by the service are replayed first.
+func (c *Client) Events(ctx context.Context, lastEventID string) (*EventStream, error) {
+	header := http.Header{"Accept": {"text/event-stream"}}
+	if lastEventID != "" {
+		header.Set("Last-Event-ID", lastEventID)
+	}
+	rq, err := c.newRequest(ctx, http.MethodGet, risksPath+"/events", header, nil)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := c.httpClient.Do(rq)
+	if err != nil {
+		return nil, err
+	}
+	if rs.StatusCode >= http.StatusBadRequest {
+		return nil, c.handleResponse(rs, nil)
+	}
+	scanner := bufio.NewScanner(rs.Body)
+	scanner.Buffer(make([]byte, 0, 4096), maxEventSize)
+	return &EventStream{body: rs.Body, scanner: scanner, lastEventID: lastEventID}, nil
+}
+
+// Next waits for the next event and reports whether there is one. It returns false once the stream ended, when
+// the context of the stream is done or the service closed it.
+func (s *EventStream) Next() bool {
+	var data strings.Builder
+	for s.err == nil && s.scanner.Scan() {
+		line := s.scanner.Text()
+		switch {
+		case line == "":
+			// a blank line ends the event, the heartbeats are comments and have no data
+			if data.Len() == 0 {
+				continue
+			}
+			var event Event
+			if err := json.Unmarshal([]byte(data.String()), &event); err != nil {
+				s.err = fmt.Errorf("decoding event: %w", err)
+				return false
+			}
+			s.event = &event
+			s.lastEventID = event.ID
+			return true
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if s.err == nil {
+		s.err = s.scanner.Err()
+	}
+	return false
+}
+
+// Event returns the current event.
+func (s *EventStream) Event() *Event {
+	return s.event
+}
+
+// LastEventID returns the id of the last event read, to resume the stream after it.
+func (s *EventStream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Err returns the error which ended the stream, it is nil when the service closed the stream.
+func (s *EventStream) Err() error {
+	return s.err
+}
+
+// Close ends the stream.
+func (s *EventStream) Close() error {
+	return s.body.Close()
+}
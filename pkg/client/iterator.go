@@ -0,0 +1,57 @@
+package client
+
+import "context"
+
+// RiskIterator iterates over all the risks, following the pages of the API.
+//
+//	it := c.List(ctx)
+//	for it.Next() {
+//		risk := it.Risk()
+//	}
+//	if err := it.Err(); err != nil {
+//		// handle the error
+//	}
+type RiskIterator struct {
+	ctx    context.Context
+	client *Client
+	page   []*Risk
+	offset int
+	index  int
+	done   bool
+	err    error
+}
+
+// Next advances to the next risk and reports whether there is one.
+func (it *RiskIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.index+1 < len(it.page) {
+		it.index++
+		return true
+	}
+	if it.done {
+		return false
+	}
+	page, err := it.client.ListPage(it.ctx, it.offset, it.client.pageSize)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.page = page
+	it.index = 0
+	it.offset += len(page)
+	// a short page is the last one
+	it.done = len(page) < it.client.pageSize
+	return len(page) > 0
+}
+
+// Risk returns the current risk.
+func (it *RiskIterator) Risk() *Risk {
+	return it.page[it.index]
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *RiskIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,199 @@
+package clienttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/stretchr/testify/assert"
+	"github.com/vikasgithub/risky-plumbers/internal/auth"
+	"github.com/vikasgithub/risky-plumbers/internal/idempotency"
+	"github.com/vikasgithub/risky-plumbers/internal/log"
+	"github.com/vikasgithub/risky-plumbers/internal/risk"
+	"github.com/vikasgithub/risky-plumbers/internal/tenant"
+	"github.com/vikasgithub/risky-plumbers/pkg/client"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newServer starts a server running the real risk handlers
+func newServer(t *testing.T) *httptest.Server {
+	logger := log.New()
+	api := chi.NewRouter()
+	api.Use(idempotency.Middleware(idempotency.NewMemoryStore(), time.Hour, log.New()))
+	risk.RegisterHandlers(api, risk.NewService(risk.NewStore(logger), logger), logger)
+
+	r := chi.NewRouter()
+	r.Use(render.SetContentType(render.ContentTypeJSON))
+	r.Mount("/api/v1", api)
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient(t *testing.T) {
+	server := newServer(t)
+	c := client.New(server.URL, client.WithPageSize(2))
+	ctx := context.Background()
+
+	t.Run("Create And Get", func(t *testing.T) {
+		created, err := c.Create(ctx, &client.CreateRiskRequest{State: "open", Title: "t", Description: "d"})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, created.ID)
+
+		found, err := c.Get(ctx, created.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, created, found)
+	})
+
+	t.Run("Get Not Found", func(t *testing.T) {
+		_, err := c.Get(ctx, "unknown")
+		assert.True(t, errors.Is(err, client.ErrNotFound))
+	})
+
+	t.Run("Create Invalid", func(t *testing.T) {
+		_, err := c.Create(ctx, &client.CreateRiskRequest{State: "open", Title: "t"})
+		assert.True(t, errors.Is(err, client.ErrInvalidRequest))
+		var apiErr *client.APIError
+		assert.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, "description", apiErr.Errors[0].Field)
+		assert.Equal(t, "required", apiErr.Errors[0].Code)
+	})
+
+	t.Run("Create Is Idempotent For The Same Key", func(t *testing.T) {
+		input := &client.CreateRiskRequest{State: "closed", Title: "t", Description: "d"}
+		first, err := c.CreateWithKey(ctx, "key-1", input)
+		assert.NoError(t, err)
+		second, err := c.CreateWithKey(ctx, "key-1", input)
+		assert.NoError(t, err)
+		assert.Equal(t, first.ID, second.ID)
+	})
+
+	t.Run("Update And Transition", func(t *testing.T) {
+		created, err := c.Create(ctx, &client.CreateRiskRequest{State: "open", Title: "t", Description: "d"})
+		assert.NoError(t, err)
+		title := "new"
+		updated, err := c.Update(ctx, created.ID, &client.UpdateRiskRequest{Title: &title})
+		assert.NoError(t, err)
+		assert.Equal(t, &client.Risk{ID: created.ID, State: "open", Title: "new", Description: "d"}, updated)
+
+		transitioned, err := c.Transition(ctx, created.ID, "closed")
+		assert.NoError(t, err)
+		assert.Equal(t, "closed", transitioned.State)
+
+		_, err = c.Transition(ctx, created.ID, "gone")
+		assert.True(t, errors.Is(err, client.ErrInvalidRequest))
+		empty := ""
+		_, err = c.Update(ctx, created.ID, &client.UpdateRiskRequest{Description: &empty})
+		var apiErr *client.APIError
+		assert.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, "description", apiErr.Errors[0].Field)
+	})
+
+	t.Run("Update Not Found", func(t *testing.T) {
+		title := "new"
+		_, err := c.Update(ctx, "unknown", &client.UpdateRiskRequest{Title: &title})
+		assert.True(t, errors.Is(err, client.ErrNotFound))
+		_, err = c.Transition(ctx, "unknown", "closed")
+		assert.True(t, errors.Is(err, client.ErrNotFound))
+	})
+
+	t.Run("List Follows Pages", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			_, err := c.Create(ctx, &client.CreateRiskRequest{State: "open", Title: fmt.Sprint(i), Description: "d"})
+			assert.NoError(t, err)
+		}
+		it := c.List(ctx)
+		ids := map[string]bool{}
+		for it.Next() {
+			ids[it.Risk().ID] = true
+		}
+		assert.NoError(t, it.Err())
+		assert.Equal(t, 6, len(ids))
+	})
+}
+
+func TestClientRetry(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id":"1","state":"open","title":"t","description":"d"}`))
+	}))
+	defer server.Close()
+
+	c := client.New(server.URL, client.WithRetry(3, time.Millisecond, 5*time.Millisecond))
+	risk, err := c.Get(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", risk.ID)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	atomic.StoreInt32(&calls, -10)
+	_, err = c.Get(context.Background(), "1")
+	var apiErr *client.APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+}
+
+func TestClientContextCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	c := client.New(server.URL, client.WithRetry(10, time.Second, time.Second))
+	_, err := c.Get(ctx, "1")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClientAuthentication(t *testing.T) {
+	logger := log.New()
+	authenticator, _ := auth.NewAPIKeyAuthenticator([]auth.APIKey{{Name: "ci", Key: "ci-key"}})
+	api := chi.NewRouter()
+	api.Use(auth.Middleware(authenticator, logger))
+	risk.RegisterHandlers(api, risk.NewService(risk.NewStore(logger), logger), logger)
+	r := chi.NewRouter()
+	r.Mount("/api/v1", api)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	_, err := client.New(server.URL).ListPage(context.Background(), 0, 10)
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+
+	_, err = client.New(server.URL, client.WithAPIKey("ci-key")).ListPage(context.Background(), 0, 10)
+	assert.NoError(t, err)
+}
+
+func TestClientTenant(t *testing.T) {
+	logger := log.New()
+	api := chi.NewRouter()
+	api.Use(tenant.Middleware())
+	risk.RegisterHandlers(api, risk.NewService(risk.NewStore(logger), logger), logger)
+	r := chi.NewRouter()
+	r.Mount("/api/v1", api)
+	server := httptest.NewServer(r)
+	defer server.Close()
+	acme := client.New(server.URL, client.WithTenant("acme"))
+	globex := client.New(server.URL, client.WithTenant("globex"))
+	ctx := context.Background()
+
+	created, err := acme.Create(ctx, &client.CreateRiskRequest{State: "open", Title: "t", Description: "d"})
+	assert.NoError(t, err)
+	_, err = globex.Get(ctx, created.ID)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+
+	risks, err := acme.Export(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []*client.Risk{created}, risks)
+	risks, err = globex.Export(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, risks)
+}
@@ -0,0 +1,379 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: risk/v1/risk.proto
+
+package riskpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RiskService_GetRisk_FullMethodName        = "/risk.v1.RiskService/GetRisk"
+	RiskService_ListRisks_FullMethodName      = "/risk.v1.RiskService/ListRisks"
+	RiskService_CreateRisk_FullMethodName     = "/risk.v1.RiskService/CreateRisk"
+	RiskService_UpdateRisk_FullMethodName     = "/risk.v1.RiskService/UpdateRisk"
+	RiskService_TransitionRisk_FullMethodName = "/risk.v1.RiskService/TransitionRisk"
+	RiskService_ExportRisks_FullMethodName    = "/risk.v1.RiskService/ExportRisks"
+	RiskService_WatchRisks_FullMethodName     = "/risk.v1.RiskService/WatchRisks"
+)
+
+// RiskServiceClient is the client API for RiskService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// RiskService exposes the risk register over gRPC. It shares risk.Service with the REST API and has the same
+// operations. The comments, mitigations and history of the risks are only served by the GraphQL API.
+// Unexpected errors are returned as INTERNAL with the message "internal error", the cause is only logged.
+type RiskServiceClient interface {
+	// GetRisk returns a risk by id. NOT_FOUND is returned when the risk does not exist.
+	GetRisk(ctx context.Context, in *GetRiskRequest, opts ...grpc.CallOption) (*Risk, error)
+	// ListRisks returns a page of risks ordered by id.
+	ListRisks(ctx context.Context, in *ListRisksRequest, opts ...grpc.CallOption) (*ListRisksResponse, error)
+	// CreateRisk creates a risk. INVALID_ARGUMENT with BadRequest details is returned when validation fails.
+	CreateRisk(ctx context.Context, in *CreateRiskRequest, opts ...grpc.CallOption) (*Risk, error)
+	// UpdateRisk changes the fields of a risk which are set, the others keep their value. NOT_FOUND is returned when
+	// the risk does not exist, INVALID_ARGUMENT with BadRequest details when the updated risk is not valid.
+	UpdateRisk(ctx context.Context, in *UpdateRiskRequest, opts ...grpc.CallOption) (*Risk, error)
+	// TransitionRisk moves a risk to another state. Accepting a risk requires the transition-to-accepted permission.
+	TransitionRisk(ctx context.Context, in *TransitionRiskRequest, opts ...grpc.CallOption) (*Risk, error)
+	// ExportRisks returns all the risks of the tenant except the confidential ones, ordered by id.
+	ExportRisks(ctx context.Context, in *ExportRisksRequest, opts ...grpc.CallOption) (*ExportRisksResponse, error)
+	// WatchRisks streams the changes of risks made after the call started. The response headers are sent once the
+	// subscription is registered, the changes made after they are received are streamed.
+	WatchRisks(ctx context.Context, in *WatchRisksRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RiskEvent], error)
+}
+
+type riskServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRiskServiceClient(cc grpc.ClientConnInterface) RiskServiceClient {
+	return &riskServiceClient{cc}
+}
+
+func (c *riskServiceClient) GetRisk(ctx context.Context, in *GetRiskRequest, opts ...grpc.CallOption) (*Risk, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Risk)
+	err := c.cc.Invoke(ctx, RiskService_GetRisk_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *riskServiceClient) ListRisks(ctx context.Context, in *ListRisksRequest, opts ...grpc.CallOption) (*ListRisksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRisksResponse)
+	err := c.cc.Invoke(ctx, RiskService_ListRisks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *riskServiceClient) CreateRisk(ctx context.Context, in *CreateRiskRequest, opts ...grpc.CallOption) (*Risk, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Risk)
+	err := c.cc.Invoke(ctx, RiskService_CreateRisk_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *riskServiceClient) UpdateRisk(ctx context.Context, in *UpdateRiskRequest, opts ...grpc.CallOption) (*Risk, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Risk)
+	err := c.cc.Invoke(ctx, RiskService_UpdateRisk_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *riskServiceClient) TransitionRisk(ctx context.Context, in *TransitionRiskRequest, opts ...grpc.CallOption) (*Risk, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Risk)
+	err := c.cc.Invoke(ctx, RiskService_TransitionRisk_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *riskServiceClient) ExportRisks(ctx context.Context, in *ExportRisksRequest, opts ...grpc.CallOption) (*ExportRisksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExportRisksResponse)
+	err := c.cc.Invoke(ctx, RiskService_ExportRisks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *riskServiceClient) WatchRisks(ctx context.Context, in *WatchRisksRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RiskEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RiskService_ServiceDesc.Streams[0], RiskService_WatchRisks_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRisksRequest, RiskEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RiskService_WatchRisksClient = grpc.ServerStreamingClient[RiskEvent]
+
+// RiskServiceServer is the server API for RiskService service.
+// All implementations must embed UnimplementedRiskServiceServer
+// for forward compatibility.
+//
+// RiskService exposes the risk register over gRPC. It shares risk.Service with the REST API and has the same
+// operations. The comments, mitigations and history of the risks are only served by the GraphQL API.
+// Unexpected errors are returned as INTERNAL with the message "internal error", the cause is only logged.
+type RiskServiceServer interface {
+	// GetRisk returns a risk by id. NOT_FOUND is returned when the risk does not exist.
+	GetRisk(context.Context, *GetRiskRequest) (*Risk, error)
+	// ListRisks returns a page of risks ordered by id.
+	ListRisks(context.Context, *ListRisksRequest) (*ListRisksResponse, error)
+	// CreateRisk creates a risk. INVALID_ARGUMENT with BadRequest details is returned when validation fails.
+	CreateRisk(context.Context, *CreateRiskRequest) (*Risk, error)
+	// UpdateRisk changes the fields of a risk which are set, the others keep their value. NOT_FOUND is returned when
+	// the risk does not exist, INVALID_ARGUMENT with BadRequest details when the updated risk is not valid.
+	UpdateRisk(context.Context, *UpdateRiskRequest) (*Risk, error)
+	// TransitionRisk moves a risk to another state. Accepting a risk requires the transition-to-accepted permission.
+	TransitionRisk(context.Context, *TransitionRiskRequest) (*Risk, error)
+	// ExportRisks returns all the risks of the tenant except the confidential ones, ordered by id.
+	ExportRisks(context.Context, *ExportRisksRequest) (*ExportRisksResponse, error)
+	// WatchRisks streams the changes of risks made after the call started. The response headers are sent once the
+	// subscription is registered, the changes made after they are received are streamed.
+	WatchRisks(*WatchRisksRequest, grpc.ServerStreamingServer[RiskEvent]) error
+	mustEmbedUnimplementedRiskServiceServer()
+}
+
+// UnimplementedRiskServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRiskServiceServer struct{}
+
+func (UnimplementedRiskServiceServer) GetRisk(context.Context, *GetRiskRequest) (*Risk, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRisk not implemented")
+}
+func (UnimplementedRiskServiceServer) ListRisks(context.Context, *ListRisksRequest) (*ListRisksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRisks not implemented")
+}
+func (UnimplementedRiskServiceServer) CreateRisk(context.Context, *CreateRiskRequest) (*Risk, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateRisk not implemented")
+}
+func (UnimplementedRiskServiceServer) UpdateRisk(context.Context, *UpdateRiskRequest) (*Risk, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateRisk not implemented")
+}
+func (UnimplementedRiskServiceServer) TransitionRisk(context.Context, *TransitionRiskRequest) (*Risk, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TransitionRisk not implemented")
+}
+func (UnimplementedRiskServiceServer) ExportRisks(context.Context, *ExportRisksRequest) (*ExportRisksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExportRisks not implemented")
+}
+func (UnimplementedRiskServiceServer) WatchRisks(*WatchRisksRequest, grpc.ServerStreamingServer[RiskEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchRisks not implemented")
+}
+func (UnimplementedRiskServiceServer) mustEmbedUnimplementedRiskServiceServer() {}
+func (UnimplementedRiskServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeRiskServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RiskServiceServer will
+// result in compilation errors.
+type UnsafeRiskServiceServer interface {
+	mustEmbedUnimplementedRiskServiceServer()
+}
+
+func RegisterRiskServiceServer(s grpc.ServiceRegistrar, srv RiskServiceServer) {
+	// If the following call pancis, it indicates UnimplementedRiskServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RiskService_ServiceDesc, srv)
+}
+
+func _RiskService_GetRisk_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRiskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RiskServiceServer).GetRisk(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RiskService_GetRisk_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RiskServiceServer).GetRisk(ctx, req.(*GetRiskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RiskService_ListRisks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRisksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RiskServiceServer).ListRisks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RiskService_ListRisks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RiskServiceServer).ListRisks(ctx, req.(*ListRisksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RiskService_CreateRisk_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRiskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RiskServiceServer).CreateRisk(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RiskService_CreateRisk_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RiskServiceServer).CreateRisk(ctx, req.(*CreateRiskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RiskService_UpdateRisk_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRiskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RiskServiceServer).UpdateRisk(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RiskService_UpdateRisk_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RiskServiceServer).UpdateRisk(ctx, req.(*UpdateRiskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RiskService_TransitionRisk_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransitionRiskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RiskServiceServer).TransitionRisk(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RiskService_TransitionRisk_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RiskServiceServer).TransitionRisk(ctx, req.(*TransitionRiskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RiskService_ExportRisks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportRisksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RiskServiceServer).ExportRisks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RiskService_ExportRisks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RiskServiceServer).ExportRisks(ctx, req.(*ExportRisksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RiskService_WatchRisks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRisksRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RiskServiceServer).WatchRisks(m, &grpc.GenericServerStream[WatchRisksRequest, RiskEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RiskService_WatchRisksServer = grpc.ServerStreamingServer[RiskEvent]
+
+// RiskService_ServiceDesc is the grpc.ServiceDesc for RiskService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RiskService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "risk.v1.RiskService",
+	HandlerType: (*RiskServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetRisk",
+			Handler:    _RiskService_GetRisk_Handler,
+		},
+		{
+			MethodName: "ListRisks",
+			Handler:    _RiskService_ListRisks_Handler,
+		},
+		{
+			MethodName: "CreateRisk",
+			Handler:    _RiskService_CreateRisk_Handler,
+		},
+		{
+			MethodName: "UpdateRisk",
+			Handler:    _RiskService_UpdateRisk_Handler,
+		},
+		{
+			MethodName: "TransitionRisk",
+			Handler:    _RiskService_TransitionRisk_Handler,
+		},
+		{
+			MethodName: "ExportRisks",
+			Handler:    _RiskService_ExportRisks_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchRisks",
+			Handler:       _RiskService_WatchRisks_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "risk/v1/risk.proto",
+}